@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+)
+
+// APIKeyCredentials are per-RPC credentials that send an API key in the x-api-key header,
+// so that machine clients don't need to login with a username and password
+type APIKeyCredentials struct {
+	apiKey     string
+	requireTLS bool
+}
+
+// NewAPIKeyCredentials returns the credentials of an API key. The key is only sent over TLS
+// if requireTLS is true, which should only be false for local development.
+func NewAPIKeyCredentials(apiKey string, requireTLS bool) *APIKeyCredentials {
+	return &APIKeyCredentials{apiKey: apiKey, requireTLS: requireTLS}
+}
+
+// GetRequestMetadata returns the metadata that carries the API key
+func (credentials *APIKeyCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"x-api-key": credentials.apiKey}, nil
+}
+
+// RequireTransportSecurity reports whether the API key can only be sent over TLS
+func (credentials *APIKeyCredentials) RequireTransportSecurity() bool {
+	return credentials.requireTLS
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"github.com/treeforest/grpc-pcbook/pb"
 	"google.golang.org/grpc"
+	"log"
+	"sync"
 	"time"
 )
 
@@ -12,15 +14,19 @@ type AuthClient struct {
 	service  pb.AuthServiceClient
 	username string
 	password string
+
+	mutex sync.Mutex
+	// refresh token of the last login or refresh, empty before the first login
+	refreshToken string
 }
 
 // NewAuthClient returns a new auth client
 func NewAuthClient(cc *grpc.ClientConn, username, password string) *AuthClient {
 	service := pb.NewAuthServiceClient(cc)
-	return &AuthClient{service, username, password}
+	return &AuthClient{service: service, username: username, password: password}
 }
 
-// Login login user and returns the access token
+// Login login user and returns the access token, the client keeps the refresh token
 func (client *AuthClient) Login() (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
 	defer cancel()
@@ -35,5 +41,81 @@ func (client *AuthClient) Login() (string, error) {
 		return "", err
 	}
 
+	client.mutex.Lock()
+	client.refreshToken = res.GetRefreshToken()
+	client.mutex.Unlock()
+
 	return res.AccessToken, nil
 }
+
+// Refresh returns a new access token got with the refresh token, which is rotated.
+// It logs in again if there is no refresh token or it cannot be used anymore.
+func (client *AuthClient) Refresh() (string, error) {
+	client.mutex.Lock()
+	refreshToken := client.refreshToken
+	client.mutex.Unlock()
+
+	if len(refreshToken) == 0 {
+		return client.Login()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+
+	res, err := client.service.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: refreshToken})
+	if err != nil {
+		log.Printf("cannot refresh token, login again: %v", err)
+		return client.Login()
+	}
+
+	client.mutex.Lock()
+	client.refreshToken = res.GetRefreshToken()
+	client.mutex.Unlock()
+
+	return res.GetAccessToken(), nil
+}
+
+// Logout revokes the refresh token and every access token issued with it
+func (client *AuthClient) Logout() error {
+	client.mutex.Lock()
+	refreshToken := client.refreshToken
+	client.refreshToken = ""
+	client.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+
+	_, err := client.service.Logout(ctx, &pb.LogoutRequest{RefreshToken: refreshToken})
+	return err
+}
+
+// Register registers the user of the client with the user role
+func (client *AuthClient) Register() (*pb.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+
+	req := &pb.RegisterRequest{
+		Username: client.username,
+		Password: client.password,
+	}
+
+	res, err := client.service.Register(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.GetUser(), nil
+}
+
+// GetAuthPolicy gets the auth policy of the server, which tells which methods need the access token
+func (client *AuthClient) GetAuthPolicy() (*AuthPolicy, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+
+	res, err := client.service.GetAuthPolicy(ctx, &pb.GetAuthPolicyRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAuthPolicy(res.GetPolicy()), nil
+}
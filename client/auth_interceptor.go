@@ -5,19 +5,24 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 	"log"
+	"sync"
 	"time"
 )
 
 // AuthInterceptor is a client interceptor for authentication
 type AuthInterceptor struct {
 	authClient  *AuthClient
-	authMethods map[string]bool
+	authMethods AuthMethods
+
+	mutex       sync.RWMutex
 	accessToken string
 }
 
+// NewAuthInterceptor returns an AuthInterceptor that attaches the access token to the auth methods,
+// like the methods of the AuthPolicy returned by the server
 func NewAuthInterceptor(
 	authClient *AuthClient,
-	authMethods map[string]bool,
+	authMethods AuthMethods,
 	refreshDuration time.Duration,
 	) (*AuthInterceptor, error) {
 	interceptor := &AuthInterceptor{
@@ -45,7 +50,7 @@ func (interceptor *AuthInterceptor) Unary() grpc.UnaryClientInterceptor {
 		) error {
 		log.Printf("--> unary interceptor: %s", method)
 
-		if interceptor.authMethods[method] {
+		if interceptor.authMethods.NeedsToken(method) {
 			return invoker(interceptor.attachToken(ctx), method, req, reply, cc, opts...)
 		}
 
@@ -65,7 +70,7 @@ func (interceptor *AuthInterceptor) Stream() grpc.StreamClientInterceptor {
 		) (grpc.ClientStream, error) {
 		log.Printf("--> stream interceptor: %s", method)
 
-		if interceptor.authMethods[method] {
+		if interceptor.authMethods.NeedsToken(method) {
 			return streamer(interceptor.attachToken(ctx), desc, cc, method, opts...)
 		}
 
@@ -74,6 +79,9 @@ func (interceptor *AuthInterceptor) Stream() grpc.StreamClientInterceptor {
 }
 
 func (interceptor *AuthInterceptor) attachToken(ctx context.Context) context.Context {
+	interceptor.mutex.RLock()
+	defer interceptor.mutex.RUnlock()
+
 	return metadata.AppendToOutgoingContext(ctx, "authorization", interceptor.accessToken)
 }
 
@@ -99,13 +107,17 @@ func (interceptor *AuthInterceptor) scheduleRefreshToken(refreshDuration time.Du
 	return nil
 }
 
+// refreshToken gets a new access token with the refresh token, the password is only sent again
+// when the refresh token cannot be used
 func (interceptor *AuthInterceptor) refreshToken() error {
-	accessToken, err := interceptor.authClient.Login()
+	accessToken, err := interceptor.authClient.Refresh()
 	if err != nil {
 		return err
 	}
 
+	interceptor.mutex.Lock()
 	interceptor.accessToken = accessToken
+	interceptor.mutex.Unlock()
 	log.Printf("token refreshed: %v", accessToken)
 
 	return nil
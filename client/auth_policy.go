@@ -0,0 +1,47 @@
+package client
+
+import (
+	"github.com/treeforest/grpc-pcbook/pb"
+	"path"
+)
+
+// AuthMethods tells which methods the AuthInterceptor attaches the access token to
+type AuthMethods interface {
+	// NeedsToken reports whether the access token is attached to the method
+	NeedsToken(method string) bool
+}
+
+// MethodSet is a fixed set of full method names that need the access token
+type MethodSet map[string]bool
+
+// NeedsToken reports whether the method is in the set
+func (methods MethodSet) NeedsToken(method string) bool {
+	return methods[method]
+}
+
+// AuthPolicy is the auth policy of the server, as returned by AuthClient.GetAuthPolicy.
+// It is a copy taken when it was fetched: the server reloads its policy when the file changes,
+// so a method that a new rule restricts fails with Unauthenticated until the policy is fetched again.
+type AuthPolicy struct {
+	policy *pb.AuthPolicy
+}
+
+// NewAuthPolicy returns the auth policy of the protobuf message
+func NewAuthPolicy(policy *pb.AuthPolicy) *AuthPolicy {
+	return &AuthPolicy{policy: policy}
+}
+
+// NeedsToken reports whether a rule of the policy matches the method: the method either needs
+// the access token, or is public but authenticates the callers who send one.
+// The server denies the methods that no rule matches to everyone.
+func (policy *AuthPolicy) NeedsToken(method string) bool {
+	for _, rule := range policy.policy.GetRules() {
+		for _, pattern := range rule.GetMethods() {
+			if ok, _ := path.Match(pattern, method); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
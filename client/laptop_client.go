@@ -8,6 +8,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 	"io"
 	"log"
 	"os"
@@ -81,6 +82,259 @@ func (laptopClient *LaptopClient) SearchLaptop(filter *pb.Filter) {
 	}
 }
 
+// ListLaptops calls list laptops RPC and returns a page of laptops with the token of the next page
+func (laptopClient *LaptopClient) ListLaptops(
+	filter *pb.Filter,
+	query string,
+	filterExpr string,
+	orderBy string,
+	pageSize int32,
+	pageToken string,
+) ([]*pb.Laptop, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+
+	req := &pb.ListLaptopsRequest{
+		Filter:     filter,
+		Query:      query,
+		FilterExpr: filterExpr,
+		OrderBy:    orderBy,
+		PageSize:   pageSize,
+		PageToken:  pageToken,
+	}
+
+	res, err := laptopClient.service.ListLaptops(ctx, req)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot list laptops: %v", err)
+	}
+
+	return res.GetLaptops(), res.GetNextPageToken(), nil
+}
+
+// GetSearchFacets calls get search facets RPC and returns the facets of the laptops of a search
+func (laptopClient *LaptopClient) GetSearchFacets(
+	filter *pb.Filter,
+	query string,
+	filterExpr string,
+) (*pb.SearchFacets, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &pb.GetSearchFacetsRequest{
+		Filter:     filter,
+		Query:      query,
+		FilterExpr: filterExpr,
+	}
+
+	res, err := laptopClient.service.GetSearchFacets(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get search facets: %v", err)
+	}
+
+	return res.GetFacets(), nil
+}
+
+// WatchLaptops calls watch laptops RPC and passes every event to the received function until ctx is done.
+// It reconnects after the last received event when the server becomes unavailable.
+func (laptopClient *LaptopClient) WatchLaptops(
+	ctx context.Context,
+	filter *pb.Filter,
+	filterExpr string,
+	afterSequence uint64,
+	received func(event *pb.LaptopEvent) error,
+) error {
+	const retryDelay = time.Second
+
+	for {
+		req := &pb.WatchLaptopsRequest{
+			Filter:        filter,
+			FilterExpr:    filterExpr,
+			AfterSequence: afterSequence,
+		}
+
+		err := laptopClient.watchLaptops(ctx, req, func(event *pb.LaptopEvent) error {
+			afterSequence = event.GetSequence()
+			return received(event)
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if status.Code(err) != codes.Unavailable {
+			return err
+		}
+
+		log.Printf("watch laptops interrupted, reconnect after sequence %d: %v", afterSequence, err)
+		select {
+		case <-time.After(retryDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (laptopClient *LaptopClient) watchLaptops(
+	ctx context.Context,
+	req *pb.WatchLaptopsRequest,
+	received func(event *pb.LaptopEvent) error,
+) error {
+	stream, err := laptopClient.service.WatchLaptops(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		res, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		err = received(res.GetEvent())
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// GetLaptop calls get laptop RPC
+func (laptopClient *LaptopClient) GetLaptop(laptopID string) (*pb.Laptop, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+
+	req := &pb.GetLaptopRequest{Id: laptopID}
+	res, err := laptopClient.service.GetLaptop(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get laptop: %v", err)
+	}
+
+	return res.GetLaptop(), nil
+}
+
+// CompareLaptops calls compare laptops RPC and returns the spec by spec comparison of the laptops
+func (laptopClient *LaptopClient) CompareLaptops(laptopIDs ...string) (*pb.LaptopComparison, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+
+	req := &pb.CompareLaptopsRequest{Ids: laptopIDs}
+	res, err := laptopClient.service.CompareLaptops(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot compare laptops: %v", err)
+	}
+
+	return res.GetComparison(), nil
+}
+
+// FindSimilarLaptops calls find similar laptops RPC and returns the k laptops nearest to the laptop,
+// a nil weights gives the same weight to every spec
+func (laptopClient *LaptopClient) FindSimilarLaptops(
+	laptopID string,
+	k uint32,
+	weights *pb.SimilarityWeights,
+) ([]*pb.SimilarLaptop, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+
+	req := &pb.FindSimilarLaptopsRequest{
+		Id:      laptopID,
+		K:       k,
+		Weights: weights,
+	}
+
+	res, err := laptopClient.service.FindSimilarLaptops(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find similar laptops: %v", err)
+	}
+
+	return res.GetLaptops(), nil
+}
+
+// TopRatedLaptops calls top rated laptops RPC and returns the leaderboard of the laptops that pass the filter
+func (laptopClient *LaptopClient) TopRatedLaptops(filter *pb.Filter, limit uint32) ([]*pb.TopRatedLaptopsResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+
+	req := &pb.TopRatedLaptopsRequest{
+		Filter: filter,
+		Limit:  limit,
+	}
+
+	stream, err := laptopClient.service.TopRatedLaptops(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get top rated laptops: %v", err)
+	}
+
+	var laptops []*pb.TopRatedLaptopsResponse
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return laptops, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot receive response: %v", err)
+		}
+
+		laptops = append(laptops, res)
+	}
+}
+
+// UpdateLaptop calls update laptop RPC, only the fields listed in paths are changed.
+// A non-zero laptop revision must match the stored one.
+func (laptopClient *LaptopClient) UpdateLaptop(laptop *pb.Laptop, paths ...string) (*pb.Laptop, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+
+	req := &pb.UpdateLaptopRequest{
+		Laptop:     laptop,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: paths},
+	}
+
+	res, err := laptopClient.service.UpdateLaptop(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot update laptop: %v", err)
+	}
+
+	log.Printf("updated laptop with id: %s", res.GetLaptop().GetId())
+	return res.GetLaptop(), nil
+}
+
+// DeleteLaptop calls delete laptop RPC, a non-zero revision must match the stored one
+func (laptopClient *LaptopClient) DeleteLaptop(laptopID string, revision uint64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+
+	req := &pb.DeleteLaptopRequest{
+		Id:       laptopID,
+		Revision: revision,
+	}
+	_, err := laptopClient.service.DeleteLaptop(ctx, req)
+	if err != nil {
+		return fmt.Errorf("cannot delete laptop: %v", err)
+	}
+
+	log.Printf("deleted laptop with id: %s", laptopID)
+	return nil
+}
+
+// ShareLaptop calls share laptop RPC, which gives the edit rights of the laptop to the added users
+// and takes them back from the removed ones
+func (laptopClient *LaptopClient) ShareLaptop(laptopID string, addEditors []string, removeEditors []string) (*pb.Laptop, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+
+	req := &pb.ShareLaptopRequest{
+		LaptopId:      laptopID,
+		AddEditors:    addEditors,
+		RemoveEditors: removeEditors,
+	}
+
+	res, err := laptopClient.service.ShareLaptop(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot share laptop: %v", err)
+	}
+
+	log.Printf("shared laptop with id: %s with editors: %v", laptopID, res.GetLaptop().GetEditors())
+	return res.GetLaptop(), nil
+}
+
 // UploadImage calls upload image RPC
 func (laptopClient *LaptopClient) UploadImage(laptopID string, imagePath string) {
 	file, err := os.Open(imagePath)
@@ -195,4 +449,18 @@ func (laptopClient *LaptopClient) RateLaptop(laptopIDs []string, scores []float6
 
 	err = <- waitResponse
 	return err
-}
\ No newline at end of file
+}
+
+// GetLaptopRating calls get laptop rating RPC and returns the rating of the laptop with the score of the user
+func (laptopClient *LaptopClient) GetLaptopRating(laptopID string) (*pb.GetLaptopRatingResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+
+	req := &pb.GetLaptopRatingRequest{LaptopId: laptopID}
+	res, err := laptopClient.service.GetLaptopRating(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get laptop rating: %v", err)
+	}
+
+	return res, nil
+}
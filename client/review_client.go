@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"google.golang.org/grpc"
+	"time"
+)
+
+// ReviewClient is a client to call review service RPCs
+type ReviewClient struct {
+	service pb.ReviewServiceClient
+}
+
+// NewReviewClient returns a review client
+func NewReviewClient(cc *grpc.ClientConn) *ReviewClient {
+	service := pb.NewReviewServiceClient(cc)
+	return &ReviewClient{service: service}
+}
+
+// SubmitReview calls submit review RPC, a zero score doesn't rate the laptop
+func (reviewClient *ReviewClient) SubmitReview(laptopID string, text string, score float64) (*pb.Review, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &pb.SubmitReviewRequest{
+		LaptopId: laptopID,
+		Text:     text,
+		Score:    score,
+	}
+
+	res, err := reviewClient.service.SubmitReview(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot submit review: %v", err)
+	}
+
+	return res.GetReview(), nil
+}
+
+// EditReview calls edit review RPC, a zero score keeps the score of the review
+func (reviewClient *ReviewClient) EditReview(id string, text string, score float64) (*pb.Review, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &pb.EditReviewRequest{
+		Id:    id,
+		Text:  text,
+		Score: score,
+	}
+
+	res, err := reviewClient.service.EditReview(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot edit review: %v", err)
+	}
+
+	return res.GetReview(), nil
+}
+
+// DeleteReview calls delete review RPC
+func (reviewClient *ReviewClient) DeleteReview(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := reviewClient.service.DeleteReview(ctx, &pb.DeleteReviewRequest{Id: id})
+	if err != nil {
+		return fmt.Errorf("cannot delete review: %v", err)
+	}
+
+	return nil
+}
+
+// ListReviews calls list reviews RPC and returns a page of reviews with the token of the next page,
+// which is empty after the last page
+func (reviewClient *ReviewClient) ListReviews(
+	laptopID string,
+	reviewStatus pb.Review_ModerationStatus,
+	pageSize int32,
+	pageToken string,
+) ([]*pb.Review, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &pb.ListReviewsRequest{
+		LaptopId:  laptopID,
+		Status:    reviewStatus,
+		PageSize:  pageSize,
+		PageToken: pageToken,
+	}
+
+	res, err := reviewClient.service.ListReviews(ctx, req)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot list reviews: %v", err)
+	}
+
+	return res.GetReviews(), res.GetNextPageToken(), nil
+}
+
+// ApproveReview calls approve review RPC
+func (reviewClient *ReviewClient) ApproveReview(id string) (*pb.Review, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := reviewClient.service.ApproveReview(ctx, &pb.ApproveReviewRequest{Id: id})
+	if err != nil {
+		return nil, fmt.Errorf("cannot approve review: %v", err)
+	}
+
+	return res.GetReview(), nil
+}
+
+// RejectReview calls reject review RPC
+func (reviewClient *ReviewClient) RejectReview(id string, reason string) (*pb.Review, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &pb.RejectReviewRequest{
+		Id:     id,
+		Reason: reason,
+	}
+
+	res, err := reviewClient.service.RejectReview(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reject review: %v", err)
+	}
+
+	return res.GetReview(), nil
+}
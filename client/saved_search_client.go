@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"google.golang.org/grpc"
+	"time"
+)
+
+// SavedSearchClient is a client to call saved search service RPCs
+type SavedSearchClient struct {
+	service pb.SavedSearchServiceClient
+}
+
+// NewSavedSearchClient returns a saved search client
+func NewSavedSearchClient(cc *grpc.ClientConn) *SavedSearchClient {
+	service := pb.NewSavedSearchServiceClient(cc)
+	return &SavedSearchClient{service: service}
+}
+
+// CreateSavedSearch calls create saved search RPC
+func (savedSearchClient *SavedSearchClient) CreateSavedSearch(
+	name string,
+	filter *pb.Filter,
+	filterExpr string,
+) (*pb.SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &pb.CreateSavedSearchRequest{
+		Name:       name,
+		Filter:     filter,
+		FilterExpr: filterExpr,
+	}
+
+	res, err := savedSearchClient.service.CreateSavedSearch(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create saved search: %v", err)
+	}
+
+	return res.GetSavedSearch(), nil
+}
+
+// ListSavedSearches calls list saved searches RPC
+func (savedSearchClient *SavedSearchClient) ListSavedSearches() ([]*pb.SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := savedSearchClient.service.ListSavedSearches(ctx, &pb.ListSavedSearchesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list saved searches: %v", err)
+	}
+
+	return res.GetSavedSearches(), nil
+}
+
+// DeleteSavedSearch calls delete saved search RPC
+func (savedSearchClient *SavedSearchClient) DeleteSavedSearch(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := savedSearchClient.service.DeleteSavedSearch(ctx, &pb.DeleteSavedSearchRequest{Id: id})
+	if err != nil {
+		return fmt.Errorf("cannot delete saved search: %v", err)
+	}
+
+	return nil
+}
+
+// WatchSavedSearchMatches calls watch saved search matches RPC and passes every match
+// to the received function until ctx is done
+func (savedSearchClient *SavedSearchClient) WatchSavedSearchMatches(
+	ctx context.Context,
+	afterSequence uint64,
+	received func(match *pb.SavedSearchMatch) error,
+) error {
+	req := &pb.WatchSavedSearchMatchesRequest{AfterSequence: afterSequence}
+	stream, err := savedSearchClient.service.WatchSavedSearchMatches(ctx, req)
+	if err != nil {
+		return fmt.Errorf("cannot watch saved search matches: %v", err)
+	}
+
+	for {
+		res, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("cannot receive saved search match: %v", err)
+		}
+
+		err = received(res.GetMatch())
+		if err != nil {
+			return err
+		}
+	}
+}
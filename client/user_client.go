@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"google.golang.org/grpc"
+	"time"
+)
+
+// UserClient is a client to call the user RPCs of the auth service that need an access token
+type UserClient struct {
+	service pb.AuthServiceClient
+}
+
+// NewUserClient returns a user client, the connection must attach the access token
+func NewUserClient(cc *grpc.ClientConn) *UserClient {
+	service := pb.NewAuthServiceClient(cc)
+	return &UserClient{service: service}
+}
+
+// ChangePassword calls change password RPC to change the password of the logged in user
+func (userClient *UserClient) ChangePassword(oldPassword string, newPassword string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &pb.ChangePasswordRequest{
+		OldPassword: oldPassword,
+		NewPassword: newPassword,
+	}
+
+	_, err := userClient.service.ChangePassword(ctx, req)
+	if err != nil {
+		return fmt.Errorf("cannot change password: %v", err)
+	}
+
+	return nil
+}
+
+// ListUsers calls list users RPC and returns a page of users with the token of the next page
+func (userClient *UserClient) ListUsers(pageSize int32, pageToken string) ([]*pb.User, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &pb.ListUsersRequest{
+		PageSize:  pageSize,
+		PageToken: pageToken,
+	}
+
+	res, err := userClient.service.ListUsers(ctx, req)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot list users: %v", err)
+	}
+
+	return res.GetUsers(), res.GetNextPageToken(), nil
+}
+
+// SetUserRole calls set user role RPC
+func (userClient *UserClient) SetUserRole(username string, role string) (*pb.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &pb.SetUserRoleRequest{
+		Username: username,
+		Role:     role,
+	}
+
+	res, err := userClient.service.SetUserRole(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot set user role: %v", err)
+	}
+
+	return res.GetUser(), nil
+}
+
+// DisableUser calls disable user RPC, or enables the user again if enable is true
+func (userClient *UserClient) DisableUser(username string, enable bool) (*pb.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &pb.DisableUserRequest{
+		Username: username,
+		Enable:   enable,
+	}
+
+	res, err := userClient.service.DisableUser(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot disable user: %v", err)
+	}
+
+	return res.GetUser(), nil
+}
+
+// CreateAPIKey calls create API key RPC and returns the key with its secret,
+// which cannot be retrieved again. The key never expires if expiresAt is zero.
+func (userClient *UserClient) CreateAPIKey(name string, role string, expiresAt time.Time) (*pb.APIKey, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &pb.CreateAPIKeyRequest{
+		Name: name,
+		Role: role,
+	}
+
+	if !expiresAt.IsZero() {
+		expiresAtProto, err := ptypes.TimestampProto(expiresAt)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid expiry: %v", err)
+		}
+		req.ExpiresAt = expiresAtProto
+	}
+
+	res, err := userClient.service.CreateAPIKey(ctx, req)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot create API key: %v", err)
+	}
+
+	return res.GetApiKey(), res.GetKey(), nil
+}
+
+// ListAPIKeys calls list API keys RPC
+func (userClient *UserClient) ListAPIKeys() ([]*pb.APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := userClient.service.ListAPIKeys(ctx, &pb.ListAPIKeysRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list API keys: %v", err)
+	}
+
+	return res.GetApiKeys(), nil
+}
+
+// RevokeAPIKey calls revoke API key RPC
+func (userClient *UserClient) RevokeAPIKey(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := userClient.service.RevokeAPIKey(ctx, &pb.RevokeAPIKeyRequest{Id: id})
+	if err != nil {
+		return fmt.Errorf("cannot revoke API key: %v", err)
+	}
+
+	return nil
+}
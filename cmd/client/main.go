@@ -12,6 +12,7 @@ import (
 	"google.golang.org/grpc/credentials"
 	"io/ioutil"
 	"log"
+	"os"
 	"strings"
 	"time"
 )
@@ -72,21 +73,14 @@ func testRateLaptop(laptopClient *client.LaptopClient) {
 	}
 }
 
+// passwordEnv is the environment variable of the password of the user, which is not a flag
+// so that it doesn't show in the process list
+const passwordEnv = "PCBOOK_PASSWORD"
+
 const (
-	username = "admin1"
-	password = "secret"
-	refreshDuration = 30 * time.Second
+	refreshDuration = 10 * time.Minute
 )
 
-func authMethods() map[string]bool {
-	const laptopServicePath = "/techschool.pcbook.LaptopService/"
-	return map[string]bool{
-		laptopServicePath + "CreateLaptop": true,
-		laptopServicePath + "UploadImage":  true,
-		laptopServicePath + "RateLaptop":   true,
-	}
-}
-
 func loadTLSCredentials() (credentials.TransportCredentials, error) {
 	// Load certificate of the CA who signed server's certificate
 	pemServerCA, err := ioutil.ReadFile("cert/ca-cert.pem")
@@ -114,9 +108,46 @@ func loadTLSCredentials() (credentials.TransportCredentials, error) {
 	return credentials.NewTLS(config), nil
 }
 
+// dialAuthenticated dials a connection that sends the API key with every RPC if there is one,
+// or else the access token of the user with the RPCs that need it
+func dialAuthenticated(
+	cc *grpc.ClientConn,
+	serverAddress string,
+	transportOption grpc.DialOption,
+	apiKey string,
+	enableTLS bool,
+	username string,
+	password string,
+) (*grpc.ClientConn, error) {
+	if apiKey != "" {
+		apiKeyCredentials := client.NewAPIKeyCredentials(apiKey, enableTLS)
+		return grpc.Dial(serverAddress, transportOption, grpc.WithPerRPCCredentials(apiKeyCredentials))
+	}
+
+	authClient := client.NewAuthClient(cc, username, password)
+	policy, err := authClient.GetAuthPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("cannot get auth policy: %w", err)
+	}
+
+	interceptor, err := client.NewAuthInterceptor(authClient, policy, refreshDuration)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create auth interceptor: %w", err)
+	}
+
+	return grpc.Dial(
+		serverAddress,
+		transportOption,
+		grpc.WithUnaryInterceptor(interceptor.Unary()),
+		grpc.WithStreamInterceptor(interceptor.Stream()),
+	)
+}
+
 func main() {
 	serverAddress := flag.String("address", "", "the server address")
 	enableILS := flag.Bool("tls", false, "enable SSL/TLS")
+	apiKey := flag.String("api-key", "", "API key to send instead of logging in with the username and password")
+	username := flag.String("username", "admin1", "username to log in with, the password is read from "+passwordEnv)
 	flag.Parse()
 	log.Printf("dial server %s, TLS = %t", *serverAddress, *enableILS)
 
@@ -137,18 +168,7 @@ func main() {
 		log.Fatal("cannot dial server: ", err)
 	}
 
-	authClient := client.NewAuthClient(cc1, username, password)
-	interceptor, err := client.NewAuthInterceptor(authClient, authMethods(), refreshDuration)
-	if err != nil {
-		log.Fatal("cannot create auth interceptor: ", err)
-	}
-
-	cc2, err := grpc.Dial(
-		*serverAddress,
-		transportOption,
-		grpc.WithUnaryInterceptor(interceptor.Unary()),
-		grpc.WithStreamInterceptor(interceptor.Stream()),
-		)
+	cc2, err := dialAuthenticated(cc1, *serverAddress, transportOption, *apiKey, *enableILS, *username, os.Getenv(passwordEnv))
 	if err != nil {
 		log.Fatal("cannot dial server: ", err)
 	}
@@ -16,28 +16,52 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 )
 
-func seedUsers(userStore service.UserStore) error {
-	err := createUser(userStore, "admin1", "secret", "admin")
+// adminPasswordEnv is the environment variable of the password of the first admin,
+// which is not a flag so that it doesn't show in the process list
+const adminPasswordEnv = "PCBOOK_ADMIN_PASSWORD"
+
+// createFirstAdmin creates an admin with the password if the user store is empty,
+// the other users then register and the admin gives them their roles
+func createFirstAdmin(userStore service.UserStore, username string, password string) error {
+	empty := true
+	err := userStore.List("", func(user *service.User) bool {
+		empty = false
+		return false
+	})
 	if err != nil {
-		return err
+		return fmt.Errorf("cannot list users: %w", err)
+	}
+	if !empty {
+		return nil
 	}
-	return createUser(userStore, "user1", "secret", "user")
-}
 
-func createUser(userStore service.UserStore, username, password, role string) error {
-	user, err := service.NewUser(username, password, role)
+	if password == "" {
+		log.Printf("no user yet, set %s to create admin %s", adminPasswordEnv, username)
+		return nil
+	}
+
+	admin, err := service.NewAdmin(username, password)
 	if err != nil {
 		return err
 	}
-	return userStore.Save(user)
+
+	log.Printf("create admin %s", username)
+	return userStore.Save(admin)
 }
 
 const (
 	secretKey = "secret"
 	tokenDuration = 15 * time.Minute
+	refreshTokenDuration = 7 * 24 * time.Hour
+	// interval at which the auth policy file is checked for changes
+	authPolicyReloadInterval = 5 * time.Second
 )
 
 const (
@@ -46,15 +70,6 @@ const (
 	clientCACertFile 	= "cert/ca-cert.pem"
 )
 
-func accessibleRoles() map[string][]string {
-	const laptopServicePath = "/techschool.pcbook.LaptopService/"
-	return map[string][]string{
-		laptopServicePath + "CreateLaptop":{"admin"},
-		laptopServicePath + "UploadImage":{"admin"},
-		laptopServicePath + "RateLaptop":{"admin", "user"},
-	}
-}
-
 func loadTLSCredentials() (credentials.TransportCredentials, error) {
 	// Load certificate of the CA who signed client's certificate
 	pemClientCA, err := ioutil.ReadFile(clientCACertFile)
@@ -86,11 +101,23 @@ func loadTLSCredentials() (credentials.TransportCredentials, error) {
 func runRPCServer (
 	authServer pb.AuthServiceServer,
 	laptopServer pb.LaptopServiceServer,
+	savedSearchServer pb.SavedSearchServiceServer,
+	reviewServer pb.ReviewServiceServer,
 	jwtManager *service.JWTManager,
+	userStore service.UserStore,
+	tokenStore service.TokenStore,
+	apiKeyStore service.APIKeyStore,
+	policy service.AuthPolicyProvider,
+	certificateRoles map[string]string,
 	enableTLS bool,
 	listener net.Listener,
 	) error {
-	interceptor := service.NewAuthInterceptor(jwtManager, accessibleRoles())
+	interceptor := service.NewAuthInterceptor(jwtManager, userStore, tokenStore, apiKeyStore, policy)
+	err := interceptor.SetCertificateRoles(certificateRoles)
+	if err != nil {
+		return err
+	}
+
 	serverOptions := []grpc.ServerOption {
 		grpc.UnaryInterceptor(interceptor.Unary()),
 		grpc.StreamInterceptor(interceptor.Stream()),
@@ -109,38 +136,67 @@ func runRPCServer (
 
 	pb.RegisterAuthServiceServer(grpcServer, authServer)
 	pb.RegisterLaptopServiceServer(grpcServer, laptopServer)
+	pb.RegisterSavedSearchServiceServer(grpcServer, savedSearchServer)
+	pb.RegisterReviewServiceServer(grpcServer, reviewServer)
 	reflection.Register(grpcServer)
 
 	log.Printf("Start GRPC server at %s, TLS = %t", listener.Addr().String(), enableTLS)
 	return grpcServer.Serve(listener)
 }
 
+// incomingHeaderMatcher forwards the If-Match and X-Api-Key headers to the gRPC server as is
+func incomingHeaderMatcher(key string) (string, bool) {
+	switch http.CanonicalHeaderKey(key) {
+	case "If-Match":
+		return service.IfMatchHeader, true
+	case "X-Api-Key":
+		return service.APIKeyHeader, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// outgoingHeaderMatcher returns the laptop revision to REST clients as the ETag header
+func outgoingHeaderMatcher(key string) (string, bool) {
+	if key == service.ETagHeader {
+		return "ETag", true
+	}
+	return fmt.Sprintf("%s%s", runtime.MetadataHeaderPrefix, key), true
+}
+
+// runRESTServer serves the REST gateway, which proxies the requests to the gRPC server at grpcEndpoint
 func runRESTServer (
-	authServer pb.AuthServiceServer,
-	laptopServer pb.LaptopServiceServer,
-	jwtManager *service.JWTManager,
 	enableTLS bool,
 	listener net.Listener,
 	grpcEndpoint string,
 ) error {
-	mux := runtime.NewServeMux()
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(incomingHeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
+	)
 	dialOptions := []grpc.DialOption{grpc.WithInsecure()}
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// in-process handler
-	// err := pb.RegisterAuthServiceHandlerServer(ctx, mux, authServer)
 	err := pb.RegisterAuthServiceHandlerFromEndpoint(ctx, mux, grpcEndpoint, dialOptions)
 	if err != nil {
 		return err
 	}
 
-	// err = pb.RegisterLaptopServiceHandlerServer(ctx, mux, laptopServer)
 	err = pb.RegisterLaptopServiceHandlerFromEndpoint(ctx, mux, grpcEndpoint, dialOptions)
 	if err != nil {
 		return err
 	}
 
+	err = pb.RegisterSavedSearchServiceHandlerFromEndpoint(ctx, mux, grpcEndpoint, dialOptions)
+	if err != nil {
+		return err
+	}
+
+	err = pb.RegisterReviewServiceHandlerFromEndpoint(ctx, mux, grpcEndpoint, dialOptions)
+	if err != nil {
+		return err
+	}
+
 	log.Printf("Start REST server at %s, TLS = %t", listener.Addr().String(), enableTLS)
 	if enableTLS {
 		return http.ServeTLS(listener, mux, serverCertFile, serverKeyFile)
@@ -148,39 +204,256 @@ func runRESTServer (
 	return http.Serve(listener, mux)
 }
 
+func newLaptopStore(dataDir string) (service.LaptopStore, error) {
+	if dataDir == "" {
+		return service.NewInMemoryLaptopStore(), nil
+	}
+
+	log.Printf("persist laptops to %s", dataDir)
+	return service.NewFileLaptopStore(dataDir)
+}
+
+func newRatingStore(dataDir string) (service.RatingStore, error) {
+	if dataDir == "" {
+		return service.NewInMemoryRatingStore(), nil
+	}
+
+	log.Printf("persist ratings to %s", dataDir)
+	return service.NewFileRatingStore(dataDir)
+}
+
+func newSavedSearchStore(dataDir string) (service.SavedSearchStore, error) {
+	if dataDir == "" {
+		return service.NewInMemorySavedSearchStore(), nil
+	}
+
+	log.Printf("persist saved searches to %s", dataDir)
+	return service.NewFileSavedSearchStore(dataDir)
+}
+
+func newReviewStore(dataDir string) (service.ReviewStore, error) {
+	if dataDir == "" {
+		return service.NewInMemoryReviewStore(), nil
+	}
+
+	log.Printf("persist reviews to %s", dataDir)
+	return service.NewFileReviewStore(dataDir)
+}
+
+func newJWTManager(signingKeyFile string, verificationKeyFiles string) (*service.JWTManager, error) {
+	if signingKeyFile == "" {
+		return service.NewJWTManager(secretKey, tokenDuration), nil
+	}
+
+	signingKey, err := service.LoadSigningKey(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var verificationKeys []*service.SigningKey
+	for _, file := range strings.Split(verificationKeyFiles, ",") {
+		if file == "" {
+			continue
+		}
+
+		key, err := service.LoadVerificationKey(file)
+		if err != nil {
+			return nil, err
+		}
+		verificationKeys = append(verificationKeys, key)
+	}
+
+	log.Printf("sign access tokens with key %s", signingKey.ID)
+	return service.NewKeyJWTManager(signingKey, verificationKeys, tokenDuration)
+}
+
+// parseCertificateRoles parses comma-separated identity=role pairs, the identity can contain = itself
+func parseCertificateRoles(value string) (map[string]string, error) {
+	roles := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if pair == "" {
+			continue
+		}
+
+		i := strings.LastIndex(pair, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("certificate role %q is not an identity=role pair", pair)
+		}
+		roles[pair[:i]] = pair[i+1:]
+	}
+
+	return roles, nil
+}
+
+func newUserStore(dataDir string) (service.UserStore, error) {
+	if dataDir == "" {
+		return service.NewInMemoryUserStore(), nil
+	}
+
+	log.Printf("persist users to %s", dataDir)
+	return service.NewFileUserStore(dataDir)
+}
+
+func newAPIKeyStore(dataDir string) (service.APIKeyStore, error) {
+	if dataDir == "" {
+		return service.NewInMemoryAPIKeyStore(), nil
+	}
+
+	log.Printf("persist API keys to %s", dataDir)
+	return service.NewFileAPIKeyStore(dataDir)
+}
+
+func newTokenStore(dataDir string) (service.TokenStore, error) {
+	if dataDir == "" {
+		return service.NewInMemoryTokenStore(), nil
+	}
+
+	log.Printf("persist tokens to %s", dataDir)
+	return service.NewFileTokenStore(dataDir)
+}
+
+// unlockDataDir releases the lock on the data directory, if the server took one
+var unlockDataDir = func() error { return nil }
+
+// releaseDataDirOnExit releases the lock when the server exits through fatal or fatalf, or is interrupted.
+// A deferred Unlock doesn't run then, and the lock file of the platforms without flock would stay.
+func releaseDataDirOnExit(lock *service.DataDirLock) {
+	unlockDataDir = lock.Unlock
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Printf("received %v, stopping server", sig)
+		err := unlockDataDir()
+		if err != nil {
+			log.Fatal("cannot unlock data directory: ", err)
+		}
+		os.Exit(0)
+	}()
+}
+
+// fatal releases the lock on the data directory and exits like log.Fatal
+func fatal(v ...interface{}) {
+	unlockDataDir()
+	log.Fatal(v...)
+}
+
+// fatalf releases the lock on the data directory and exits like log.Fatalf
+func fatalf(format string, v ...interface{}) {
+	unlockDataDir()
+	log.Fatalf(format, v...)
+}
+
 func main() {
 	port := flag.Int("port", 0, "the server port")
 	enableTLS := flag.Bool("tls", false, "enable SSL/TLS")
 	serverType := flag.String("type", "grpc", "type of server (grpc/rest)")
 	endpoint := flag.String("endpoint", "", "gRPC endpoint")
+	dataDir := flag.String("data-dir", "", "directory to persist laptops, ratings, reviews, saved searches, users, tokens and API keys, keep them in memory if empty")
+	jwtSigningKey := flag.String("jwt-signing-key", "", "PEM file of the RSA or ECDSA private key that signs access tokens, HS256 with a secret key if empty")
+	jwtVerificationKeys := flag.String("jwt-verification-keys", "", "comma-separated PEM files of the previous public keys that still verify access tokens")
+	authPolicy := flag.String("auth-policy", "auth_policy.yaml", "YAML or JSON file of the auth policy, which is reloaded when it changes")
+	certRoles := flag.String("cert-roles", "", "comma-separated identity=role pairs giving a role to the TLS clients whose certificate has the identity, a SAN URI or CN=<common name>")
+	ratingPriorMean := flag.Float64("rating-prior-mean", 5.5, "score a laptop is assumed to have before it is rated")
+	ratingPriorWeight := flag.Float64("rating-prior-weight", 10, "number of scores the rating prior mean counts as, 0 ranks laptops by average score")
+	adminUsername := flag.String("admin-username", "admin1", "username of the admin created with the password of "+adminPasswordEnv+" when there is no user yet")
+	ratingHalfLife := flag.Duration("rating-half-life", 0, "time after which a score counts half when ranking laptops, 0 disables the decay")
 	flag.Parse()
 
-	userStore := service.NewInMemoryUserStore()
-	if err := seedUsers(userStore); err != nil {
-		log.Fatal("cannot seed users")
-	}
-	jwtManager := service.NewJWTManager(secretKey, tokenDuration)
-	authServer := service.NewAuthServer(userStore, jwtManager)
-
-	laptopStore := service.NewInMemoryLaptopStore()
-	imageStore := service.NewDiskImageStore("img")
-	ratingStore := service.NewInMemoryRatingStore()
-	laptopServer := service.NewLaptopServer(laptopStore, imageStore, ratingStore)
-
 	address := fmt.Sprintf("0.0.0.0:%d", *port)
 	listener, err := net.Listen("tcp", address)
 	if err != nil {
 		log.Fatal("cannot start server: ", err)
 	}
 
-	if *serverType == "grpc" {
-		err = runRPCServer(authServer, laptopServer, jwtManager, *enableTLS, listener)
-	} else {
-		err = runRESTServer(authServer, laptopServer, jwtManager, *enableTLS, listener, *endpoint)
+	switch *serverType {
+	case "rest":
+		// the REST server only proxies to the gRPC server, which owns the stores
+		err = runRESTServer(*enableTLS, listener, *endpoint)
+		log.Fatalf("cannot run rest server: %v", err)
+	case "grpc":
+		// served below
+	default:
+		log.Fatalf("unknown server type %s", *serverType)
+	}
+
+	if *dataDir != "" {
+		// a single server process writes the stores of the directory
+		lock, err := service.LockDataDir(*dataDir)
+		if err != nil {
+			log.Fatal("cannot lock data directory: ", err)
+		}
+		releaseDataDirOnExit(lock)
+	}
+
+	certificateRoles, err := parseCertificateRoles(*certRoles)
+	if err != nil {
+		fatal("cannot parse certificate roles: ", err)
+	}
+	if len(certificateRoles) > 0 && !*enableTLS {
+		fatal("certificate roles need TLS")
+	}
+
+	userStore, err := newUserStore(*dataDir)
+	if err != nil {
+		fatal("cannot create user store: ", err)
+	}
+	err = createFirstAdmin(userStore, *adminUsername, os.Getenv(adminPasswordEnv))
+	if err != nil {
+		fatal("cannot create first admin: ", err)
+	}
+	jwtManager, err := newJWTManager(*jwtSigningKey, *jwtVerificationKeys)
+	if err != nil {
+		fatal("cannot create JWT manager: ", err)
+	}
+	tokenStore, err := newTokenStore(*dataDir)
+	if err != nil {
+		fatal("cannot create token store: ", err)
+	}
+	apiKeyStore, err := newAPIKeyStore(*dataDir)
+	if err != nil {
+		fatal("cannot create API key store: ", err)
+	}
+	authServer := service.NewAuthServer(userStore, tokenStore, apiKeyStore, jwtManager, refreshTokenDuration)
+
+	policyFile, err := service.LoadAuthPolicyFile(*authPolicy)
+	if err != nil {
+		fatal("cannot load auth policy: ", err)
 	}
+	go policyFile.Watch(context.Background(), authPolicyReloadInterval)
+	authServer.SetAuthPolicy(policyFile)
 
+	laptopStore, err := newLaptopStore(*dataDir)
+	if err != nil {
+		fatal("cannot create laptop store: ", err)
+	}
+	imageStore := service.NewDiskImageStore("img")
+	ratingStore, err := newRatingStore(*dataDir)
 	if err != nil {
-		log.Fatalf("cannot run %s server: %v", *serverType, err)
+		fatal("cannot create rating store: ", err)
 	}
+	laptopServer := service.NewLaptopServer(laptopStore, imageStore, ratingStore)
+	err = laptopServer.SetRatingScorer(service.RatingScorer{
+		PriorMean:   *ratingPriorMean,
+		PriorWeight: *ratingPriorWeight,
+		HalfLife:    *ratingHalfLife,
+	})
+	if err != nil {
+		fatal("cannot set rating scorer: ", err)
+	}
+	savedSearchStore, err := newSavedSearchStore(*dataDir)
+	if err != nil {
+		fatal("cannot create saved search store: ", err)
+	}
+	savedSearchServer := service.NewSavedSearchServer(savedSearchStore, laptopStore)
+	reviewStore, err := newReviewStore(*dataDir)
+	if err != nil {
+		fatal("cannot create review store: ", err)
+	}
+	reviewServer := service.NewReviewServer(reviewStore, laptopStore, ratingStore)
+
+	err = runRPCServer(authServer, laptopServer, savedSearchServer, reviewServer, jwtManager, userStore, tokenStore, apiKeyStore, policyFile, certificateRoles, *enableTLS, listener)
+	fatalf("cannot run grpc server: %v", err)
 }
 
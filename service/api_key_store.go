@@ -0,0 +1,193 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// APIKey is a key that machine clients send instead of an access token, it grants a role like a user has
+type APIKey struct {
+	ID string
+	// Name identifies the client that uses the key
+	Name string
+	// HashedKey is the SHA-256 hash of the key, the key itself is never stored
+	HashedKey string
+	Role      string
+	// CreatedBy is the username of the admin who created the key
+	CreatedBy string
+	CreatedAt time.Time
+	// ExpiresAt is zero if the key never expires
+	ExpiresAt time.Time
+}
+
+// apiKeyUsernamePrefix prefixes the name of an API key in the username that the clients using it have,
+// no user can have such a username. The names of the stored keys are unique, so that two keys never share
+// the laptops and reviews of a username; an admin can give the name of a deleted key to a new key.
+const apiKeyUsernamePrefix = "apikey:"
+
+// Username returns the username that the clients using the key have
+func (key *APIKey) Username() string {
+	return apiKeyUsernamePrefix + key.Name
+}
+
+// IsExpired reports whether the key is expired at the given time
+func (key *APIKey) IsExpired(now time.Time) bool {
+	return !key.ExpiresAt.IsZero() && now.After(key.ExpiresAt)
+}
+
+// Clone returns a clone of this key
+func (key *APIKey) Clone() *APIKey {
+	other := *key
+	return &other
+}
+
+// APIKeyStore is an interface to store API keys
+type APIKeyStore interface {
+	// Save saves a new API key, returns ErrAlreadyExists if a key has the same ID, name or hash
+	Save(key *APIKey) error
+	// FindByHash finds an API key by the hash of the key, returns nil if there is none
+	FindByHash(hashedKey string) (*APIKey, error)
+	// List calls found with the API keys ordered by creation time until found returns false
+	List(found func(key *APIKey) bool) error
+	// Delete deletes an API key by ID, returns ErrNotFound if there is none
+	Delete(id string) error
+}
+
+// InMemoryAPIKeyStore stores API keys in memory
+type InMemoryAPIKeyStore struct {
+	mutex sync.RWMutex
+	keys  map[string]*APIKey
+	// IDs of the keys by hash
+	ids map[string]string
+	// IDs of the keys by name
+	names map[string]string
+}
+
+// NewInMemoryAPIKeyStore returns a new in-memory API key store
+func NewInMemoryAPIKeyStore() *InMemoryAPIKeyStore {
+	return &InMemoryAPIKeyStore{
+		keys:  make(map[string]*APIKey),
+		ids:   make(map[string]string),
+		names: make(map[string]string),
+	}
+}
+
+// Save saves a new API key, returns ErrAlreadyExists if a key has the same ID, name or hash
+func (store *InMemoryAPIKeyStore) Save(key *APIKey) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.isSaved(key.ID, key.Name, key.HashedKey) {
+		return ErrAlreadyExists
+	}
+
+	store.set(key.Clone())
+	return nil
+}
+
+// FindByHash finds an API key by the hash of the key, returns nil if there is none
+func (store *InMemoryAPIKeyStore) FindByHash(hashedKey string) (*APIKey, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	id, ok := store.ids[hashedKey]
+	if !ok {
+		return nil, nil
+	}
+
+	return store.keys[id].Clone(), nil
+}
+
+// List calls found with the API keys ordered by creation time until found returns false
+func (store *InMemoryAPIKeyStore) List(found func(key *APIKey) bool) error {
+	store.mutex.RLock()
+	keys := make([]*APIKey, 0, len(store.keys))
+	for _, key := range store.keys {
+		keys = append(keys, key.Clone())
+	}
+	store.mutex.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+
+	for _, key := range keys {
+		if !found(key) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Delete deletes an API key by ID, returns ErrNotFound if there is none
+func (store *InMemoryAPIKeyStore) Delete(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.keys[id] == nil {
+		return ErrNotFound
+	}
+
+	store.unset(id)
+	return nil
+}
+
+// exists reports whether a key has the ID, the name or the hash, an empty name or hash matches no key
+func (store *InMemoryAPIKeyStore) exists(id string, name string, hashedKey string) bool {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return store.isSaved(id, name, hashedKey)
+}
+
+// put stores a clone of the key, replacing the key with the same ID
+func (store *InMemoryAPIKeyStore) put(key *APIKey) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.unset(key.ID)
+	store.set(key.Clone())
+}
+
+// remove removes the key with the ID if there is one
+func (store *InMemoryAPIKeyStore) remove(id string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.unset(id)
+}
+
+// isSaved reports whether a key has the ID, the name or the hash, the caller must hold the lock
+func (store *InMemoryAPIKeyStore) isSaved(id string, name string, hashedKey string) bool {
+	_, hashFound := store.ids[hashedKey]
+	_, nameFound := store.names[name]
+	return (hashedKey != "" && hashFound) || (name != "" && nameFound) || store.keys[id] != nil
+}
+
+// set stores the key and indexes it by hash and name, the caller must hold the write lock
+func (store *InMemoryAPIKeyStore) set(key *APIKey) {
+	store.keys[key.ID] = key
+	store.ids[key.HashedKey] = key.ID
+	store.names[key.Name] = key.ID
+}
+
+// unset removes the key with the ID and its index entries, the caller must hold the write lock
+func (store *InMemoryAPIKeyStore) unset(id string) {
+	key := store.keys[id]
+	if key == nil {
+		return
+	}
+
+	delete(store.keys, id)
+	if store.ids[key.HashedKey] == id {
+		delete(store.ids, key.HashedKey)
+	}
+	if store.names[key.Name] == id {
+		delete(store.names, key.Name)
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInMemoryAPIKeyStore(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	newStore := func(t *testing.T) APIKeyStore {
+		return NewInMemoryAPIKeyStore()
+	}
+
+	t.Run("save_and_find", func(t *testing.T) {
+		store := newStore(t)
+
+		key := &APIKey{ID: "id1", Name: "ci", HashedKey: "hash1", Role: userRole, CreatedBy: "admin1", CreatedAt: now}
+		require.NoError(t, store.Save(key))
+		require.Equal(t, ErrAlreadyExists, store.Save(&APIKey{ID: "id1", HashedKey: "hash2", CreatedAt: now}))
+		require.Equal(t, ErrAlreadyExists, store.Save(&APIKey{ID: "id2", HashedKey: "hash1", CreatedAt: now}))
+		require.Equal(t, ErrAlreadyExists, store.Save(&APIKey{ID: "id2", Name: "ci", HashedKey: "hash2", CreatedAt: now}))
+
+		other, err := store.FindByHash("hash1")
+		require.NoError(t, err)
+		require.Equal(t, key, other)
+
+		// the found key is a clone
+		other.Role = adminRole
+		other, err = store.FindByHash("hash1")
+		require.NoError(t, err)
+		require.Equal(t, userRole, other.Role)
+
+		other, err = store.FindByHash("hash2")
+		require.NoError(t, err)
+		require.Nil(t, other)
+	})
+
+	t.Run("list_and_delete", func(t *testing.T) {
+		store := newStore(t)
+
+		for i, id := range []string{"id3", "id1", "id2"} {
+			key := &APIKey{ID: id, HashedKey: "hash-" + id, CreatedAt: now.Add(time.Duration(i) * time.Minute)}
+			require.NoError(t, store.Save(key))
+		}
+
+		listIDs := func() []string {
+			var ids []string
+			err := store.List(func(key *APIKey) bool {
+				ids = append(ids, key.ID)
+				return true
+			})
+			require.NoError(t, err)
+			return ids
+		}
+
+		require.Equal(t, []string{"id3", "id1", "id2"}, listIDs())
+
+		require.NoError(t, store.Delete("id1"))
+		require.Equal(t, ErrNotFound, store.Delete("id1"))
+		require.Equal(t, []string{"id3", "id2"}, listIDs())
+
+		other, err := store.FindByHash("hash-id1")
+		require.NoError(t, err)
+		require.Nil(t, other)
+	})
+
+	t.Run("reuse_name", func(t *testing.T) {
+		store := newStore(t)
+
+		require.NoError(t, store.Save(&APIKey{ID: "id1", Name: "ci", HashedKey: "hash1", CreatedAt: now}))
+		require.NoError(t, store.Delete("id1"))
+
+		// the name of a deleted key is free again
+		require.NoError(t, store.Save(&APIKey{ID: "id2", Name: "ci", HashedKey: "hash2", CreatedAt: now}))
+		require.Equal(t, ErrAlreadyExists, store.Save(&APIKey{ID: "id3", Name: "ci", HashedKey: "hash3", CreatedAt: now}))
+	})
+}
+
+func TestAPIKeyIsExpired(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	require.False(t, (&APIKey{}).IsExpired(now))
+	require.False(t, (&APIKey{ExpiresAt: now.Add(time.Minute)}).IsExpired(now))
+	require.True(t, (&APIKey{ExpiresAt: now.Add(-time.Minute)}).IsExpired(now))
+}
+
+func TestFileAPIKeyStore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createdAt := time.Unix(1600000000, 0)
+	expiresAt := createdAt.Add(24 * time.Hour)
+
+	store, err := NewFileAPIKeyStore(dir)
+	require.NoError(t, err)
+
+	key := &APIKey{
+		ID:        "id1",
+		Name:      "ci",
+		HashedKey: hashToken("secret-key"),
+		Role:      adminRole,
+		CreatedBy: "admin1",
+		CreatedAt: createdAt,
+		ExpiresAt: expiresAt,
+	}
+	require.NoError(t, store.Save(key))
+
+	// a taken name or hash and an unknown ID are rejected before they reach the log
+	require.Equal(t, ErrAlreadyExists, store.Save(&APIKey{ID: "id2", Name: "ci", HashedKey: hashToken("other-key"), CreatedAt: createdAt}))
+	require.Equal(t, ErrAlreadyExists, store.Save(&APIKey{ID: "id2", HashedKey: key.HashedKey, CreatedAt: createdAt}))
+	require.Equal(t, ErrNotFound, store.Delete("id2"))
+	require.Equal(t, 1, store.wal.count)
+
+	require.NoError(t, store.Save(&APIKey{ID: "id2", HashedKey: hashToken("other-key"), CreatedAt: createdAt}))
+	require.NoError(t, store.Delete("id2"))
+	require.NoError(t, store.Close())
+
+	// only the hash of a key reaches the disk
+	data, err := ioutil.ReadFile(filepath.Join(dir, "api_keys.wal"))
+	require.NoError(t, err)
+	require.False(t, strings.Contains(string(data), "secret-key"))
+
+	store, err = NewFileAPIKeyStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	other, err := store.FindByHash(hashToken("secret-key"))
+	require.NoError(t, err)
+	require.NotNil(t, other)
+	require.Equal(t, key.Name, other.Name)
+	require.Equal(t, key.Role, other.Role)
+	require.Equal(t, key.CreatedBy, other.CreatedBy)
+	require.True(t, createdAt.Equal(other.CreatedAt))
+	require.True(t, expiresAt.Equal(other.ExpiresAt))
+
+	other, err = store.FindByHash(hashToken("other-key"))
+	require.NoError(t, err)
+	require.Nil(t, other)
+	// the replayed names are still taken
+	require.Equal(t, ErrAlreadyExists, store.Save(&APIKey{ID: "id3", Name: "ci", HashedKey: hashToken("third-key")}))
+}
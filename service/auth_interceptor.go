@@ -2,22 +2,68 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"log"
+	"time"
 )
 
+// APIKeyHeader is the metadata key of the API key that clients send instead of an access token
+const APIKeyHeader = "x-api-key"
+
 // AuthInterceptor is a server interceptor for authentication and  authorization
 type AuthInterceptor struct {
-	jwtManager      *JWTManager
-	accessibleRoles map[string][]string
+	jwtManager *JWTManager
+	// users whose access tokens are accepted, every token is accepted if it is nil
+	userStore UserStore
+	// revoked access tokens, no token is revoked if it is nil
+	tokenStore TokenStore
+	// API keys accepted in the x-api-key header, API keys are not accepted if it is nil
+	apiKeyStore APIKeyStore
+	// policy that tells which roles can call each method
+	policy AuthPolicyProvider
+	// roles of the client certificate identities, no certificate is accepted if it is empty
+	certificateRoles map[string]string
 }
 
-// NewAuthInterceptor
-func NewAuthInterceptor(jwtManager *JWTManager, accessibleRoles map[string][]string) *AuthInterceptor {
-	return &AuthInterceptor{jwtManager, accessibleRoles}
+// NewAuthInterceptor returns a new AuthInterceptor. If userStore is not nil, the access tokens of
+// disabled or unknown users are rejected and the users have the role they currently have in the store.
+// If tokenStore is not nil, the revoked access tokens are rejected.
+// If apiKeyStore is not nil, the API keys it has are accepted instead of an access token.
+func NewAuthInterceptor(
+	jwtManager *JWTManager,
+	userStore UserStore,
+	tokenStore TokenStore,
+	apiKeyStore APIKeyStore,
+	policy AuthPolicyProvider,
+) *AuthInterceptor {
+	return &AuthInterceptor{
+		jwtManager:  jwtManager,
+		userStore:   userStore,
+		tokenStore:  tokenStore,
+		apiKeyStore: apiKeyStore,
+		policy:      policy,
+	}
+}
+
+// SetCertificateRoles sets the roles of the clients whose verified certificate has one of the identities,
+// a SAN URI or the subject common name prefixed by CN=. A client sending neither an access token nor
+// an API key is authenticated by the role of the first identity of its certificate found in roles.
+// The roles must be declared by the current policy.
+func (interceptor *AuthInterceptor) SetCertificateRoles(roles map[string]string) error {
+	policy := interceptor.policy.Policy()
+	for identity, role := range roles {
+		if !policy.HasRole(role) {
+			return fmt.Errorf("unknown role %q of certificate identity %s", role, identity)
+		}
+	}
+
+	interceptor.certificateRoles = roles
+	return nil
 }
 
 // Unary returns a server interceptor function to authenticate and authorize unary RPC
@@ -30,11 +76,14 @@ func (interceptor *AuthInterceptor) Unary() grpc.UnaryServerInterceptor {
 	) (resp interface{}, err error) {
 		log.Println("--> unary interceptor: ", info.FullMethod)
 
-		err = interceptor.authorize(ctx, info.FullMethod)
+		claims, err := interceptor.authorize(ctx, info.FullMethod)
 		if err != nil {
 			return nil, err
 		}
 
+		if claims != nil {
+			ctx = contextWithUserClaims(ctx, claims)
+		}
 		return handler(ctx, req)
 	}
 }
@@ -49,43 +98,191 @@ func (interceptor *AuthInterceptor) Stream() grpc.StreamServerInterceptor {
 	) error {
 		log.Println("--> stream interceptor: ", info.FullMethod)
 
-		err := interceptor.authorize(stream.Context(), info.FullMethod)
+		claims, err := interceptor.authorize(stream.Context(), info.FullMethod)
 		if err != nil {
 			return err
 		}
 
+		if claims != nil {
+			stream = &authorizedServerStream{
+				ServerStream: stream,
+				ctx:          contextWithUserClaims(stream.Context(), claims),
+			}
+		}
 		return handler(srv, stream)
 	}
 }
 
-func (interceptor *AuthInterceptor) authorize(ctx context.Context, method string) error {
-	accessibleRoles, ok := interceptor.accessibleRoles[method]
-	if !ok {
+// authorize returns the claims of the caller if the method requires authentication.
+// A method that everyone can access still gets the claims of an authenticated caller.
+func (interceptor *AuthInterceptor) authorize(ctx context.Context, method string) (*UserClaims, error) {
+	// the policy may be reloaded, the same one is used for the whole RPC
+	policy := interceptor.policy.Policy()
+	if !policy.RequiresAuthentication(method) {
 		// everyone can access
-		return nil
+		return interceptor.optionalClaims(ctx), nil
 	}
 
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return status.Error(codes.Unauthenticated, "metadata is not provided")
+	claims, err := interceptor.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !policy.Allows(method, claims.Role) {
+		return nil, status.Error(codes.PermissionDenied, "no permission to access this RPC")
+	}
+
+	return claims, nil
+}
+
+// optionalClaims returns the claims of the caller, nil if it is not authenticated
+func (interceptor *AuthInterceptor) optionalClaims(ctx context.Context) *UserClaims {
+	claims, err := interceptor.authenticate(ctx)
+	if err != nil {
+		return nil
 	}
 
+	return claims
+}
+
+// authenticate returns the claims of the access token of the request, or else of its API key,
+// or else of the client certificate
+func (interceptor *AuthInterceptor) authenticate(ctx context.Context) (*UserClaims, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
 	values := md["authorization"]
 	if len(values) == 0 {
-		return status.Error(codes.Unauthenticated, "authorization token is not provided")
+		if apiKeys := md[APIKeyHeader]; len(apiKeys) > 0 && interceptor.apiKeyStore != nil {
+			return interceptor.authenticateAPIKey(apiKeys[0])
+		}
+		if claims := interceptor.certificateClaims(ctx); claims != nil {
+			return claims, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "authorization token is not provided")
 	}
 
 	accessToken := values[0]
 	claims, err := interceptor.jwtManager.Verify(accessToken)
 	if err != nil {
-		return status.Errorf(codes.Unauthenticated, "access token is invalid: %v", err)
+		return nil, status.Errorf(codes.Unauthenticated, "access token is invalid: %v", err)
+	}
+
+	err = interceptor.checkRevoked(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return interceptor.checkUser(claims)
+}
+
+// authenticateAPIKey returns the claims of an API key, which have the role of the key
+// and the ID of the key as token ID
+func (interceptor *AuthInterceptor) authenticateAPIKey(key string) (*UserClaims, error) {
+	apiKey, err := interceptor.apiKeyStore.FindByHash(hashToken(key))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot find API key: %v", err)
+	}
+	if apiKey == nil {
+		return nil, status.Error(codes.Unauthenticated, "API key is invalid")
+	}
+	if apiKey.IsExpired(time.Now()) {
+		return nil, status.Error(codes.Unauthenticated, "API key is expired")
+	}
+
+	claims := &UserClaims{
+		StandardClaims: jwt.StandardClaims{Id: apiKey.ID},
+		Username:       apiKey.Username(),
+		Role:           apiKey.Role,
+	}
+	if !apiKey.ExpiresAt.IsZero() {
+		claims.ExpiresAt = apiKey.ExpiresAt.Unix()
+	}
+	return claims, nil
+}
+
+// certificateClaims returns the claims of the verified client certificate, which have the role
+// of the first identity of the certificate that has one, nil if there is none
+func (interceptor *AuthInterceptor) certificateClaims(ctx context.Context) *UserClaims {
+	if len(interceptor.certificateRoles) == 0 {
+		return nil
 	}
 
-	for _, role := range accessibleRoles {
-		if role == claims.Role {
-			return nil
+	cert := peerCertificate(ctx)
+	if cert == nil {
+		return nil
+	}
+
+	for _, identity := range certificateIdentities(cert) {
+		if role, ok := interceptor.certificateRoles[identity]; ok {
+			claims := &UserClaims{
+				Username: certificateUsernamePrefix + identity,
+				Role:     role,
+			}
+			return claims
 		}
 	}
 
-	return status.Error(codes.PermissionDenied, "no permission to access this RPC")
-}
\ No newline at end of file
+	return nil
+}
+
+// checkRevoked returns an error if the access token of the claims is revoked
+func (interceptor *AuthInterceptor) checkRevoked(claims *UserClaims) error {
+	if interceptor.tokenStore == nil {
+		return nil
+	}
+
+	revoked, err := interceptor.tokenStore.IsRevoked(claims.Id, claims.Family)
+	if err != nil {
+		return status.Errorf(codes.Internal, "cannot check access token: %v", err)
+	}
+	if revoked {
+		return status.Error(codes.Unauthenticated, "access token is revoked")
+	}
+
+	return nil
+}
+
+// checkUser returns the claims with the current role of the user,
+// or an error if the user is disabled or doesn't exist anymore
+func (interceptor *AuthInterceptor) checkUser(claims *UserClaims) (*UserClaims, error) {
+	if interceptor.userStore == nil {
+		return claims, nil
+	}
+
+	user, err := interceptor.userStore.Find(claims.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot find user: %v", err)
+	}
+	if user == nil {
+		return nil, status.Errorf(codes.Unauthenticated, "user %s doesn't exist", claims.Username)
+	}
+	if user.Disabled {
+		return nil, status.Errorf(codes.PermissionDenied, "user %s is disabled", claims.Username)
+	}
+
+	current := *claims
+	current.Role = user.Role
+	return &current, nil
+}
+
+type userClaimsKey struct{}
+
+// UserClaimsFromContext returns the claims of the user authorized by the AuthInterceptor
+func UserClaimsFromContext(ctx context.Context) (*UserClaims, bool) {
+	claims, ok := ctx.Value(userClaimsKey{}).(*UserClaims)
+	return claims, ok
+}
+
+func contextWithUserClaims(ctx context.Context, claims *UserClaims) context.Context {
+	return context.WithValue(ctx, userClaimsKey{}, claims)
+}
+
+// authorizedServerStream is a server stream whose context carries the user claims
+type authorizedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *authorizedServerStream) Context() context.Context {
+	return stream.ctx
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/stretchr/testify/require"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCertificateIdentities(t *testing.T) {
+	t.Parallel()
+
+	uri, err := url.Parse("spiffe://pcbook/importer")
+	require.NoError(t, err)
+
+	cert := &x509.Certificate{
+		Subject: pkix.Name{CommonName: "importer.pcbook.internal"},
+		URIs:    []*url.URL{uri},
+	}
+	require.Equal(t, []string{"spiffe://pcbook/importer", "CN=importer.pcbook.internal"}, certificateIdentities(cert))
+	require.Empty(t, certificateIdentities(&x509.Certificate{}))
+}
+
+func TestAuthInterceptorCertificateRoles(t *testing.T) {
+	t.Parallel()
+
+	const adminMethod = "/techschool.pcbook.AuthService/ListUsers"
+	const userMethod = "/techschool.pcbook.AuthService/ChangePassword"
+	const publicMethod = "/techschool.pcbook.LaptopService/SearchLaptop"
+
+	jwtManager := NewJWTManager("secret", time.Minute)
+	interceptor := NewAuthInterceptor(jwtManager, nil, nil, nil, NewAuthPolicy(map[string][]string{
+		adminMethod:  {adminRole},
+		userMethod:   {userRole},
+		publicMethod: nil,
+	}))
+	// the roles are those of the policy
+	require.Error(t, interceptor.SetCertificateRoles(map[string]string{"CN=seller": sellerRole}))
+	require.Error(t, interceptor.SetCertificateRoles(map[string]string{"CN=importer": "root"}))
+	require.NoError(t, interceptor.SetCertificateRoles(map[string]string{
+		"spiffe://pcbook/importer": adminRole,
+		"CN=indexer":               userRole,
+	}))
+
+	uri, err := url.Parse("spiffe://pcbook/importer")
+	require.NoError(t, err)
+	importer := &x509.Certificate{Subject: pkix.Name{CommonName: "indexer"}, URIs: []*url.URL{uri}}
+	indexer := &x509.Certificate{Subject: pkix.Name{CommonName: "indexer"}}
+	unknown := &x509.Certificate{Subject: pkix.Name{CommonName: "unknown"}}
+
+	withCertificate := func(cert *x509.Certificate, verified bool) context.Context {
+		state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		if verified {
+			state.VerifiedChains = [][]*x509.Certificate{{cert}}
+		}
+		return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+	}
+
+	userToken, err := jwtManager.Generate(&User{Username: "alice", Role: userRole})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		ctx      context.Context
+		method   string
+		code     codes.Code
+		username string
+	}{
+		{"uri", withCertificate(importer, true), adminMethod, codes.OK, "cert:spiffe://pcbook/importer"},
+		{"common_name", withCertificate(indexer, true), adminMethod, codes.PermissionDenied, ""},
+		{"common_name_public", withCertificate(indexer, true), publicMethod, codes.OK, "cert:CN=indexer"},
+		{"unknown", withCertificate(unknown, true), adminMethod, codes.Unauthenticated, ""},
+		{"not_verified", withCertificate(importer, false), adminMethod, codes.Unauthenticated, ""},
+		{"no_certificate", context.Background(), adminMethod, codes.Unauthenticated, ""},
+		{
+			"access_token_first",
+			metadata.NewIncomingContext(withCertificate(importer, true), metadata.Pairs("authorization", userToken)),
+			adminMethod,
+			codes.PermissionDenied,
+			"",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var username string
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				if claims, ok := UserClaimsFromContext(ctx); ok {
+					username = claims.Username
+				}
+				return nil, nil
+			}
+
+			info := &grpc.UnaryServerInfo{FullMethod: tc.method}
+			_, err := interceptor.Unary()(tc.ctx, nil, info, handler)
+			require.Equal(t, tc.code, status.Code(err))
+			require.Equal(t, tc.username, username)
+		})
+	}
+}
+
+func TestAuthInterceptorMutualTLS(t *testing.T) {
+	t.Parallel()
+
+	ca, caKey := newTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "pcbook test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	serverCert, serverKey := newTestCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "localhost"},
+		IPAddresses: []net.IP{net.IPv6loopback, net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+	uri, err := url.Parse("spiffe://pcbook/importer")
+	require.NoError(t, err)
+	clientCert, clientKey := newTestCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "importer"},
+		URIs:        []*url.URL{uri},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+
+	certPool := x509.NewCertPool()
+	certPool.AddCert(ca)
+
+	userStore := NewInMemoryUserStore()
+	jwtManager := NewJWTManager("secret", time.Minute)
+	authServer := NewAuthServer(userStore, NewInMemoryTokenStore(), NewInMemoryAPIKeyStore(), jwtManager, time.Hour)
+	interceptor := NewAuthInterceptor(jwtManager, userStore, nil, nil, NewAuthPolicy(map[string][]string{
+		"/techschool.pcbook.AuthService/ListUsers": {adminRole},
+	}))
+	require.NoError(t, interceptor.SetCertificateRoles(map[string]string{"spiffe://pcbook/importer": adminRole}))
+
+	serverCredentials := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    certPool,
+	})
+	grpcServer := grpc.NewServer(
+		grpc.Creds(serverCredentials),
+		grpc.UnaryInterceptor(interceptor.Unary()),
+	)
+	pb.RegisterAuthServiceServer(grpcServer, authServer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	clientCredentials := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{clientCert.Raw}, PrivateKey: clientKey}},
+		RootCAs:      certPool,
+	})
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(clientCredentials))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// the client calls an admin RPC without logging in
+	_, err = pb.NewAuthServiceClient(conn).ListUsers(context.Background(), &pb.ListUsersRequest{})
+	require.NoError(t, err)
+}
+
+// newTestCertificate returns a certificate of the template signed by the parent,
+// or a self-signed certificate if the parent is nil
+func newTestCertificate(
+	t *testing.T,
+	template *x509.Certificate,
+	parent *x509.Certificate,
+	parentKey *ecdsa.PrivateKey,
+) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serialNumber, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template.SerialNumber = serialNumber
+	template.NotBefore = time.Now().Add(-time.Minute)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	if parent == nil {
+		parent = template
+		parentKey = key
+	}
+
+	data, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(data)
+	require.NoError(t, err)
+	return cert, key
+}
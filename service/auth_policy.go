@@ -0,0 +1,316 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/ghodss/yaml"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"path"
+	"sort"
+	"strings"
+)
+
+// AuthPolicy tells which roles can call each method. A method that only public rules match is public,
+// the others can only be called by authenticated callers whose role is allowed by a rule and denied by none,
+// so a method that no rule matches is denied to everyone.
+type AuthPolicy struct {
+	// roles that each role inherits directly
+	inherits map[string][]string
+	// roles that each role has, itself and the roles it inherits directly or not
+	effectiveRoles map[string]map[string]bool
+	rules          []*authPolicyRule
+}
+
+type authPolicyRule struct {
+	methods []string
+	roles   []string
+	effect  pb.AuthPolicyRule_Effect
+}
+
+// AuthPolicyProvider provides the current auth policy
+type AuthPolicyProvider interface {
+	Policy() *AuthPolicy
+}
+
+// authPolicyConfig is the content of an auth policy file
+type authPolicyConfig struct {
+	Roles map[string]struct {
+		Inherits []string `json:"inherits"`
+	} `json:"roles"`
+	Rules []struct {
+		Methods []string `json:"methods"`
+		Roles   []string `json:"roles"`
+		// allow if empty, deny or public
+		Effect string `json:"effect"`
+	} `json:"rules"`
+}
+
+// NewAuthPolicy returns a policy that allows each method of accessibleRoles only to the listed roles,
+// a method listed without roles is public and every other method is denied. The methods can be patterns.
+func NewAuthPolicy(accessibleRoles map[string][]string) *AuthPolicy {
+	inherits := make(map[string][]string)
+	rules := make([]*authPolicyRule, 0, len(accessibleRoles))
+	for method, roles := range accessibleRoles {
+		effect := pb.AuthPolicyRule_ALLOW
+		if len(roles) == 0 {
+			effect = pb.AuthPolicyRule_PUBLIC
+		}
+
+		for _, role := range roles {
+			inherits[role] = nil
+		}
+		rules = append(rules, &authPolicyRule{methods: []string{method}, roles: roles, effect: effect})
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].methods[0] < rules[j].methods[0]
+	})
+
+	// the roles inherit nothing, so the policy is always valid
+	policy, _ := newAuthPolicy(inherits, rules)
+	return policy
+}
+
+// ParseAuthPolicy parses a YAML or JSON auth policy, which lists the roles with the roles they inherit
+// and the rules that allow or deny methods to roles, or make them public:
+//
+//	roles:
+//	  admin: {inherits: [user]}
+//	  user: {}
+//	rules:
+//	  - methods: ["/techschool.pcbook.LaptopService/*"]
+//	    roles: [admin]
+//	  - methods: ["/techschool.pcbook.LaptopService/DeleteLaptop"]
+//	    roles: [admin]
+//	    effect: deny
+//	  - methods: ["/techschool.pcbook.LaptopService/SearchLaptop"]
+//	    effect: public
+func ParseAuthPolicy(data []byte) (*AuthPolicy, error) {
+	data, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse auth policy: %w", err)
+	}
+
+	config := &authPolicyConfig{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse auth policy: %w", err)
+	}
+
+	inherits := make(map[string][]string, len(config.Roles))
+	for role, roleConfig := range config.Roles {
+		inherits[role] = roleConfig.Inherits
+	}
+
+	rules := make([]*authPolicyRule, 0, len(config.Rules))
+	for _, ruleConfig := range config.Rules {
+		effect, ok := pb.AuthPolicyRule_Effect_value[strings.ToUpper(ruleConfig.Effect)]
+		if !ok && ruleConfig.Effect != "" {
+			return nil, fmt.Errorf("unknown effect %q", ruleConfig.Effect)
+		}
+
+		rules = append(rules, &authPolicyRule{
+			methods: ruleConfig.Methods,
+			roles:   ruleConfig.Roles,
+			effect:  pb.AuthPolicyRule_Effect(effect),
+		})
+	}
+
+	return newAuthPolicy(inherits, rules)
+}
+
+func newAuthPolicy(inherits map[string][]string, rules []*authPolicyRule) (*AuthPolicy, error) {
+	policy := &AuthPolicy{
+		inherits:       inherits,
+		effectiveRoles: make(map[string]map[string]bool, len(inherits)),
+		rules:          rules,
+	}
+
+	for role := range inherits {
+		err := policy.addEffectiveRoles(role, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, rule := range rules {
+		err := policy.validateRule(rule)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return policy, nil
+}
+
+// addEffectiveRoles computes the effective roles of a role, path has the roles that inherit it
+// and are being computed
+func (policy *AuthPolicy) addEffectiveRoles(role string, path []string) error {
+	if policy.effectiveRoles[role] != nil {
+		return nil
+	}
+
+	for _, other := range path {
+		if other == role {
+			return fmt.Errorf("role %s inherits itself through %s", role, strings.Join(path, ", "))
+		}
+	}
+
+	roles := map[string]bool{role: true}
+	for _, inherited := range policy.inherits[role] {
+		if _, ok := policy.inherits[inherited]; !ok {
+			return fmt.Errorf("role %s inherits unknown role %s", role, inherited)
+		}
+
+		err := policy.addEffectiveRoles(inherited, append(path, role))
+		if err != nil {
+			return err
+		}
+
+		for other := range policy.effectiveRoles[inherited] {
+			roles[other] = true
+		}
+	}
+
+	policy.effectiveRoles[role] = roles
+	return nil
+}
+
+func (policy *AuthPolicy) validateRule(rule *authPolicyRule) error {
+	if len(rule.methods) == 0 {
+		return fmt.Errorf("rule has no method")
+	}
+
+	for _, method := range rule.methods {
+		if !strings.HasPrefix(method, "/") {
+			return fmt.Errorf("method %s is not a full method name", method)
+		}
+		if _, err := path.Match(method, ""); err != nil {
+			return fmt.Errorf("invalid method %s: %w", method, err)
+		}
+	}
+
+	if rule.effect == pb.AuthPolicyRule_PUBLIC {
+		if len(rule.roles) > 0 {
+			return fmt.Errorf("public rule of %s has roles", rule.methods[0])
+		}
+		return nil
+	}
+
+	if len(rule.roles) == 0 {
+		return fmt.Errorf("rule of %s has no role", rule.methods[0])
+	}
+
+	for _, role := range rule.roles {
+		if _, ok := policy.inherits[role]; !ok {
+			return fmt.Errorf("rule of %s has unknown role %s", rule.methods[0], role)
+		}
+	}
+
+	return nil
+}
+
+// Policy returns the policy itself, so that a policy that never changes is an AuthPolicyProvider
+func (policy *AuthPolicy) Policy() *AuthPolicy {
+	return policy
+}
+
+// HasRole reports whether the policy declares the role, only such a role can be given to a caller
+func (policy *AuthPolicy) HasRole(role string) bool {
+	_, ok := policy.inherits[role]
+	return ok
+}
+
+// RequiresAuthentication reports whether only authenticated callers can call the method,
+// which is the case of every method but those that only public rules match
+func (policy *AuthPolicy) RequiresAuthentication(method string) bool {
+	public := false
+	for _, rule := range policy.rules {
+		if !rule.matches(method) {
+			continue
+		}
+		if rule.effect != pb.AuthPolicyRule_PUBLIC {
+			return true
+		}
+		public = true
+	}
+
+	return !public
+}
+
+// Allows reports whether the callers with the role can call the method. A rule allowing or denying
+// the method to a role applies to the roles inheriting it as well, and a denying rule wins, so a role
+// cannot be allowed a method that a role it inherits is denied. A method that no rule matches
+// is allowed to no role.
+func (policy *AuthPolicy) Allows(method string, role string) bool {
+	if !policy.RequiresAuthentication(method) {
+		return true
+	}
+
+	roles := policy.effectiveRoles[role]
+	allowed := false
+	for _, rule := range policy.rules {
+		if !rule.matches(method) || rule.effect == pb.AuthPolicyRule_PUBLIC {
+			continue
+		}
+
+		for other := range roles {
+			if !rule.hasRole(other) {
+				continue
+			}
+			if rule.effect == pb.AuthPolicyRule_DENY {
+				return false
+			}
+			allowed = true
+		}
+	}
+
+	return allowed
+}
+
+// Proto returns the policy as a protobuf message
+func (policy *AuthPolicy) Proto() *pb.AuthPolicy {
+	roles := make([]*pb.AuthPolicyRole, 0, len(policy.inherits))
+	for role, inherits := range policy.inherits {
+		roles = append(roles, &pb.AuthPolicyRole{Name: role, Inherits: inherits})
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].GetName() < roles[j].GetName()
+	})
+
+	rules := make([]*pb.AuthPolicyRule, 0, len(policy.rules))
+	for _, rule := range policy.rules {
+		rules = append(rules, &pb.AuthPolicyRule{
+			Methods: rule.methods,
+			Roles:   rule.roles,
+			Effect:  rule.effect,
+		})
+	}
+
+	return &pb.AuthPolicy{Roles: roles, Rules: rules}
+}
+
+// matches reports whether the method matches one of the method patterns of the rule
+func (rule *authPolicyRule) matches(method string) bool {
+	for _, pattern := range rule.methods {
+		// the patterns are validated, so there is no error
+		if ok, _ := path.Match(pattern, method); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (rule *authPolicyRule) hasRole(role string) bool {
+	for _, other := range rule.roles {
+		if other == role {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sync"
+	"time"
+)
+
+// AuthPolicyFile provides the auth policy of a YAML or JSON file, which is reloaded when the file changes
+type AuthPolicyFile struct {
+	filename string
+
+	mutex  sync.RWMutex
+	policy *AuthPolicy
+	// content of the file when it was last read, valid or not
+	data []byte
+}
+
+// LoadAuthPolicyFile loads the auth policy of a file
+func LoadAuthPolicyFile(filename string) (*AuthPolicyFile, error) {
+	file := &AuthPolicyFile{filename: filename}
+
+	_, err := file.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// Policy returns the last valid policy of the file
+func (file *AuthPolicyFile) Policy() *AuthPolicy {
+	file.mutex.RLock()
+	defer file.mutex.RUnlock()
+
+	return file.policy
+}
+
+// Reload loads the policy again if the file changed since it was last read, and reports whether it did.
+// The current policy is kept if the file is invalid, and the error is only returned once per change.
+func (file *AuthPolicyFile) Reload() (bool, error) {
+	data, err := ioutil.ReadFile(file.filename)
+	if err != nil {
+		return false, fmt.Errorf("cannot read auth policy file: %w", err)
+	}
+
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	if file.policy != nil && bytes.Equal(data, file.data) {
+		return false, nil
+	}
+	file.data = data
+
+	policy, err := ParseAuthPolicy(data)
+	if err != nil {
+		return false, fmt.Errorf("invalid auth policy file %s: %w", file.filename, err)
+	}
+
+	file.policy = policy
+	return true, nil
+}
+
+// Watch reloads the policy every interval until the context is done, the errors are logged
+func (file *AuthPolicyFile) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := file.Reload()
+			if err != nil {
+				log.Printf("cannot reload auth policy: %v", err)
+			} else if reloaded {
+				log.Printf("reloaded auth policy %s", file.filename)
+			}
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"google.golang.org/grpc"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+const testAuthPolicy = `
+roles:
+  admin:
+    inherits: [seller]
+  seller:
+    inherits: [user]
+  user: {}
+  auditor: {}
+rules:
+  - methods: ["/techschool.pcbook.LaptopService/*"]
+    roles: [seller]
+  - methods: ["/techschool.pcbook.LaptopService/DeleteLaptop"]
+    roles: [seller]
+    effect: deny
+  - methods: ["/techschool.pcbook.LaptopService/DeleteLaptop"]
+    roles: [admin]
+  - methods: ["/techschool.pcbook.LaptopService/ShareLaptop"]
+    roles: [admin]
+    effect: deny
+  - methods: ["/techschool.pcbook.*/List*"]
+    roles: [user, auditor]
+  - methods: ["/techschool.pcbook.LaptopService/SearchLaptop", "/techschool.pcbook.LaptopService/GetLaptopRating"]
+    effect: public
+  - methods: ["/techschool.pcbook.ReviewService/Get*"]
+    effect: public
+`
+
+func TestAuthPolicyAllows(t *testing.T) {
+	t.Parallel()
+
+	policy, err := ParseAuthPolicy([]byte(testAuthPolicy))
+	require.NoError(t, err)
+
+	const laptopServicePath = "/techschool.pcbook.LaptopService/"
+	const reviewServicePath = "/techschool.pcbook.ReviewService/"
+
+	testCases := []struct {
+		name   string
+		method string
+		// roles allowed to call the method, nil if it is public
+		allowed []string
+	}{
+		{"service_wildcard", laptopServicePath + "CreateLaptop", []string{"seller", "admin"}},
+		{"deny_inherited", laptopServicePath + "DeleteLaptop", []string{}},
+		{"deny_inheriting_role", laptopServicePath + "ShareLaptop", []string{"seller"}},
+		{"method_wildcard", reviewServicePath + "ListReviewsByUser", []string{"user", "seller", "admin", "auditor"}},
+		{"public_and_restricted", laptopServicePath + "SearchLaptop", []string{"seller", "admin"}},
+		{"public", reviewServicePath + "GetReviewSummary", nil},
+		{"no_rule", reviewServicePath + "ApproveReview", []string{}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.allowed != nil, policy.RequiresAuthentication(tc.method))
+			if tc.allowed == nil {
+				return
+			}
+
+			for _, role := range []string{"admin", "seller", "user", "auditor", "unknown"} {
+				expected := false
+				for _, allowed := range tc.allowed {
+					expected = expected || allowed == role
+				}
+				require.Equal(t, expected, policy.Allows(tc.method, role), role)
+			}
+		})
+	}
+}
+
+func TestParseAuthPolicyInvalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		policy string
+	}{
+		{"syntax", "roles: ["},
+		{"unknown_field", "roles: {user: {}}\nrule: []"},
+		{"unknown_effect", "roles: {user: {}}\nrules: [{methods: [/a/b], roles: [user], effect: maybe}]"},
+		{"unknown_inherited_role", "roles: {admin: {inherits: [user]}}"},
+		{"inheritance_cycle", "roles: {a: {inherits: [b]}, b: {inherits: [c]}, c: {inherits: [a]}}"},
+		{"unknown_rule_role", "roles: {user: {}}\nrules: [{methods: [/a/b], roles: [admin]}]"},
+		{"no_method", "roles: {user: {}}\nrules: [{roles: [user]}]"},
+		{"no_role", "roles: {user: {}}\nrules: [{methods: [/a/b]}]"},
+		{"public_with_role", "roles: {user: {}}\nrules: [{methods: [/a/b], roles: [user], effect: public}]"},
+		{"not_full_method", "roles: {user: {}}\nrules: [{methods: [a/b], roles: [user]}]"},
+		{"bad_pattern", "roles: {user: {}}\nrules: [{methods: [\"/a/[\"], roles: [user]}]"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseAuthPolicy([]byte(tc.policy))
+			require.Error(t, err)
+		})
+	}
+
+	// JSON is YAML as well
+	_, err := ParseAuthPolicy([]byte(`{"roles": {"user": {}}, "rules": [{"methods": ["/a/*"], "roles": ["user"]}]}`))
+	require.NoError(t, err)
+}
+
+func TestNewAuthPolicy(t *testing.T) {
+	t.Parallel()
+
+	policy := NewAuthPolicy(map[string][]string{
+		"/a/Admin":  {"admin"},
+		"/a/User":   {"admin", "user"},
+		"/a/Public": nil,
+	})
+
+	require.True(t, policy.Allows("/a/Admin", "admin"))
+	require.False(t, policy.Allows("/a/Admin", "user"))
+	require.True(t, policy.Allows("/a/User", "user"))
+	require.False(t, policy.RequiresAuthentication("/a/Public"))
+
+	// the methods that are not listed are denied
+	require.True(t, policy.RequiresAuthentication("/a/Other"))
+	require.False(t, policy.Allows("/a/Other", "admin"))
+
+	proto := policy.Proto()
+	require.Len(t, proto.GetRoles(), 2)
+	require.Len(t, proto.GetRules(), 3)
+	require.Equal(t, []string{"/a/Admin"}, proto.GetRules()[0].GetMethods())
+	require.Equal(t, pb.AuthPolicyRule_PUBLIC, proto.GetRules()[1].GetEffect())
+}
+
+func TestAuthPolicyFileListsEveryMethod(t *testing.T) {
+	t.Parallel()
+
+	file, err := LoadAuthPolicyFile("../auth_policy.yaml")
+	require.NoError(t, err)
+	policy := file.Policy()
+
+	// the methods that no rule lists are denied, so a new method needs a rule
+	services := []grpc.ServiceDesc{
+		pb.AuthService_ServiceDesc,
+		pb.LaptopService_ServiceDesc,
+		pb.SavedSearchService_ServiceDesc,
+		pb.ReviewService_ServiceDesc,
+	}
+	for _, service := range services {
+		var names []string
+		for _, method := range service.Methods {
+			names = append(names, method.MethodName)
+		}
+		for _, stream := range service.Streams {
+			names = append(names, stream.StreamName)
+		}
+
+		for _, name := range names {
+			method := "/" + service.ServiceName + "/" + name
+			allowed := !policy.RequiresAuthentication(method)
+			for role := range policy.inherits {
+				allowed = allowed || policy.Allows(method, role)
+			}
+			require.True(t, allowed, method)
+		}
+	}
+}
+
+func TestAuthPolicyFileReload(t *testing.T) {
+	t.Parallel()
+
+	filename := filepath.Join(t.TempDir(), "auth_policy.yaml")
+	writePolicy := func(policy string) {
+		require.NoError(t, ioutil.WriteFile(filename, []byte(policy), 0600))
+	}
+
+	writePolicy("roles: {user: {}}\nrules: [{methods: [/a/b], roles: [user]}]")
+	file, err := LoadAuthPolicyFile(filename)
+	require.NoError(t, err)
+	require.True(t, file.Policy().Allows("/a/b", "user"))
+
+	reloaded, err := file.Reload()
+	require.NoError(t, err)
+	require.False(t, reloaded)
+
+	require.False(t, file.Policy().HasRole("auditor"))
+
+	// a role added to the policy can be given as soon as the policy is reloaded
+	writePolicy("roles: {user: {}, auditor: {}}\nrules: [{methods: [/a/c], roles: [user]}]")
+	reloaded, err = file.Reload()
+	require.NoError(t, err)
+	require.True(t, reloaded)
+	require.False(t, file.Policy().Allows("/a/b", "user"))
+	require.True(t, file.Policy().Allows("/a/c", "user"))
+	require.True(t, file.Policy().HasRole("auditor"))
+
+	// an invalid policy is reported once and the previous one is kept
+	writePolicy("roles: {user: {}}\nrules: [{methods: [/a/d], roles: [admin]}]")
+	_, err = file.Reload()
+	require.Error(t, err)
+	reloaded, err = file.Reload()
+	require.NoError(t, err)
+	require.False(t, reloaded)
+	require.True(t, file.Policy().Allows("/a/c", "user"))
+
+	_, err = LoadAuthPolicyFile(filepath.Join(t.TempDir(), "unknown.yaml"))
+	require.Error(t, err)
+}
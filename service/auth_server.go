@@ -2,21 +2,60 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/uuid"
 	"github.com/treeforest/grpc-pcbook/pb"
+	"google.golang.org/genproto/googleapis/api/httpbody"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"time"
+)
+
+const (
+	// page size of ListUsers when the request doesn't set one
+	defaultUserPageSize = 50
+	maxUserPageSize     = 1000
 )
 
 // AuthServer is the server for authentication
 type AuthServer struct {
 	pb.UnimplementedAuthServiceServer
-	userStore  UserStore
-	jwtManager *JWTManager
+	userStore   UserStore
+	tokenStore  TokenStore
+	apiKeyStore APIKeyStore
+	jwtManager  *JWTManager
+	// lifetime of the refresh tokens
+	refreshTokenDuration time.Duration
+	// policy returned by GetAuthPolicy, nil if there is none
+	policy AuthPolicyProvider
+}
+
+// NewAuthServer returns a new auth server that issues refresh tokens valid for refreshTokenDuration
+func NewAuthServer(
+	userStore UserStore,
+	tokenStore TokenStore,
+	apiKeyStore APIKeyStore,
+	jwtManager *JWTManager,
+	refreshTokenDuration time.Duration,
+) *AuthServer {
+	return &AuthServer{
+		userStore:            userStore,
+		tokenStore:           tokenStore,
+		apiKeyStore:          apiKeyStore,
+		jwtManager:           jwtManager,
+		refreshTokenDuration: refreshTokenDuration,
+	}
 }
 
-// NewAuthServer returns a new auth server
-func NewAuthServer(userStore UserStore, jwtManager *JWTManager)*AuthServer {
-	return &AuthServer{userStore: userStore, jwtManager: jwtManager}
+// SetAuthPolicy sets the auth policy that GetAuthPolicy returns, which should be the one of the AuthInterceptor
+func (server *AuthServer) SetAuthPolicy(policy AuthPolicyProvider) {
+	server.policy = policy
 }
 
 // Login ia a unary RPC to login user
@@ -30,11 +69,526 @@ func (server *AuthServer) Login(ctx context.Context, req *pb.LoginRequest) (*pb.
 		return nil, status.Errorf(codes.NotFound, "incorrect username/password")
 	}
 
-	token, err := server.jwtManager.Generate(user)
+	if user.Disabled {
+		return nil, status.Errorf(codes.PermissionDenied, "user %s is disabled", user.Username)
+	}
+
+	family, err := uuid.NewRandom()
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "cannot generate access token: %v", err)
+		return nil, status.Errorf(codes.Internal, "cannot generate token family: %v", err)
 	}
 
-	res := &pb.LoginResponse{AccessToken: token}
+	accessToken, refreshToken, err := server.issueTokens(user, family.String())
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}
 	return res, nil
-}
\ No newline at end of file
+}
+
+// RefreshToken is a unary RPC to exchange a refresh token for a new access token and a new refresh token.
+// Using a refresh token twice revokes every token of its family, as the token may have been stolen.
+func (server *AuthServer) RefreshToken(
+	ctx context.Context,
+	req *pb.RefreshTokenRequest,
+) (*pb.RefreshTokenResponse, error) {
+	token, err := server.tokenStore.UseRefreshToken(hashToken(req.GetRefreshToken()))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot find refresh token: %v", err)
+	}
+	if token == nil {
+		return nil, status.Error(codes.Unauthenticated, "refresh token is invalid")
+	}
+
+	if token.Used {
+		err := server.tokenStore.RevokeFamily(token.Family)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "cannot revoke token family: %v", err)
+		}
+		return nil, status.Error(codes.Unauthenticated, "refresh token is already used, its family is revoked")
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return nil, status.Error(codes.Unauthenticated, "refresh token is expired")
+	}
+
+	user, err := server.userStore.Find(token.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot find user: %v", err)
+	}
+	if user == nil {
+		return nil, status.Errorf(codes.Unauthenticated, "user %s doesn't exist", token.Username)
+	}
+	if user.Disabled {
+		return nil, status.Errorf(codes.PermissionDenied, "user %s is disabled", user.Username)
+	}
+
+	accessToken, refreshToken, err := server.issueTokens(user, token.Family)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.RefreshTokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}
+	return res, nil
+}
+
+// Logout is a unary RPC to revoke the family of a refresh token, together with the access token
+// of the request if there is one
+func (server *AuthServer) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.LogoutResponse, error) {
+	token, err := server.tokenStore.UseRefreshToken(hashToken(req.GetRefreshToken()))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot find refresh token: %v", err)
+	}
+
+	claims, ok := UserClaimsFromContext(ctx)
+	if token == nil && !ok {
+		return nil, status.Error(codes.Unauthenticated, "refresh token is invalid")
+	}
+
+	if token != nil {
+		err := server.tokenStore.RevokeFamily(token.Family)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "cannot revoke token family: %v", err)
+		}
+	}
+
+	// the callers authenticated by a client certificate have no access token
+	if ok && claims.Id != "" {
+		err := server.tokenStore.RevokeAccessToken(claims.Id, time.Unix(claims.ExpiresAt, 0))
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "cannot revoke access token: %v", err)
+		}
+	}
+
+	return &pb.LogoutResponse{}, nil
+}
+
+// GetJWKS is a unary RPC to get the JSON web key set of the public keys that verify the access tokens
+func (server *AuthServer) GetJWKS(ctx context.Context, req *pb.GetJWKSRequest) (*httpbody.HttpBody, error) {
+	jwks, err := server.jwtManager.JWKS()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot get JSON web key set: %v", err)
+	}
+
+	res := &httpbody.HttpBody{
+		ContentType: "application/jwk-set+json",
+		Data:        jwks,
+	}
+	return res, nil
+}
+
+// GetAuthPolicy is a unary RPC to get the auth policy, which tells which methods need an access token
+func (server *AuthServer) GetAuthPolicy(
+	ctx context.Context,
+	req *pb.GetAuthPolicyRequest,
+) (*pb.GetAuthPolicyResponse, error) {
+	if server.policy == nil {
+		return nil, status.Error(codes.Unimplemented, "auth policy is not available")
+	}
+
+	res := &pb.GetAuthPolicyResponse{Policy: server.policy.Policy().Proto()}
+	return res, nil
+}
+
+// Register is a unary RPC to create a new user with the user role
+func (server *AuthServer) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
+	err := validateUsername(req.GetUsername())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid username: %v", err)
+	}
+
+	err = validatePassword(req.GetUsername(), req.GetPassword())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "weak password: %v", err)
+	}
+
+	user, err := NewUser(req.GetUsername(), req.GetPassword(), userRole)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot create user: %v", err)
+	}
+
+	err = server.userStore.Save(user)
+	if err != nil {
+		code := codes.Internal
+		if errors.Is(err, ErrAlreadyExists) {
+			code = codes.AlreadyExists
+		}
+		return nil, status.Errorf(code, "cannot save user: %v", err)
+	}
+
+	res := &pb.RegisterResponse{User: userToProto(user)}
+	return res, nil
+}
+
+// ChangePassword is a unary RPC to change the password of the authenticated user
+func (server *AuthServer) ChangePassword(
+	ctx context.Context,
+	req *pb.ChangePasswordRequest,
+) (*pb.ChangePasswordResponse, error) {
+	username, err := authenticatedUsername(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := server.findUser(username)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsCorrectPassword(req.GetOldPassword()) {
+		return nil, status.Error(codes.PermissionDenied, "incorrect password")
+	}
+
+	err = validatePassword(username, req.GetNewPassword())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "weak password: %v", err)
+	}
+
+	err = user.SetPassword(req.GetNewPassword())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot set password: %v", err)
+	}
+
+	err = server.updateUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	// a stolen refresh token doesn't outlive the old password
+	err = server.revokeUserTokens(username)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.ChangePasswordResponse{}, nil
+}
+
+// ListUsers is a unary RPC for admins to list a page of the users ordered by username
+func (server *AuthServer) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	pageSize := int(req.GetPageSize())
+	if pageSize < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page size cannot be negative")
+	}
+	if pageSize == 0 {
+		pageSize = defaultUserPageSize
+	}
+	if pageSize > maxUserPageSize {
+		pageSize = maxUserPageSize
+	}
+
+	// the page token is the last username of the previous page
+	after, err := base64.RawURLEncoding.DecodeString(req.GetPageToken())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid page token")
+	}
+
+	var users []*pb.User
+	more := false
+	err = server.userStore.List(string(after), func(user *User) bool {
+		if len(users) == pageSize {
+			more = true
+			return false
+		}
+		users = append(users, userToProto(user))
+		return true
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot list users: %v", err)
+	}
+
+	res := &pb.ListUsersResponse{Users: users}
+	if more {
+		last := users[len(users)-1].GetUsername()
+		res.NextPageToken = base64.RawURLEncoding.EncodeToString([]byte(last))
+	}
+	return res, nil
+}
+
+// SetUserRole is a unary RPC for admins to change the role of a user
+func (server *AuthServer) SetUserRole(ctx context.Context, req *pb.SetUserRoleRequest) (*pb.SetUserRoleResponse, error) {
+	err := server.validateRole(req.GetRole())
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := server.findOtherUser(ctx, req.GetUsername())
+	if err != nil {
+		return nil, err
+	}
+
+	user.Role = req.GetRole()
+	err = server.updateUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	// the access tokens carry the old role
+	err = server.revokeUserTokens(user.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.SetUserRoleResponse{User: userToProto(user)}
+	return res, nil
+}
+
+// DisableUser is a unary RPC for admins to disable a user, or to enable it again
+func (server *AuthServer) DisableUser(ctx context.Context, req *pb.DisableUserRequest) (*pb.DisableUserResponse, error) {
+	user, err := server.findOtherUser(ctx, req.GetUsername())
+	if err != nil {
+		return nil, err
+	}
+
+	user.Disabled = !req.GetEnable()
+	err = server.updateUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Disabled {
+		err = server.revokeUserTokens(user.Username)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res := &pb.DisableUserResponse{User: userToProto(user)}
+	return res, nil
+}
+
+// CreateAPIKey is a unary RPC for admins to create an API key with a role.
+// The key is only returned by this RPC since the store only has its hash.
+func (server *AuthServer) CreateAPIKey(
+	ctx context.Context,
+	req *pb.CreateAPIKeyRequest,
+) (*pb.CreateAPIKeyResponse, error) {
+	admin, err := authenticatedUsername(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateUsername(req.GetName())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid name: %v", err)
+	}
+
+	err = server.validateRole(req.GetRole())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var expiresAt time.Time
+	if req.GetExpiresAt() != nil {
+		expiresAt, err = ptypes.Timestamp(req.GetExpiresAt())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid expiry: %v", err)
+		}
+		if !expiresAt.After(now) {
+			return nil, status.Error(codes.InvalidArgument, "expiry must be in the future")
+		}
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot generate API key ID: %v", err)
+	}
+
+	key, err := newRandomToken()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot generate API key: %v", err)
+	}
+
+	apiKey := &APIKey{
+		ID:        id.String(),
+		Name:      req.GetName(),
+		HashedKey: hashToken(key),
+		Role:      req.GetRole(),
+		CreatedBy: admin,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+
+	err = server.apiKeyStore.Save(apiKey)
+	if err != nil {
+		code := codes.Internal
+		if errors.Is(err, ErrAlreadyExists) {
+			code = codes.AlreadyExists
+		}
+		return nil, status.Errorf(code, "cannot save API key: %v", err)
+	}
+
+	res := &pb.CreateAPIKeyResponse{
+		ApiKey: apiKeyToProto(apiKey),
+		Key:    key,
+	}
+	return res, nil
+}
+
+// ListAPIKeys is a unary RPC for admins to list the API keys ordered by creation time
+func (server *AuthServer) ListAPIKeys(ctx context.Context, req *pb.ListAPIKeysRequest) (*pb.ListAPIKeysResponse, error) {
+	var apiKeys []*pb.APIKey
+	err := server.apiKeyStore.List(func(key *APIKey) bool {
+		apiKeys = append(apiKeys, apiKeyToProto(key))
+		return true
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot list API keys: %v", err)
+	}
+
+	res := &pb.ListAPIKeysResponse{ApiKeys: apiKeys}
+	return res, nil
+}
+
+// RevokeAPIKey is a unary RPC for admins to revoke an API key, which is rejected from then on
+func (server *AuthServer) RevokeAPIKey(ctx context.Context, req *pb.RevokeAPIKeyRequest) (*pb.RevokeAPIKeyResponse, error) {
+	err := server.apiKeyStore.Delete(req.GetId())
+	if err != nil {
+		code := codes.Internal
+		if errors.Is(err, ErrNotFound) {
+			code = codes.NotFound
+		}
+		return nil, status.Errorf(code, "cannot revoke API key: %v", err)
+	}
+
+	return &pb.RevokeAPIKeyResponse{}, nil
+}
+
+// findUser finds a user by username. Errors are gRPC status errors.
+func (server *AuthServer) findUser(username string) (*User, error) {
+	user, err := server.userStore.Find(username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot find user: %v", err)
+	}
+	if user == nil {
+		return nil, status.Errorf(codes.NotFound, "user %s doesn't exist", username)
+	}
+
+	return user, nil
+}
+
+// validateRole checks that the current auth policy declares the role, a role that it doesn't declare
+// cannot be given to a user or an API key. Errors are gRPC status errors.
+func (server *AuthServer) validateRole(role string) error {
+	if server.policy == nil {
+		return status.Error(codes.FailedPrecondition, "roles cannot be given without an auth policy")
+	}
+
+	if !server.policy.Policy().HasRole(role) {
+		return status.Errorf(codes.InvalidArgument, "unknown role %q", role)
+	}
+
+	return nil
+}
+
+// findOtherUser finds a user that the authenticated admin manages, admins cannot manage themselves
+// so that they cannot lock every admin out. Errors are gRPC status errors.
+func (server *AuthServer) findOtherUser(ctx context.Context, username string) (*User, error) {
+	admin, err := authenticatedUsername(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if username == admin {
+		return nil, status.Error(codes.FailedPrecondition, "admins cannot change their own role or disable themselves")
+	}
+
+	return server.findUser(username)
+}
+
+// updateUser saves the changes of a user. Errors are gRPC status errors.
+func (server *AuthServer) updateUser(user *User) error {
+	err := server.userStore.Update(user)
+	if err != nil {
+		code := codes.Internal
+		if errors.Is(err, ErrNotFound) {
+			code = codes.NotFound
+		}
+		return status.Errorf(code, "cannot update user: %v", err)
+	}
+
+	return nil
+}
+
+// revokeUserTokens revokes the refresh tokens of the user and the access tokens issued with them.
+// Errors are gRPC status errors.
+func (server *AuthServer) revokeUserTokens(username string) error {
+	err := server.tokenStore.RevokeUser(username)
+	if err != nil {
+		return status.Errorf(codes.Internal, "cannot revoke tokens: %v", err)
+	}
+
+	return nil
+}
+
+// issueTokens returns a new access token and a new refresh token of the family for the user.
+// Errors are gRPC status errors.
+func (server *AuthServer) issueTokens(user *User, family string) (string, string, error) {
+	accessToken, err := server.jwtManager.GenerateInFamily(user, family)
+	if err != nil {
+		return "", "", status.Errorf(codes.Internal, "cannot generate access token: %v", err)
+	}
+
+	refreshToken, err := newRandomToken()
+	if err != nil {
+		return "", "", status.Errorf(codes.Internal, "cannot generate refresh token: %v", err)
+	}
+
+	err = server.tokenStore.SaveRefreshToken(&RefreshToken{
+		HashedToken: hashToken(refreshToken),
+		Family:      family,
+		Username:    user.Username,
+		ExpiresAt:   time.Now().Add(server.refreshTokenDuration),
+	})
+	if err != nil {
+		return "", "", status.Errorf(codes.Internal, "cannot save refresh token: %v", err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// newRandomToken returns a new random token, used as a refresh token or an API key
+func newRandomToken() (string, error) {
+	data := make([]byte, 32)
+	_, err := rand.Read(data)
+	if err != nil {
+		return "", fmt.Errorf("cannot read random bytes: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// hashToken returns the hash of a token, which is stored instead of the token
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func userToProto(user *User) *pb.User {
+	return &pb.User{
+		Username: user.Username,
+		Role:     user.Role,
+		Disabled: user.Disabled,
+	}
+}
+
+func apiKeyToProto(key *APIKey) *pb.APIKey {
+	apiKey := &pb.APIKey{
+		Id:        key.ID,
+		Name:      key.Name,
+		Role:      key.Role,
+		CreatedBy: key.CreatedBy,
+		CreatedAt: timestampFromUnixNano(key.CreatedAt.UnixNano()),
+	}
+	if !key.ExpiresAt.IsZero() {
+		apiKey.ExpiresAt = timestampFromUnixNano(key.ExpiresAt.UnixNano())
+	}
+	return apiKey
+}
@@ -0,0 +1,516 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/require"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidatePassword(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{"strong", "Secret123", true},
+		{"unicode", "Pässwort99", true},
+		{"too_short", "Sec123", false},
+		{"too_long", "Secret123" + strings.Repeat("a", maxPasswordLength), false},
+		{"no_uppercase", "secret123", false},
+		{"no_lowercase", "SECRET123", false},
+		{"no_digit", "SecretSecret", false},
+		{"contains_username", "xAlice2024x", false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validatePassword("alice", tc.password)
+			if tc.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateUsername(t *testing.T) {
+	t.Parallel()
+
+	for _, username := range []string{"bob", "alice.smith", "user_1", "a-b"} {
+		require.NoError(t, validateUsername(username), username)
+	}
+
+	for _, username := range []string{"", "ab", strings.Repeat("a", maxUsernameLength+1), "al ice", "alice!", "ålice"} {
+		require.Error(t, validateUsername(username), username)
+	}
+}
+
+func TestNewAdmin(t *testing.T) {
+	t.Parallel()
+
+	user, err := NewAdmin("root", "Secret123")
+	require.NoError(t, err)
+	require.Equal(t, adminRole, user.Role)
+	require.True(t, user.IsCorrectPassword("Secret123"))
+
+	_, err = NewAdmin("root", "secret")
+	require.Error(t, err)
+
+	_, err = NewAdmin("r", "Secret123")
+	require.Error(t, err)
+}
+
+func TestAuthServer(t *testing.T) {
+	t.Parallel()
+
+	userStore := NewInMemoryUserStore()
+	admin, err := NewUser("admin1", "secret", adminRole)
+	require.NoError(t, err)
+	require.NoError(t, userStore.Save(admin))
+
+	jwtManager := NewJWTManager("secret", time.Minute)
+	serverAddress := startTestAuthServer(t, userStore, NewInMemoryTokenStore(), NewInMemoryAPIKeyStore(), jwtManager)
+
+	conn, err := grpc.Dial(serverAddress, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	authClient := pb.NewAuthServiceClient(conn)
+
+	login := func(username string, password string) (context.Context, error) {
+		req := &pb.LoginRequest{Username: username, Password: password}
+		res, err := authClient.Login(context.Background(), req)
+		if err != nil {
+			return nil, err
+		}
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", res.GetAccessToken()), nil
+	}
+
+	adminCtx, err := login("admin1", "secret")
+	require.NoError(t, err)
+
+	// register
+	_, err = authClient.Register(context.Background(), &pb.RegisterRequest{Username: "alice", Password: "weak"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = authClient.Register(context.Background(), &pb.RegisterRequest{Username: "a!", Password: "Secret123"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	registerRes, err := authClient.Register(context.Background(), &pb.RegisterRequest{Username: "alice", Password: "Secret123"})
+	require.NoError(t, err)
+	require.Equal(t, "alice", registerRes.GetUser().GetUsername())
+	require.Equal(t, userRole, registerRes.GetUser().GetRole())
+
+	_, err = authClient.Register(context.Background(), &pb.RegisterRequest{Username: "alice", Password: "Secret456"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+	_, err = authClient.Register(context.Background(), &pb.RegisterRequest{Username: "bob", Password: "Secret456"})
+	require.NoError(t, err)
+
+	aliceCtx, err := login("alice", "Secret123")
+	require.NoError(t, err)
+
+	// change password
+	_, err = authClient.ChangePassword(context.Background(), &pb.ChangePasswordRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	req := &pb.ChangePasswordRequest{OldPassword: "wrong", NewPassword: "Secret789"}
+	_, err = authClient.ChangePassword(aliceCtx, req)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	req = &pb.ChangePasswordRequest{OldPassword: "Secret123", NewPassword: "secret"}
+	_, err = authClient.ChangePassword(aliceCtx, req)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	req = &pb.ChangePasswordRequest{OldPassword: "Secret123", NewPassword: "Secret789"}
+	_, err = authClient.ChangePassword(aliceCtx, req)
+	require.NoError(t, err)
+
+	_, err = login("alice", "Secret123")
+	require.Equal(t, codes.NotFound, status.Code(err))
+	aliceCtx, err = login("alice", "Secret789")
+	require.NoError(t, err)
+
+	// list users
+	_, err = authClient.ListUsers(aliceCtx, &pb.ListUsersRequest{})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	var usernames []string
+	pageToken := ""
+	for {
+		res, err := authClient.ListUsers(adminCtx, &pb.ListUsersRequest{PageSize: 2, PageToken: pageToken})
+		require.NoError(t, err)
+		for _, user := range res.GetUsers() {
+			usernames = append(usernames, user.GetUsername())
+		}
+
+		pageToken = res.GetNextPageToken()
+		if pageToken == "" {
+			break
+		}
+	}
+	require.Equal(t, []string{"admin1", "alice", "bob"}, usernames)
+
+	// set user role, which revokes the access tokens already issued
+	_, err = authClient.SetUserRole(adminCtx, &pb.SetUserRoleRequest{Username: "alice", Role: "root"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = authClient.SetUserRole(adminCtx, &pb.SetUserRoleRequest{Username: "carol", Role: adminRole})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = authClient.SetUserRole(adminCtx, &pb.SetUserRoleRequest{Username: "admin1", Role: userRole})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	roleRes, err := authClient.SetUserRole(adminCtx, &pb.SetUserRoleRequest{Username: "alice", Role: adminRole})
+	require.NoError(t, err)
+	require.Equal(t, adminRole, roleRes.GetUser().GetRole())
+
+	_, err = authClient.ListUsers(aliceCtx, &pb.ListUsersRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	aliceCtx, err = login("alice", "Secret789")
+	require.NoError(t, err)
+	_, err = authClient.ListUsers(aliceCtx, &pb.ListUsersRequest{})
+	require.NoError(t, err)
+
+	// disable user
+	_, err = authClient.DisableUser(aliceCtx, &pb.DisableUserRequest{Username: "alice"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	bobCtx, err := login("bob", "Secret456")
+	require.NoError(t, err)
+
+	disableRes, err := authClient.DisableUser(aliceCtx, &pb.DisableUserRequest{Username: "bob"})
+	require.NoError(t, err)
+	require.True(t, disableRes.GetUser().GetDisabled())
+
+	_, err = login("bob", "Secret456")
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	// disabling a user revokes its access tokens
+	req = &pb.ChangePasswordRequest{OldPassword: "Secret456", NewPassword: "Secret789"}
+	_, err = authClient.ChangePassword(bobCtx, req)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	disableRes, err = authClient.DisableUser(adminCtx, &pb.DisableUserRequest{Username: "bob", Enable: true})
+	require.NoError(t, err)
+	require.False(t, disableRes.GetUser().GetDisabled())
+
+	// the access tokens of a disabled user stay revoked
+	_, err = authClient.ChangePassword(bobCtx, req)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	bobCtx, err = login("bob", "Secret456")
+	require.NoError(t, err)
+	_, err = authClient.ChangePassword(bobCtx, req)
+	require.NoError(t, err)
+}
+
+func TestAuthServerRefreshToken(t *testing.T) {
+	t.Parallel()
+
+	userStore := NewInMemoryUserStore()
+	user, err := NewUser("alice", "Secret123", userRole)
+	require.NoError(t, err)
+	require.NoError(t, userStore.Save(user))
+
+	tokenStore := NewInMemoryTokenStore()
+	jwtManager := NewJWTManager("secret", time.Minute)
+	serverAddress := startTestAuthServer(t, userStore, tokenStore, NewInMemoryAPIKeyStore(), jwtManager)
+
+	conn, err := grpc.Dial(serverAddress, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	authClient := pb.NewAuthServiceClient(conn)
+
+	withToken := func(accessToken string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", accessToken)
+	}
+	// an RPC that needs a valid access token
+	checkToken := func(accessToken string) error {
+		_, err := authClient.ChangePassword(withToken(accessToken), &pb.ChangePasswordRequest{})
+		if status.Code(err) == codes.PermissionDenied {
+			// the token is valid but the password is not
+			return nil
+		}
+		return err
+	}
+	login := func() *pb.LoginResponse {
+		res, err := authClient.Login(context.Background(), &pb.LoginRequest{Username: "alice", Password: "Secret123"})
+		require.NoError(t, err)
+		require.NotEmpty(t, res.GetRefreshToken())
+		return res
+	}
+
+	// rotation
+	loginRes := login()
+	refreshRes, err := authClient.RefreshToken(context.Background(), &pb.RefreshTokenRequest{RefreshToken: loginRes.GetRefreshToken()})
+	require.NoError(t, err)
+	require.NotEqual(t, loginRes.GetRefreshToken(), refreshRes.GetRefreshToken())
+	require.NoError(t, checkToken(refreshRes.GetAccessToken()))
+
+	claims, err := jwtManager.Verify(refreshRes.GetAccessToken())
+	require.NoError(t, err)
+	require.NotEmpty(t, claims.Id)
+	require.NotEmpty(t, claims.Family)
+
+	_, err = authClient.RefreshToken(context.Background(), &pb.RefreshTokenRequest{RefreshToken: "unknown"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// using a refresh token twice revokes its family
+	_, err = authClient.RefreshToken(context.Background(), &pb.RefreshTokenRequest{RefreshToken: loginRes.GetRefreshToken()})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = authClient.RefreshToken(context.Background(), &pb.RefreshTokenRequest{RefreshToken: refreshRes.GetRefreshToken()})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.Equal(t, codes.Unauthenticated, status.Code(checkToken(refreshRes.GetAccessToken())))
+	require.Equal(t, codes.Unauthenticated, status.Code(checkToken(loginRes.GetAccessToken())))
+
+	// logout revokes the family and the access token of the request, other logins keep working
+	loginRes = login()
+	otherRes := login()
+	refreshRes, err = authClient.RefreshToken(context.Background(), &pb.RefreshTokenRequest{RefreshToken: loginRes.GetRefreshToken()})
+	require.NoError(t, err)
+
+	_, err = authClient.Logout(withToken(loginRes.GetAccessToken()), &pb.LogoutRequest{RefreshToken: refreshRes.GetRefreshToken()})
+	require.NoError(t, err)
+	require.Equal(t, codes.Unauthenticated, status.Code(checkToken(loginRes.GetAccessToken())))
+	require.Equal(t, codes.Unauthenticated, status.Code(checkToken(refreshRes.GetAccessToken())))
+	_, err = authClient.RefreshToken(context.Background(), &pb.RefreshTokenRequest{RefreshToken: refreshRes.GetRefreshToken()})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	require.NoError(t, checkToken(otherRes.GetAccessToken()))
+	_, err = authClient.Logout(context.Background(), &pb.LogoutRequest{RefreshToken: "unknown"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// a disabled user cannot refresh
+	user.Disabled = true
+	require.NoError(t, userStore.Update(user))
+	_, err = authClient.RefreshToken(context.Background(), &pb.RefreshTokenRequest{RefreshToken: otherRes.GetRefreshToken()})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// changing the password revokes every login of the user
+	user.Disabled = false
+	require.NoError(t, userStore.Update(user))
+	loginRes = login()
+	otherRes = login()
+
+	req := &pb.ChangePasswordRequest{OldPassword: "Secret123", NewPassword: "Secret456"}
+	_, err = authClient.ChangePassword(withToken(loginRes.GetAccessToken()), req)
+	require.NoError(t, err)
+
+	for _, res := range []*pb.LoginResponse{loginRes, otherRes} {
+		require.Equal(t, codes.Unauthenticated, status.Code(checkToken(res.GetAccessToken())))
+		_, err = authClient.RefreshToken(context.Background(), &pb.RefreshTokenRequest{RefreshToken: res.GetRefreshToken()})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+}
+
+func TestAuthServerGetJWKS(t *testing.T) {
+	t.Parallel()
+
+	userStore := NewInMemoryUserStore()
+	user, err := NewUser("alice", "secret", userRole)
+	require.NoError(t, err)
+	require.NoError(t, userStore.Save(user))
+
+	signingKey := newTestECKey(t)
+	jwtManager, err := NewKeyJWTManager(signingKey, []*SigningKey{newTestRSAKey(t)}, time.Minute)
+	require.NoError(t, err)
+	serverAddress := startTestAuthServer(t, userStore, NewInMemoryTokenStore(), NewInMemoryAPIKeyStore(), jwtManager)
+
+	conn, err := grpc.Dial(serverAddress, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	authClient := pb.NewAuthServiceClient(conn)
+
+	res, err := authClient.GetJWKS(context.Background(), &pb.GetJWKSRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "application/jwk-set+json", res.GetContentType())
+
+	var jwks struct {
+		Keys []*jsonWebKey `json:"keys"`
+	}
+	require.NoError(t, json.Unmarshal(res.GetData(), &jwks))
+	require.Len(t, jwks.Keys, 2)
+	require.Equal(t, signingKey.ID, jwks.Keys[0].Kid)
+
+	// the access tokens are signed with the published signing key
+	loginRes, err := authClient.Login(context.Background(), &pb.LoginRequest{Username: "alice", Password: "secret"})
+	require.NoError(t, err)
+	token, _, err := new(jwt.Parser).ParseUnverified(loginRes.GetAccessToken(), &UserClaims{})
+	require.NoError(t, err)
+	require.Equal(t, signingKey.ID, token.Header["kid"])
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", loginRes.GetAccessToken())
+	_, err = authClient.Logout(ctx, &pb.LogoutRequest{RefreshToken: loginRes.GetRefreshToken()})
+	require.NoError(t, err)
+}
+
+func TestAuthServerAPIKeys(t *testing.T) {
+	t.Parallel()
+
+	userStore := NewInMemoryUserStore()
+	for _, user := range []struct{ username, role string }{{"admin1", adminRole}, {"alice", userRole}} {
+		other, err := NewUser(user.username, "secret", user.role)
+		require.NoError(t, err)
+		require.NoError(t, userStore.Save(other))
+	}
+
+	apiKeyStore := NewInMemoryAPIKeyStore()
+	jwtManager := NewJWTManager("secret", time.Minute)
+	serverAddress := startTestAuthServer(t, userStore, NewInMemoryTokenStore(), apiKeyStore, jwtManager)
+
+	conn, err := grpc.Dial(serverAddress, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	authClient := pb.NewAuthServiceClient(conn)
+
+	login := func(username string) context.Context {
+		res, err := authClient.Login(context.Background(), &pb.LoginRequest{Username: username, Password: "secret"})
+		require.NoError(t, err)
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", res.GetAccessToken())
+	}
+	withAPIKey := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), APIKeyHeader, key)
+	}
+	adminCtx := login("admin1")
+
+	// only admins create keys, which must have a valid name, a known role and a future expiry
+	_, err = authClient.CreateAPIKey(login("alice"), &pb.CreateAPIKeyRequest{Name: "ci-bot", Role: userRole})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = authClient.CreateAPIKey(context.Background(), &pb.CreateAPIKeyRequest{Name: "ci-bot", Role: userRole})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	past, err := ptypes.TimestampProto(time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	invalidRequests := []*pb.CreateAPIKeyRequest{
+		{Name: "c", Role: userRole},
+		{Name: "ci-bot", Role: "root"},
+		{Name: "ci-bot", Role: userRole, ExpiresAt: past},
+	}
+	for _, req := range invalidRequests {
+		_, err = authClient.CreateAPIKey(adminCtx, req)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+
+	future, err := ptypes.TimestampProto(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	userKey, err := authClient.CreateAPIKey(adminCtx, &pb.CreateAPIKeyRequest{Name: "import", Role: userRole, ExpiresAt: future})
+	require.NoError(t, err)
+	require.NotEmpty(t, userKey.GetKey())
+	require.Equal(t, "admin1", userKey.GetApiKey().GetCreatedBy())
+	require.True(t, proto.Equal(future, userKey.GetApiKey().GetExpiresAt()))
+
+	adminKey, err := authClient.CreateAPIKey(adminCtx, &pb.CreateAPIKeyRequest{Name: "ci-bot", Role: adminRole})
+	require.NoError(t, err)
+	require.Nil(t, adminKey.GetApiKey().GetExpiresAt())
+
+	// the name of a key is the username of its clients, so it is unique
+	_, err = authClient.CreateAPIKey(adminCtx, &pb.CreateAPIKeyRequest{Name: "ci-bot", Role: userRole})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	// the keys are listed without their secret
+	res, err := authClient.ListAPIKeys(withAPIKey(adminKey.GetKey()), &pb.ListAPIKeysRequest{})
+	require.NoError(t, err)
+	require.Len(t, res.GetApiKeys(), 2)
+	require.True(t, proto.Equal(userKey.GetApiKey(), res.GetApiKeys()[0]))
+	require.True(t, proto.Equal(adminKey.GetApiKey(), res.GetApiKeys()[1]))
+
+	// a key grants its role
+	_, err = authClient.ListUsers(withAPIKey(userKey.GetKey()), &pb.ListUsersRequest{})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = authClient.ListUsers(withAPIKey("unknown"), &pb.ListUsersRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// keys created by a key are attributed to the client of the key
+	otherKey, err := authClient.CreateAPIKey(withAPIKey(adminKey.GetKey()), &pb.CreateAPIKeyRequest{Name: "other", Role: userRole})
+	require.NoError(t, err)
+	require.Equal(t, "apikey:ci-bot", otherKey.GetApiKey().GetCreatedBy())
+
+	// an expired key is rejected
+	require.NoError(t, apiKeyStore.Save(&APIKey{
+		ID:        "expired",
+		Name:      "expired",
+		HashedKey: hashToken("expired-key"),
+		Role:      adminRole,
+		CreatedAt: time.Now().Add(-time.Hour),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}))
+	_, err = authClient.ListUsers(withAPIKey("expired-key"), &pb.ListUsersRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// a revoked key is rejected
+	_, err = authClient.RevokeAPIKey(adminCtx, &pb.RevokeAPIKeyRequest{Id: adminKey.GetApiKey().GetId()})
+	require.NoError(t, err)
+	_, err = authClient.RevokeAPIKey(adminCtx, &pb.RevokeAPIKeyRequest{Id: adminKey.GetApiKey().GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = authClient.ListUsers(withAPIKey(adminKey.GetKey()), &pb.ListUsersRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAuthServerGetAuthPolicy(t *testing.T) {
+	t.Parallel()
+
+	jwtManager := NewJWTManager("secret", time.Minute)
+	serverAddress := startTestAuthServer(t, NewInMemoryUserStore(), NewInMemoryTokenStore(), NewInMemoryAPIKeyStore(), jwtManager)
+
+	conn, err := grpc.Dial(serverAddress, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	authClient := pb.NewAuthServiceClient(conn)
+
+	// everyone can get the policy
+	res, err := authClient.GetAuthPolicy(context.Background(), &pb.GetAuthPolicyRequest{})
+	require.NoError(t, err)
+
+	policy, err := LoadAuthPolicyFile("../auth_policy.yaml")
+	require.NoError(t, err)
+	require.True(t, proto.Equal(policy.Policy().Proto(), res.GetPolicy()))
+
+	roles := res.GetPolicy().GetRoles()
+	require.Len(t, roles, 3)
+	require.Equal(t, adminRole, roles[0].GetName())
+	require.Equal(t, []string{sellerRole}, roles[0].GetInherits())
+	require.Equal(t, sellerRole, roles[1].GetName())
+	require.Equal(t, []string{userRole}, roles[1].GetInherits())
+}
+
+func startTestAuthServer(
+	t *testing.T,
+	userStore UserStore,
+	tokenStore TokenStore,
+	apiKeyStore APIKeyStore,
+	jwtManager *JWTManager,
+) string {
+	authServer := NewAuthServer(userStore, tokenStore, apiKeyStore, jwtManager, time.Hour)
+
+	// the policy that the server uses by default
+	policy, err := LoadAuthPolicyFile("../auth_policy.yaml")
+	require.NoError(t, err)
+	authServer.SetAuthPolicy(policy)
+	interceptor := NewAuthInterceptor(jwtManager, userStore, tokenStore, apiKeyStore, policy)
+
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(interceptor.Unary()),
+		grpc.StreamInterceptor(interceptor.Stream()),
+	)
+	pb.RegisterAuthServiceServer(grpcServer, authServer)
+
+	listener, err := net.Listen("tcp", ":0") // random available port
+	require.NoError(t, err)
+
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	return listener.Addr().String()
+}
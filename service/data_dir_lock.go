@@ -0,0 +1,35 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// dataDirLockFile is the file of a data directory that its lock is taken on
+const dataDirLockFile = "LOCK"
+
+// DataDirLock is an exclusive lock on a data directory, which keeps two servers from writing the same stores
+type DataDirLock struct {
+	file *os.File
+}
+
+// LockDataDir creates the data directory if needed and locks it, it fails if another process holds the lock
+func LockDataDir(dir string) (*DataDirLock, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create data directory: %w", err)
+	}
+
+	file, err := lockFile(filepath.Join(dir, dataDirLockFile))
+	if err != nil {
+		return nil, fmt.Errorf("cannot lock data directory %s: %w", dir, err)
+	}
+
+	return &DataDirLock{file: file}, nil
+}
+
+// Unlock releases the lock
+func (lock *DataDirLock) Unlock() error {
+	return unlockFile(lock.file)
+}
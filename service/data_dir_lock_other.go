@@ -0,0 +1,27 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package service
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile creates the file, which must not exist. The server removes the file when it exits or is
+// interrupted, it only stays if the process is killed, and must then be removed by hand.
+func lockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return nil, errors.New("locked by another process, or by one that died, in which case remove " + path)
+	}
+	return file, err
+}
+
+func unlockFile(file *os.File) error {
+	err := file.Close()
+	if err != nil {
+		return err
+	}
+	return os.Remove(file.Name())
+}
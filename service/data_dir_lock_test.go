@@ -0,0 +1,24 @@
+package service
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestLockDataDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	lock, err := LockDataDir(dir)
+	require.NoError(t, err)
+
+	_, err = LockDataDir(dir)
+	require.Error(t, err)
+
+	require.NoError(t, lock.Unlock())
+
+	lock, err = LockDataDir(dir)
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock())
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"fmt"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"strings"
+)
+
+// applyFieldMask copies the fields listed in the mask from src to dst.
+// An empty mask replaces every field of dst except the ID, the revision and the ownership fields,
+// which only ShareLaptop changes.
+func applyFieldMask(dst, src *pb.Laptop, mask *fieldmaskpb.FieldMask) error {
+	paths := mask.GetPaths()
+	if len(paths) == 0 {
+		id, revision, owner, editors := dst.GetId(), dst.GetRevision(), dst.GetOwner(), dst.GetEditors()
+		proto.Reset(dst)
+		proto.Merge(dst, src)
+		dst.Id, dst.Revision, dst.Owner, dst.Editors = id, revision, owner, editors
+		return nil
+	}
+
+	if !mask.IsValid(dst) {
+		return fmt.Errorf("invalid field mask: %v", paths)
+	}
+
+	for _, path := range paths {
+		if path == "id" || path == "revision" || path == "owner" || path == "editors" {
+			return fmt.Errorf("field mask cannot contain %s", path)
+		}
+
+		copyField(dst.ProtoReflect(), src.ProtoReflect(), strings.Split(path, "."))
+	}
+
+	return nil
+}
+
+func copyField(dst, src protoreflect.Message, names []string) {
+	field := dst.Descriptor().Fields().ByName(protoreflect.Name(names[0]))
+	if field == nil {
+		return
+	}
+
+	if len(names) > 1 {
+		copyField(dst.Mutable(field).Message(), src.Get(field).Message(), names[1:])
+		return
+	}
+
+	if !src.Has(field) {
+		dst.Clear(field)
+		return
+	}
+
+	dst.Set(field, src.Get(field))
+}
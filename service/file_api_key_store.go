@@ -0,0 +1,163 @@
+package service
+
+import (
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"io"
+	"sync"
+	"time"
+)
+
+// FileAPIKeyStore stores API keys in memory and persists every change to a local directory.
+// Only the hash of a key is ever written to disk.
+type FileAPIKeyStore struct {
+	mutex  sync.Mutex
+	memory *InMemoryAPIKeyStore
+	*recordStore
+}
+
+// NewFileAPIKeyStore returns a new FileAPIKeyStore that loads the snapshot and replays the log found in dir
+func NewFileAPIKeyStore(dir string) (*FileAPIKeyStore, error) {
+	store := &FileAPIKeyStore{
+		memory: NewInMemoryAPIKeyStore(),
+	}
+
+	records, err := openRecordStore(dir, "api_keys", store.replay, store.writeSnapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	store.recordStore = records
+	return store, nil
+}
+
+// Save saves a new API key, returns ErrAlreadyExists if a key has the same ID, name or hash
+func (store *FileAPIKeyStore) Save(key *APIKey) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.memory.exists(key.ID, key.Name, key.HashedKey) {
+		return ErrAlreadyExists
+	}
+
+	record, err := newAPIKeyLogRecord(key)
+	if err != nil {
+		return err
+	}
+
+	return store.commit(record, func() error {
+		store.memory.put(key)
+		return nil
+	})
+}
+
+// FindByHash finds an API key by the hash of the key, returns nil if there is none
+func (store *FileAPIKeyStore) FindByHash(hashedKey string) (*APIKey, error) {
+	return store.memory.FindByHash(hashedKey)
+}
+
+// List calls found with the API keys ordered by creation time until found returns false
+func (store *FileAPIKeyStore) List(found func(key *APIKey) bool) error {
+	return store.memory.List(found)
+}
+
+// Delete deletes an API key by ID, returns ErrNotFound if there is none
+func (store *FileAPIKeyStore) Delete(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if !store.memory.exists(id, "", "") {
+		return ErrNotFound
+	}
+
+	record := &pb.APIKeyLogRecord{Id: id, Revoked: true}
+	return store.commit(record, func() error {
+		store.memory.remove(id)
+		return nil
+	})
+}
+
+func (store *FileAPIKeyStore) replay(payload []byte) error {
+	record := &pb.APIKeyLogRecord{}
+	err := proto.Unmarshal(payload, record)
+	if err != nil {
+		return fmt.Errorf("cannot unmarshal API key record: %w", err)
+	}
+
+	if record.GetRevoked() {
+		store.memory.remove(record.GetId())
+		return nil
+	}
+
+	createdAt, err := ptypes.Timestamp(record.GetCreatedAt())
+	if err != nil {
+		return fmt.Errorf("invalid API key creation time: %w", err)
+	}
+
+	var expiresAt time.Time
+	if record.GetExpiresAt() != nil {
+		expiresAt, err = ptypes.Timestamp(record.GetExpiresAt())
+		if err != nil {
+			return fmt.Errorf("invalid API key expiry: %w", err)
+		}
+	}
+
+	store.memory.put(&APIKey{
+		ID:        record.GetId(),
+		Name:      record.GetName(),
+		HashedKey: record.GetHashedKey(),
+		Role:      record.GetRole(),
+		CreatedBy: record.GetCreatedBy(),
+		CreatedAt: createdAt,
+		ExpiresAt: expiresAt,
+	})
+	return nil
+}
+
+func (store *FileAPIKeyStore) writeSnapshot(w io.Writer) error {
+	store.memory.mutex.RLock()
+	defer store.memory.mutex.RUnlock()
+
+	for _, key := range store.memory.keys {
+		record, err := newAPIKeyLogRecord(key)
+		if err != nil {
+			return err
+		}
+
+		err = writeRecord(w, record)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newAPIKeyLogRecord(key *APIKey) (*pb.APIKeyLogRecord, error) {
+	createdAt, err := ptypes.TimestampProto(key.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API key creation time: %w", err)
+	}
+
+	var expiresAt *tspb.Timestamp
+	if !key.ExpiresAt.IsZero() {
+		expiresAt, err = ptypes.TimestampProto(key.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid API key expiry: %w", err)
+		}
+	}
+
+	record := &pb.APIKeyLogRecord{
+		Id:        key.ID,
+		Name:      key.Name,
+		HashedKey: key.HashedKey,
+		Role:      key.Role,
+		CreatedBy: key.CreatedBy,
+		CreatedAt: createdAt,
+		ExpiresAt: expiresAt,
+	}
+	return record, nil
+}
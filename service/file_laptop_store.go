@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"io"
+	"sync"
+)
+
+// FileLaptopStore stores laptops in memory and persists every write to a local directory.
+// Writes are appended to a write-ahead log, which is compacted into a snapshot from time to time.
+type FileLaptopStore struct {
+	mutex   sync.Mutex
+	memory  *InMemoryLaptopStore
+	*recordStore
+	// sequence is the last sequence number found in the replayed records
+	sequence uint64
+}
+
+// NewFileLaptopStore returns a new FileLaptopStore that loads the snapshot and replays the log found in dir.
+// The sequence numbers of the changes go on from the replayed ones, so a watcher that resumes after
+// a restart gets ErrUnknownSequence instead of other changes under the same numbers.
+func NewFileLaptopStore(dir string) (*FileLaptopStore, error) {
+	store := &FileLaptopStore{
+		memory: NewInMemoryLaptopStore(),
+	}
+
+	records, err := openRecordStore(dir, "laptops", store.replay, store.writeSnapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	store.recordStore = records
+	// the replayed records are not changes of the catalog
+	store.memory.changes = newLaptopChangeLog(defaultChangeLogSize, store.sequence)
+	return store, nil
+}
+
+// Save saves the laptop to the laptopStore and assigns its first revision
+func (store *FileLaptopStore) Save(laptop *pb.Laptop) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if _, ok := store.memory.revision(laptop.Id); ok {
+		return ErrAlreadyExists
+	}
+
+	other, err := deepCopy(laptop)
+	if err != nil {
+		return err
+	}
+	other.Revision = 1
+
+	err = store.commitRecord(&pb.LaptopLogRecord{
+		Operation: pb.LaptopLogRecord_SAVE,
+		Laptop:    other,
+	})
+	if err != nil {
+		return err
+	}
+
+	laptop.Revision = other.Revision
+	return nil
+}
+
+// Find finds a laptop by ID
+func (store *FileLaptopStore) Find(id string) (*pb.Laptop, error) {
+	return store.memory.Find(id)
+}
+
+// Update replaces an existing laptop in the laptopStore if laptop.Revision is zero or
+// matches the stored revision, then assigns the next revision to the laptop
+func (store *FileLaptopStore) Update(laptop *pb.Laptop) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	revision, ok := store.memory.revision(laptop.Id)
+	if !ok {
+		return ErrNotFound
+	}
+	if laptop.Revision != 0 && laptop.Revision != revision {
+		return ErrRevisionMismatch
+	}
+
+	other, err := deepCopy(laptop)
+	if err != nil {
+		return err
+	}
+	other.Revision = revision + 1
+
+	err = store.commitRecord(&pb.LaptopLogRecord{
+		Operation: pb.LaptopLogRecord_UPDATE,
+		Laptop:    other,
+	})
+	if err != nil {
+		return err
+	}
+
+	laptop.Revision = other.Revision
+	return nil
+}
+
+// Delete deletes a laptop by ID if revision is zero or matches the stored revision
+func (store *FileLaptopStore) Delete(id string, revision uint64) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	stored, ok := store.memory.revision(id)
+	if !ok {
+		return ErrNotFound
+	}
+	if revision != 0 && revision != stored {
+		return ErrRevisionMismatch
+	}
+
+	return store.commitRecord(&pb.LaptopLogRecord{
+		Operation: pb.LaptopLogRecord_DELETE,
+		LaptopId:  id,
+	})
+}
+
+// Search searches for laptop with filter and the filter expression if it is not nil,
+// returns onw by one via the found function
+func (store *FileLaptopStore) Search(
+	ctx context.Context,
+	filter *pb.Filter,
+	expr *FilterExpr,
+	found func(laptop *pb.Laptop) error,
+) error {
+	return store.memory.Search(ctx, filter, expr, found)
+}
+
+// SearchText searches for laptops with filter and the filter expression whose brand, name, CPU or GPU
+// names match every word of the query, returns them by decreasing relevance score via the found function
+func (store *FileLaptopStore) SearchText(
+	ctx context.Context,
+	query string,
+	filter *pb.Filter,
+	expr *FilterExpr,
+	found func(laptop *pb.Laptop, score float64) error,
+) error {
+	return store.memory.SearchText(ctx, query, filter, expr, found)
+}
+
+// Facets counts the laptops that Search, or SearchText if the query has a word to search, would return
+// by brand, CPU brand, GPU brand, RAM size, storage driver, screen panel and release year
+func (store *FileLaptopStore) Facets(
+	ctx context.Context,
+	query string,
+	filter *pb.Filter,
+	expr *FilterExpr,
+) (*pb.SearchFacets, error) {
+	return store.memory.Facets(ctx, query, filter, expr)
+}
+
+// FindSimilar returns the k laptops nearest to the laptop with the given ID via the found function,
+// by increasing weighted distance over their normalized specs. A nil weights gives the same weight
+// to every spec. It returns ErrNotFound if the laptop doesn't exist.
+func (store *FileLaptopStore) FindSimilar(
+	ctx context.Context,
+	id string,
+	weights *pb.SimilarityWeights,
+	k int,
+	found func(laptop *pb.Laptop, distance float64) error,
+) error {
+	return store.memory.FindSimilar(ctx, id, weights, k, found)
+}
+
+// Watch calls fn with each change after the sequence number until the context is done or fn fails.
+// A sequence number of zero only watches the changes to come. It returns ErrUnknownSequence if
+// the changes after the sequence number are no longer kept.
+func (store *FileLaptopStore) Watch(ctx context.Context, after uint64, fn func(change *LaptopChange) error) error {
+	return store.memory.Watch(ctx, after, fn)
+}
+
+// Snapshot writes every laptop to a new snapshot and clears the write-ahead log
+func (store *FileLaptopStore) Snapshot() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.snapshot()
+}
+
+// commitRecord appends the record to the log before applying it to memory, the record gets the sequence
+// number of its change since the writes are serialized by the store mutex
+func (store *FileLaptopStore) commitRecord(record *pb.LaptopLogRecord) error {
+	record.Sequence = store.memory.changes.lastSequence() + 1
+	return store.commit(record, func() error {
+		return applyLaptopRecord(store.memory, record)
+	})
+}
+
+func (store *FileLaptopStore) replay(payload []byte) error {
+	record := &pb.LaptopLogRecord{}
+	err := proto.Unmarshal(payload, record)
+	if err != nil {
+		return fmt.Errorf("cannot unmarshal laptop record: %w", err)
+	}
+
+	if record.GetSequence() > store.sequence {
+		store.sequence = record.GetSequence()
+	}
+	return applyLaptopRecord(store.memory, record)
+}
+
+func (store *FileLaptopStore) writeSnapshot(w io.Writer) error {
+	store.memory.mutex.RLock()
+	defer store.memory.mutex.RUnlock()
+
+	err := writeRecord(w, &pb.LaptopLogRecord{
+		Operation: pb.LaptopLogRecord_SEQUENCE,
+		Sequence:  store.memory.changes.lastSequence(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, laptop := range store.memory.data {
+		record := &pb.LaptopLogRecord{
+			Operation: pb.LaptopLogRecord_SAVE,
+			Laptop:    laptop,
+		}
+
+		err := writeRecord(w, record)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyLaptopRecord applies a log record to memory, applying it more than once has no further effect
+func applyLaptopRecord(memory *InMemoryLaptopStore, record *pb.LaptopLogRecord) error {
+	switch record.GetOperation() {
+	case pb.LaptopLogRecord_SAVE, pb.LaptopLogRecord_UPDATE:
+		return memory.put(record.GetLaptop())
+	case pb.LaptopLogRecord_DELETE:
+		memory.remove(record.GetLaptopId())
+		return nil
+	case pb.LaptopLogRecord_SEQUENCE:
+		return nil
+	default:
+		return fmt.Errorf("unknown laptop record operation: %v", record.GetOperation())
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"github.com/treeforest/grpc-pcbook/sample"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileLaptopStoreReplay(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	store, err := NewFileLaptopStore(dir)
+	require.NoError(t, err)
+
+	laptop1 := sample.NewLaptop()
+	laptop2 := sample.NewLaptop()
+	laptop3 := sample.NewLaptop()
+	require.NoError(t, store.Save(laptop1))
+	require.NoError(t, store.Save(laptop2))
+	require.NoError(t, store.Save(laptop3))
+
+	laptop1.PriceUsd = 999
+	require.NoError(t, store.Update(laptop1))
+	require.NoError(t, store.Delete(laptop2.Id, 0))
+	require.NoError(t, store.Close())
+
+	store, err = NewFileLaptopStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	other, err := store.Find(laptop1.Id)
+	require.NoError(t, err)
+	require.Equal(t, 999.0, other.GetPriceUsd())
+	require.Equal(t, uint64(2), other.GetRevision())
+
+	other, err = store.Find(laptop2.Id)
+	require.NoError(t, err)
+	require.Nil(t, other)
+
+	other, err = store.Find(laptop3.Id)
+	require.NoError(t, err)
+	require.NotNil(t, other)
+
+	require.Equal(t, ErrAlreadyExists, store.Save(laptop3))
+
+	// the replayed records are not watched as changes
+	err = store.Watch(context.Background(), 1, func(change *LaptopChange) error {
+		return nil
+	})
+	require.Equal(t, ErrUnknownSequence, err)
+
+	// the sequence numbers go on from the ones given before the restart
+	laptop4 := sample.NewLaptop()
+	require.NoError(t, store.Save(laptop4))
+
+	stop := errors.New("stop")
+	err = store.Watch(context.Background(), 5, func(change *LaptopChange) error {
+		require.Equal(t, uint64(6), change.Event.GetSequence())
+		require.Equal(t, laptop4.Id, change.Event.GetLaptop().GetId())
+		return stop
+	})
+	require.Equal(t, stop, err)
+}
+
+func TestFileLaptopStoreSnapshotSequence(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	store, err := NewFileLaptopStore(dir)
+	require.NoError(t, err)
+
+	laptop1 := sample.NewLaptop()
+	require.NoError(t, store.Save(laptop1))
+	require.NoError(t, store.Delete(laptop1.Id, 0))
+
+	// the snapshot keeps the sequence number even without laptops
+	require.NoError(t, store.Snapshot())
+	require.NoError(t, store.Close())
+
+	store, err = NewFileLaptopStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	laptop2 := sample.NewLaptop()
+	require.NoError(t, store.Save(laptop2))
+
+	stop := errors.New("stop")
+	err = store.Watch(context.Background(), 2, func(change *LaptopChange) error {
+		require.Equal(t, uint64(3), change.Event.GetSequence())
+		return stop
+	})
+	require.Equal(t, stop, err)
+}
+
+func TestFileLaptopStoreSnapshot(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	store, err := NewFileLaptopStore(dir)
+	require.NoError(t, err)
+
+	laptop1 := sample.NewLaptop()
+	laptop2 := sample.NewLaptop()
+	require.NoError(t, store.Save(laptop1))
+	require.NoError(t, store.Snapshot())
+
+	info, err := os.Stat(filepath.Join(dir, "laptops.wal"))
+	require.NoError(t, err)
+	require.Zero(t, info.Size())
+
+	require.NoError(t, store.Save(laptop2))
+	require.NoError(t, store.Delete(laptop1.Id, 1))
+	require.NoError(t, store.Close())
+
+	store, err = NewFileLaptopStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	other, err := store.Find(laptop1.Id)
+	require.NoError(t, err)
+	require.Nil(t, other)
+
+	other, err = store.Find(laptop2.Id)
+	require.NoError(t, err)
+	require.NotNil(t, other)
+}
+
+func TestFileLaptopStoreTruncatedRecord(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	store, err := NewFileLaptopStore(dir)
+	require.NoError(t, err)
+
+	laptop1 := sample.NewLaptop()
+	laptop2 := sample.NewLaptop()
+	require.NoError(t, store.Save(laptop1))
+	require.NoError(t, store.Save(laptop2))
+	require.NoError(t, store.Close())
+
+	// cut the last record in half as if the server crashed while writing it
+	logPath := filepath.Join(dir, "laptops.wal")
+	info, err := os.Stat(logPath)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(logPath, info.Size()-10))
+
+	store, err = NewFileLaptopStore(dir)
+	require.NoError(t, err)
+
+	other, err := store.Find(laptop1.Id)
+	require.NoError(t, err)
+	require.NotNil(t, other)
+
+	other, err = store.Find(laptop2.Id)
+	require.NoError(t, err)
+	require.Nil(t, other)
+
+	// the log accepts new records after the cut
+	require.NoError(t, store.Save(laptop2))
+	require.NoError(t, store.Close())
+
+	store, err = NewFileLaptopStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	other, err = store.Find(laptop2.Id)
+	require.NoError(t, err)
+	require.NotNil(t, other)
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"io"
+	"sync"
+	"time"
+)
+
+// FileRatingStore stores laptop rating in memory and persists every change to a local directory
+type FileRatingStore struct {
+	mutex  sync.Mutex
+	memory *InMemoryRatingStore
+	*recordStore
+}
+
+// NewFileRatingStore returns a new FileRatingStore that loads the snapshot and replays the log found in dir
+func NewFileRatingStore(dir string) (*FileRatingStore, error) {
+	store := &FileRatingStore{
+		memory: NewInMemoryRatingStore(),
+	}
+
+	records, err := openRecordStore(dir, "ratings", store.replay, store.writeSnapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	store.recordStore = records
+	return store, nil
+}
+
+// Rate sets the score a user gives to a laptop, replacing the previous score of the user,
+// and returns the rating of the laptop. It returns ErrInvalidScore if the score is out of range.
+func (store *FileRatingStore) Rate(username string, laptopID string, score float64) (*Rating, error) {
+	if !isValidScore(score) {
+		return nil, ErrInvalidScore
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	ratedAt := time.Now()
+	ratedAtProto, err := ptypes.TimestampProto(ratedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &pb.RatingLogRecord{
+		LaptopId: laptopID,
+		Username: username,
+		Score:    score,
+		RatedAt:  ratedAtProto,
+	}
+
+	err = store.commit(record, func() error {
+		store.memory.put(username, laptopID, score, ratedAt)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return store.memory.Find(laptopID)
+}
+
+// Find finds the rating of a laptop, returns nil if it has not been rated
+func (store *FileRatingStore) Find(laptopID string) (*Rating, error) {
+	return store.memory.Find(laptopID)
+}
+
+// FindScore finds the score a user gives to a laptop, returns false if the user has not rated it
+func (store *FileRatingStore) FindScore(username string, laptopID string) (float64, bool, error) {
+	return store.memory.FindScore(username, laptopID)
+}
+
+// FindWeighted finds the rating of a laptop where each score counts as weight(ratedAt) scores,
+// returns nil if it has not been rated
+func (store *FileRatingStore) FindWeighted(
+	laptopID string,
+	weight func(ratedAt time.Time) float64,
+) (*WeightedRating, error) {
+	return store.memory.FindWeighted(laptopID, weight)
+}
+
+func (store *FileRatingStore) replay(payload []byte) error {
+	record := &pb.RatingLogRecord{}
+	err := proto.Unmarshal(payload, record)
+	if err != nil {
+		return fmt.Errorf("cannot unmarshal rating record: %w", err)
+	}
+
+	if record.GetUsername() == "" {
+		store.memory.putAnonymous(record.GetLaptopId(), &Rating{
+			Count: record.GetCount(),
+			Sum:   record.GetSum(),
+		})
+		return nil
+	}
+
+	// the records written before the times were recorded have no time
+	var ratedAt time.Time
+	if record.GetRatedAt() != nil {
+		ratedAt, err = ptypes.Timestamp(record.GetRatedAt())
+		if err != nil {
+			return fmt.Errorf("invalid rating time: %w", err)
+		}
+	}
+
+	store.memory.put(record.GetUsername(), record.GetLaptopId(), record.GetScore(), ratedAt)
+	return nil
+}
+
+func (store *FileRatingStore) writeSnapshot(w io.Writer) error {
+	store.memory.mutex.RLock()
+	defer store.memory.mutex.RUnlock()
+
+	for laptopID, anonymous := range store.memory.anonymous {
+		record := &pb.RatingLogRecord{
+			LaptopId: laptopID,
+			Count:    anonymous.Count,
+			Sum:      anonymous.Sum,
+		}
+
+		err := writeRecord(w, record)
+		if err != nil {
+			return err
+		}
+	}
+
+	for laptopID, scores := range store.memory.scores {
+		for username, score := range scores {
+			record := &pb.RatingLogRecord{
+				LaptopId: laptopID,
+				Username: username,
+				Score:    score.score,
+			}
+			if !score.ratedAt.IsZero() {
+				ratedAt, err := ptypes.TimestampProto(score.ratedAt)
+				if err != nil {
+					return err
+				}
+				record.RatedAt = ratedAt
+			}
+
+			err := writeRecord(w, record)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
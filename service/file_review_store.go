@@ -0,0 +1,131 @@
+package service
+
+import (
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"io"
+	"sync"
+)
+
+// FileReviewStore stores reviews in memory and persists every change to a local directory
+type FileReviewStore struct {
+	mutex  sync.Mutex
+	memory *InMemoryReviewStore
+	*recordStore
+}
+
+// NewFileReviewStore returns a new FileReviewStore that loads the snapshot and replays the log found in dir
+func NewFileReviewStore(dir string) (*FileReviewStore, error) {
+	store := &FileReviewStore{
+		memory: NewInMemoryReviewStore(),
+	}
+
+	records, err := openRecordStore(dir, "reviews", store.replay, store.writeSnapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	store.recordStore = records
+	return store, nil
+}
+
+// Save saves a new review, returns ErrAlreadyExists if the user has already reviewed the laptop
+func (store *FileReviewStore) Save(review *pb.Review) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.memory.exists(review) {
+		return ErrAlreadyExists
+	}
+
+	return store.commitReview(proto.Clone(review).(*pb.Review))
+}
+
+// Find finds a review by ID, returns nil if there is none
+func (store *FileReviewStore) Find(id string) (*pb.Review, error) {
+	return store.memory.Find(id)
+}
+
+// Update replaces an existing review, its laptop and author cannot change
+func (store *FileReviewStore) Update(review *pb.Review) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	stored, err := store.memory.Find(review.GetId())
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		return ErrNotFound
+	}
+
+	other := proto.Clone(review).(*pb.Review)
+	other.LaptopId = stored.GetLaptopId()
+	other.Username = stored.GetUsername()
+	return store.commitReview(other)
+}
+
+// Delete deletes a review by ID
+func (store *FileReviewStore) Delete(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	stored, err := store.memory.Find(id)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		return ErrNotFound
+	}
+
+	record := &pb.ReviewLogRecord{DeletedId: id}
+	return store.commit(record, func() error {
+		store.memory.remove(id)
+		return nil
+	})
+}
+
+// List calls found with the reviews of a laptop from the newest to the oldest, until found returns false
+func (store *FileReviewStore) List(laptopID string, found func(review *pb.Review) bool) error {
+	return store.memory.List(laptopID, found)
+}
+
+// commitReview appends a record with the whole review to the log before storing it in memory
+func (store *FileReviewStore) commitReview(review *pb.Review) error {
+	record := &pb.ReviewLogRecord{Review: review}
+	return store.commit(record, func() error {
+		store.memory.put(review)
+		return nil
+	})
+}
+
+func (store *FileReviewStore) replay(payload []byte) error {
+	record := &pb.ReviewLogRecord{}
+	err := proto.Unmarshal(payload, record)
+	if err != nil {
+		return fmt.Errorf("cannot unmarshal review record: %w", err)
+	}
+
+	if record.GetReview() == nil {
+		store.memory.remove(record.GetDeletedId())
+		return nil
+	}
+
+	store.memory.put(record.GetReview())
+	return nil
+}
+
+func (store *FileReviewStore) writeSnapshot(w io.Writer) error {
+	store.memory.mutex.RLock()
+	defer store.memory.mutex.RUnlock()
+
+	for _, review := range store.memory.reviews {
+		err := writeRecord(w, &pb.ReviewLogRecord{Review: review})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
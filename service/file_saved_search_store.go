@@ -0,0 +1,111 @@
+package service
+
+import (
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"io"
+	"sync"
+)
+
+// FileSavedSearchStore stores saved searches in memory and persists every change to a local directory
+type FileSavedSearchStore struct {
+	mutex  sync.Mutex
+	memory *InMemorySavedSearchStore
+	*recordStore
+}
+
+// NewFileSavedSearchStore returns a new FileSavedSearchStore that loads the snapshot and replays the log found in dir
+func NewFileSavedSearchStore(dir string) (*FileSavedSearchStore, error) {
+	store := &FileSavedSearchStore{
+		memory: NewInMemorySavedSearchStore(),
+	}
+
+	records, err := openRecordStore(dir, "saved_searches", store.replay, store.writeSnapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	store.recordStore = records
+	return store, nil
+}
+
+// Save saves a saved search of the user
+func (store *FileSavedSearchStore) Save(username string, search *pb.SavedSearch) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.memory.exists(username, search.GetId()) {
+		return ErrAlreadyExists
+	}
+
+	record := &pb.SavedSearchLogRecord{
+		Username: username,
+		Search:   search,
+	}
+	return store.commit(record, func() error {
+		store.memory.put(username, search)
+		return nil
+	})
+}
+
+// List returns the saved searches of the user in the order they were saved
+func (store *FileSavedSearchStore) List(username string) ([]*pb.SavedSearch, error) {
+	return store.memory.List(username)
+}
+
+// Delete deletes a saved search of the user by ID
+func (store *FileSavedSearchStore) Delete(username string, id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if !store.memory.exists(username, id) {
+		return ErrNotFound
+	}
+
+	record := &pb.SavedSearchLogRecord{
+		Username:  username,
+		DeletedId: id,
+	}
+	return store.commit(record, func() error {
+		store.memory.remove(username, id)
+		return nil
+	})
+}
+
+func (store *FileSavedSearchStore) replay(payload []byte) error {
+	record := &pb.SavedSearchLogRecord{}
+	err := proto.Unmarshal(payload, record)
+	if err != nil {
+		return fmt.Errorf("cannot unmarshal saved search record: %w", err)
+	}
+
+	if record.GetSearch() == nil {
+		store.memory.remove(record.GetUsername(), record.GetDeletedId())
+		return nil
+	}
+
+	store.memory.put(record.GetUsername(), record.GetSearch())
+	return nil
+}
+
+func (store *FileSavedSearchStore) writeSnapshot(w io.Writer) error {
+	store.memory.mutex.RLock()
+	defer store.memory.mutex.RUnlock()
+
+	for username, searches := range store.memory.searches {
+		for _, search := range searches {
+			record := &pb.SavedSearchLogRecord{
+				Username: username,
+				Search:   search,
+			}
+
+			err := writeRecord(w, record)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
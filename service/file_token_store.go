@@ -0,0 +1,259 @@
+package service
+
+import (
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"io"
+	"sync"
+	"time"
+)
+
+// FileTokenStore stores tokens in memory and persists every change to a local directory,
+// so that the revoked tokens stay revoked when the server restarts. Only the hash of a refresh token
+// is ever written to disk.
+type FileTokenStore struct {
+	mutex  sync.Mutex
+	memory *InMemoryTokenStore
+	*recordStore
+}
+
+// NewFileTokenStore returns a new FileTokenStore that loads the snapshot and replays the log found in dir
+func NewFileTokenStore(dir string) (*FileTokenStore, error) {
+	store := &FileTokenStore{
+		memory: NewInMemoryTokenStore(),
+	}
+
+	records, err := openRecordStore(dir, "tokens", store.replay, store.writeSnapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	store.recordStore = records
+	return store, nil
+}
+
+// SaveRefreshToken saves a new refresh token
+func (store *FileTokenStore) SaveRefreshToken(token *RefreshToken) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.memory.findRefreshToken(token.HashedToken) != nil {
+		return ErrAlreadyExists
+	}
+
+	record, err := newRefreshTokenLogRecord(token)
+	if err != nil {
+		return err
+	}
+
+	return store.commitRecord(record)
+}
+
+// UseRefreshToken marks the refresh token with the hash as used and returns it as it was before,
+// returns nil if there is none
+func (store *FileTokenStore) UseRefreshToken(hashedToken string) (*RefreshToken, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	token := store.memory.findRefreshToken(hashedToken)
+	if token == nil || token.Used {
+		return token, nil
+	}
+
+	record := &pb.TokenLogRecord{
+		Operation:   pb.TokenLogRecord_USE_REFRESH_TOKEN,
+		HashedToken: hashedToken,
+	}
+	err := store.commitRecord(record)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// RevokeFamily revokes the refresh tokens of a family and the access tokens issued with them
+func (store *FileTokenStore) RevokeFamily(family string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	record := &pb.TokenLogRecord{
+		Operation: pb.TokenLogRecord_REVOKE_FAMILY,
+		Family:    family,
+	}
+	return store.commitRecord(record)
+}
+
+// RevokeUser revokes every refresh token family of the user and the access tokens issued with them
+func (store *FileTokenStore) RevokeUser(username string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	record := &pb.TokenLogRecord{
+		Operation: pb.TokenLogRecord_REVOKE_USER,
+		Username:  username,
+	}
+	return store.commitRecord(record)
+}
+
+// RevokeAccessToken revokes an access token by ID until it expires
+func (store *FileTokenStore) RevokeAccessToken(id string, expiresAt time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	expiry, err := timestampProto(expiresAt)
+	if err != nil {
+		return fmt.Errorf("invalid access token expiry: %w", err)
+	}
+
+	record := &pb.TokenLogRecord{
+		Operation:     pb.TokenLogRecord_REVOKE_ACCESS_TOKEN,
+		AccessTokenId: id,
+		ExpiresAt:     expiry,
+	}
+	return store.commitRecord(record)
+}
+
+// IsRevoked reports whether an access token with the ID and the family, which may be empty, is revoked
+func (store *FileTokenStore) IsRevoked(id string, family string) (bool, error) {
+	return store.memory.IsRevoked(id, family)
+}
+
+// commitRecord writes the record to the log and applies it, the caller must hold the lock
+func (store *FileTokenStore) commitRecord(record *pb.TokenLogRecord) error {
+	return store.commit(record, func() error {
+		return store.apply(record)
+	})
+}
+
+func (store *FileTokenStore) replay(payload []byte) error {
+	record := &pb.TokenLogRecord{}
+	err := proto.Unmarshal(payload, record)
+	if err != nil {
+		return fmt.Errorf("cannot unmarshal token record: %w", err)
+	}
+
+	return store.apply(record)
+}
+
+// apply applies a record to the tokens in memory
+func (store *FileTokenStore) apply(record *pb.TokenLogRecord) error {
+	var expiresAt time.Time
+	if record.GetExpiresAt() != nil {
+		var err error
+		expiresAt, err = ptypes.Timestamp(record.GetExpiresAt())
+		if err != nil {
+			return fmt.Errorf("invalid token expiry: %w", err)
+		}
+	}
+
+	memory := store.memory
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	switch record.GetOperation() {
+	case pb.TokenLogRecord_SAVE_REFRESH_TOKEN:
+		memory.putRefreshToken(&RefreshToken{
+			HashedToken: record.GetHashedToken(),
+			Family:      record.GetFamily(),
+			Username:    record.GetUsername(),
+			ExpiresAt:   expiresAt,
+			Used:        record.GetUsed(),
+		})
+	case pb.TokenLogRecord_USE_REFRESH_TOKEN:
+		if token := memory.refreshTokens[record.GetHashedToken()]; token != nil {
+			token.Used = true
+		}
+	case pb.TokenLogRecord_REVOKE_FAMILY:
+		memory.revokeFamily(record.GetFamily(), expiresAt)
+	case pb.TokenLogRecord_REVOKE_ACCESS_TOKEN:
+		memory.revokedTokens[record.GetAccessTokenId()] = expiresAt
+	case pb.TokenLogRecord_REVOKE_USER:
+		memory.revokeUser(record.GetUsername())
+	default:
+		return fmt.Errorf("unknown token record operation %v", record.GetOperation())
+	}
+
+	memory.written()
+	return nil
+}
+
+func (store *FileTokenStore) writeSnapshot(w io.Writer) error {
+	memory := store.memory
+	memory.mutex.RLock()
+	defer memory.mutex.RUnlock()
+
+	for _, token := range memory.refreshTokens {
+		record, err := newRefreshTokenLogRecord(token)
+		if err != nil {
+			return err
+		}
+
+		err = writeRecord(w, record)
+		if err != nil {
+			return err
+		}
+	}
+
+	for family, expiresAt := range memory.revokedFamilies {
+		err := writeRevocationRecord(w, pb.TokenLogRecord_REVOKE_FAMILY, family, expiresAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	for id, expiresAt := range memory.revokedTokens {
+		err := writeRevocationRecord(w, pb.TokenLogRecord_REVOKE_ACCESS_TOKEN, id, expiresAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeRevocationRecord writes the record that revokes the family or the access token with the ID until expiresAt
+func writeRevocationRecord(w io.Writer, operation pb.TokenLogRecord_Operation, id string, expiresAt time.Time) error {
+	record := &pb.TokenLogRecord{Operation: operation}
+	if operation == pb.TokenLogRecord_REVOKE_FAMILY {
+		record.Family = id
+	} else {
+		record.AccessTokenId = id
+	}
+
+	var err error
+	record.ExpiresAt, err = timestampProto(expiresAt)
+	if err != nil {
+		return fmt.Errorf("invalid revocation expiry: %w", err)
+	}
+
+	return writeRecord(w, record)
+}
+
+func newRefreshTokenLogRecord(token *RefreshToken) (*pb.TokenLogRecord, error) {
+	expiresAt, err := timestampProto(token.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid refresh token expiry: %w", err)
+	}
+
+	record := &pb.TokenLogRecord{
+		Operation:   pb.TokenLogRecord_SAVE_REFRESH_TOKEN,
+		HashedToken: token.HashedToken,
+		Family:      token.Family,
+		Username:    token.Username,
+		ExpiresAt:   expiresAt,
+		Used:        token.Used,
+	}
+	return record, nil
+}
+
+// timestampProto converts a time to a timestamp, the zero time to nil
+func timestampProto(t time.Time) (*tspb.Timestamp, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	return ptypes.TimestampProto(t)
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"io"
+	"sync"
+)
+
+// FileUserStore stores users in memory and persists every change to a local directory.
+// Only the bcrypt hash of a password is ever written to disk.
+type FileUserStore struct {
+	mutex  sync.Mutex
+	memory *InMemoryUserStore
+	*recordStore
+}
+
+// NewFileUserStore returns a new FileUserStore that loads the snapshot and replays the log found in dir
+func NewFileUserStore(dir string) (*FileUserStore, error) {
+	store := &FileUserStore{
+		memory: NewInMemoryUserStore(),
+	}
+
+	records, err := openRecordStore(dir, "users", store.replay, store.writeSnapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	store.recordStore = records
+	return store, nil
+}
+
+// Save saves a user to the store
+func (store *FileUserStore) Save(user *User) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	other, err := store.memory.Find(user.Username)
+	if err != nil {
+		return err
+	}
+	if other != nil {
+		return ErrAlreadyExists
+	}
+
+	return store.commit(newUserLogRecord(user), func() error {
+		store.memory.put(user)
+		return nil
+	})
+}
+
+// Find finds a user by username
+func (store *FileUserStore) Find(username string) (*User, error) {
+	return store.memory.Find(username)
+}
+
+// Update replaces the user with the same username, returns ErrNotFound if there is none
+func (store *FileUserStore) Update(user *User) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	other, err := store.memory.Find(user.Username)
+	if err != nil {
+		return err
+	}
+	if other == nil {
+		return ErrNotFound
+	}
+
+	return store.commit(newUserLogRecord(user), func() error {
+		store.memory.put(user)
+		return nil
+	})
+}
+
+// List calls found with the users whose username comes after the given one, ordered by username,
+// until found returns false
+func (store *FileUserStore) List(after string, found func(user *User) bool) error {
+	return store.memory.List(after, found)
+}
+
+func (store *FileUserStore) replay(payload []byte) error {
+	record := &pb.UserLogRecord{}
+	err := proto.Unmarshal(payload, record)
+	if err != nil {
+		return fmt.Errorf("cannot unmarshal user record: %w", err)
+	}
+
+	store.memory.put(&User{
+		Username:       record.GetUsername(),
+		HashedPassword: record.GetHashedPassword(),
+		Role:           record.GetRole(),
+		Disabled:       record.GetDisabled(),
+	})
+	return nil
+}
+
+func (store *FileUserStore) writeSnapshot(w io.Writer) error {
+	store.memory.mutex.RLock()
+	defer store.memory.mutex.RUnlock()
+
+	for _, user := range store.memory.users {
+		err := writeRecord(w, newUserLogRecord(user))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newUserLogRecord(user *User) *pb.UserLogRecord {
+	return &pb.UserLogRecord{
+		Username:       user.Username,
+		HashedPassword: user.HashedPassword,
+		Role:           user.Role,
+		Disabled:       user.Disabled,
+	}
+}
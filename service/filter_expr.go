@@ -0,0 +1,632 @@
+package service
+
+import (
+	"fmt"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FilterExprError is returned when a filter expression cannot be parsed or type-checked
+type FilterExprError struct {
+	// Position is the 1-based offset of the error in the expression
+	Position int
+	Message  string
+}
+
+func (err *FilterExprError) Error() string {
+	return fmt.Sprintf("position %d: %s", err.Position, err.Message)
+}
+
+const (
+	// the longest filter expression, which keeps the expressions of the requests small
+	maxFilterExprLength = 4096
+	// the deepest nesting of parentheses and negations, which bounds the recursion of the parser
+	maxFilterExprDepth = 64
+)
+
+// FilterExpr is a compiled filter expression such as
+// `cpu.number_cores >= 6 && ram >= 16GB && (brand == "Dell" || brand == "Apple")`.
+//
+// A field is a path of pb.Laptop fields. A path through a repeated field, such as gpus.brand,
+// matches if any element matches. Memory fields are compared with memory literals
+// (16GB, 512MB), and the weight field with weight literals (1.5kg, 4lb) or a number of kilograms.
+// Enum fields are compared with the names of their values, for example keyboard.layout == QWERTY.
+type FilterExpr struct {
+	source string
+	root   exprNode
+}
+
+// ParseFilterExpr parses and type-checks a filter expression, errors are of type *FilterExprError
+func ParseFilterExpr(source string) (*FilterExpr, error) {
+	if len(source) > maxFilterExprLength {
+		return nil, &FilterExprError{
+			Position: maxFilterExprLength + 1,
+			Message:  fmt.Sprintf("expression is longer than %d bytes", maxFilterExprLength),
+		}
+	}
+
+	tokens, err := lexFilterExpr(source)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &exprParser{tokens: tokens}
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if next := parser.peek(); next.kind != tokenEOF {
+		return nil, next.errorf("unexpected %s", next)
+	}
+
+	return &FilterExpr{source: source, root: root}, nil
+}
+
+// Match reports whether the laptop satisfies the expression, a nil expression matches every laptop
+func (expr *FilterExpr) Match(laptop *pb.Laptop) bool {
+	if expr == nil {
+		return true
+	}
+	return expr.root.eval(laptop)
+}
+
+// String returns the source of the expression
+func (expr *FilterExpr) String() string {
+	if expr == nil {
+		return ""
+	}
+	return expr.source
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type exprToken struct {
+	kind tokenKind
+	text string
+	// unit is the suffix of a number such as GB or kg
+	unit string
+	pos  int
+}
+
+func (token exprToken) String() string {
+	if token.kind == tokenEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(token.text + token.unit)
+}
+
+func (token exprToken) errorf(format string, args ...interface{}) error {
+	return &FilterExprError{Position: token.pos + 1, Message: fmt.Sprintf(format, args...)}
+}
+
+var exprOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"}
+
+func lexFilterExpr(source string) ([]exprToken, error) {
+	var tokens []exprToken
+	isIdent := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.'
+	}
+
+	for pos := 0; pos < len(source); {
+		r := rune(source[pos])
+		switch {
+		case unicode.IsSpace(r):
+			pos++
+
+		case unicode.IsDigit(r):
+			end := pos
+			for end < len(source) && (unicode.IsDigit(rune(source[end])) || source[end] == '.') {
+				end++
+			}
+			unitEnd := end
+			for unitEnd < len(source) && unicode.IsLetter(rune(source[unitEnd])) {
+				unitEnd++
+			}
+			tokens = append(tokens, exprToken{
+				kind: tokenNumber,
+				text: source[pos:end],
+				unit: source[end:unitEnd],
+				pos:  pos,
+			})
+			pos = unitEnd
+
+		case unicode.IsLetter(r) || r == '_':
+			end := pos
+			for end < len(source) && isIdent(rune(source[end])) {
+				end++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, text: source[pos:end], pos: pos})
+			pos = end
+
+		case r == '"':
+			end := pos + 1
+			for end < len(source) && source[end] != '"' {
+				if source[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(source) {
+				return nil, &FilterExprError{Position: pos + 1, Message: "unterminated string"}
+			}
+
+			text, err := strconv.Unquote(source[pos : end+1])
+			if err != nil {
+				return nil, &FilterExprError{Position: pos + 1, Message: "invalid string"}
+			}
+			tokens = append(tokens, exprToken{kind: tokenString, text: text, pos: pos})
+			pos = end + 1
+
+		default:
+			operator := ""
+			for _, op := range exprOperators {
+				if strings.HasPrefix(source[pos:], op) {
+					operator = op
+					break
+				}
+			}
+			if operator == "" {
+				r, _ = utf8.DecodeRuneInString(source[pos:])
+				return nil, &FilterExprError{Position: pos + 1, Message: fmt.Sprintf("unexpected character %q", r)}
+			}
+			tokens = append(tokens, exprToken{kind: tokenOperator, text: operator, pos: pos})
+			pos += len(operator)
+		}
+	}
+
+	return append(tokens, exprToken{kind: tokenEOF, pos: len(source)}), nil
+}
+
+// exprNode is a node of a compiled filter expression
+type exprNode interface {
+	eval(laptop *pb.Laptop) bool
+}
+
+type andNode struct{ left, right exprNode }
+
+func (node andNode) eval(laptop *pb.Laptop) bool {
+	return node.left.eval(laptop) && node.right.eval(laptop)
+}
+
+type orNode struct{ left, right exprNode }
+
+func (node orNode) eval(laptop *pb.Laptop) bool {
+	return node.left.eval(laptop) || node.right.eval(laptop)
+}
+
+type notNode struct{ operand exprNode }
+
+func (node notNode) eval(laptop *pb.Laptop) bool {
+	return !node.operand.eval(laptop)
+}
+
+// exprParser is a recursive descent parser of the grammar:
+//
+//	or         = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | "(" or ")" | comparison
+//	comparison = field [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) literal ]
+type exprParser struct {
+	tokens []exprToken
+	next   int
+	// number of "!" and "(" that the current token is nested in
+	depth int
+}
+
+func (parser *exprParser) peek() exprToken {
+	return parser.tokens[parser.next]
+}
+
+func (parser *exprParser) advance() exprToken {
+	token := parser.tokens[parser.next]
+	if token.kind != tokenEOF {
+		parser.next++
+	}
+	return token
+}
+
+func (parser *exprParser) accept(operator string) bool {
+	token := parser.peek()
+	if token.kind == tokenOperator && token.text == operator {
+		parser.next++
+		return true
+	}
+	return false
+}
+
+func (parser *exprParser) parseOr() (exprNode, error) {
+	left, err := parser.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for parser.accept("||") {
+		right, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+
+	return left, nil
+}
+
+func (parser *exprParser) parseAnd() (exprNode, error) {
+	left, err := parser.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for parser.accept("&&") {
+		right, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+
+	return left, nil
+}
+
+func (parser *exprParser) parseUnary() (exprNode, error) {
+	if not := parser.peek(); parser.accept("!") {
+		err := parser.nest(not)
+		if err != nil {
+			return nil, err
+		}
+		defer parser.unnest()
+
+		operand, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+
+	if open := parser.peek(); parser.accept("(") {
+		err := parser.nest(open)
+		if err != nil {
+			return nil, err
+		}
+		defer parser.unnest()
+
+		node, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !parser.accept(")") {
+			return nil, parser.peek().errorf("expected \")\" to close \"(\" at position %d", open.pos+1)
+		}
+		return node, nil
+	}
+
+	return parser.parseComparison()
+}
+
+// nest enters the operand of the "!" or "(" token, unless it is nested too deeply
+func (parser *exprParser) nest(token exprToken) error {
+	if parser.depth >= maxFilterExprDepth {
+		return token.errorf("expression is nested more than %d levels deep", maxFilterExprDepth)
+	}
+	parser.depth++
+	return nil
+}
+
+func (parser *exprParser) unnest() {
+	parser.depth--
+}
+
+func (parser *exprParser) parseComparison() (exprNode, error) {
+	token := parser.advance()
+	if token.kind != tokenIdent {
+		return nil, token.errorf("expected a field, got %s", token)
+	}
+
+	field, err := resolveExprField(token)
+	if err != nil {
+		return nil, err
+	}
+
+	operator := parser.peek()
+	switch operator.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+		if operator.kind != tokenOperator {
+			break
+		}
+		parser.advance()
+
+		literal := parser.advance()
+		return field.compare(operator, literal)
+	}
+
+	// a boolean field alone is true when the field is set
+	if field.kind != fieldBool {
+		return nil, operator.errorf("expected a comparison operator after %s", token.text)
+	}
+	return comparisonNode{field: field, op: "==", value: exprValue{boolean: true}}, nil
+}
+
+type fieldKind int
+
+const (
+	fieldNumber fieldKind = iota
+	fieldString
+	fieldBool
+	fieldEnum
+	fieldMemory
+	fieldWeight
+)
+
+// exprField is a field path of pb.Laptop
+type exprField struct {
+	path []protoreflect.FieldDescriptor
+	kind fieldKind
+}
+
+var laptopDescriptor = (&pb.Laptop{}).ProtoReflect().Descriptor()
+
+// resolveExprField type-checks a field path against the pb.Laptop descriptor
+func resolveExprField(token exprToken) (exprField, error) {
+	if token.text == "weight" {
+		return exprField{kind: fieldWeight}, nil
+	}
+
+	field := exprField{}
+	message := laptopDescriptor
+	for i, name := range strings.Split(token.text, ".") {
+		if message == nil {
+			return field, token.errorf("%s has no field %s", strings.Join(strings.Split(token.text, ".")[:i], "."), name)
+		}
+
+		fd := message.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return field, token.errorf("unknown field %s", token.text)
+		}
+
+		field.path = append(field.path, fd)
+		message = fd.Message()
+	}
+
+	last := field.path[len(field.path)-1]
+	switch last.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Int64Kind, protoreflect.Uint32Kind, protoreflect.Uint64Kind,
+		protoreflect.FloatKind, protoreflect.DoubleKind:
+		field.kind = fieldNumber
+	case protoreflect.StringKind:
+		field.kind = fieldString
+	case protoreflect.BoolKind:
+		field.kind = fieldBool
+	case protoreflect.EnumKind:
+		field.kind = fieldEnum
+	case protoreflect.MessageKind:
+		if last.Message().FullName() != (&pb.Memory{}).ProtoReflect().Descriptor().FullName() {
+			return field, token.errorf("cannot compare message field %s", token.text)
+		}
+		field.kind = fieldMemory
+	default:
+		return field, token.errorf("cannot compare field %s of type %s", token.text, last.Kind())
+	}
+
+	return field, nil
+}
+
+// exprValue is the value of a literal, numbers, memory and weight are held as float64
+// in units of one, bits and kilograms respectively
+type exprValue struct {
+	number  float64
+	text    string
+	boolean bool
+	enum    protoreflect.EnumNumber
+}
+
+var memoryUnitBits = map[string]float64{
+	"bit": 1,
+	"b":   1 << 3,
+	"kb":  1 << 13,
+	"mb":  1 << 23,
+	"gb":  1 << 33,
+	"tb":  1 << 43,
+}
+
+var weightUnitKg = map[string]float64{
+	"kg": 1,
+	"lb": kgPerLb,
+}
+
+// compare type-checks the literal against the field and returns the comparison node
+func (field exprField) compare(operator exprToken, literal exprToken) (exprNode, error) {
+	node := comparisonNode{field: field, op: operator.text}
+
+	ordered := operator.text != "==" && operator.text != "!="
+	if ordered && field.kind != fieldNumber && field.kind != fieldMemory && field.kind != fieldWeight {
+		return nil, operator.errorf("operator %s cannot compare %s", operator.text, field.describe())
+	}
+
+	switch field.kind {
+	case fieldNumber, fieldMemory, fieldWeight:
+		if literal.kind != tokenNumber {
+			return nil, literal.errorf("expected %s, got %s", field.describe(), literal)
+		}
+
+		number, err := strconv.ParseFloat(literal.text, 64)
+		if err != nil {
+			return nil, literal.errorf("invalid number %s", literal)
+		}
+
+		unit := strings.ToLower(literal.unit)
+		scale, ok := 1.0, true
+		switch field.kind {
+		case fieldNumber:
+			ok = unit == ""
+		case fieldMemory:
+			scale, ok = memoryUnitBits[unit]
+		case fieldWeight:
+			if unit != "" {
+				scale, ok = weightUnitKg[unit]
+			}
+		}
+		if !ok {
+			return nil, literal.errorf("expected %s, got %s", field.describe(), literal)
+		}
+
+		node.value.number = number * scale
+		if field.kind == fieldNumber && field.path[len(field.path)-1].Kind() == protoreflect.FloatKind {
+			// compare with the value a float field would hold
+			node.value.number = float64(float32(node.value.number))
+		}
+
+	case fieldString:
+		if literal.kind != tokenString {
+			return nil, literal.errorf("expected %s, got %s", field.describe(), literal)
+		}
+		node.value.text = literal.text
+
+	case fieldBool:
+		if literal.kind != tokenIdent || (literal.text != "true" && literal.text != "false") {
+			return nil, literal.errorf("expected %s, got %s", field.describe(), literal)
+		}
+		node.value.boolean = literal.text == "true"
+
+	case fieldEnum:
+		enum := field.path[len(field.path)-1].Enum()
+		value := enum.Values().ByName(protoreflect.Name(literal.text))
+		if literal.kind != tokenIdent || value == nil {
+			return nil, literal.errorf("expected %s, got %s", field.describe(), literal)
+		}
+		node.value.enum = value.Number()
+	}
+
+	return node, nil
+}
+
+// describe describes the literals the field can be compared with
+func (field exprField) describe() string {
+	switch field.kind {
+	case fieldString:
+		return "a string"
+	case fieldBool:
+		return "true or false"
+	case fieldEnum:
+		enum := field.path[len(field.path)-1].Enum()
+		names := make([]string, enum.Values().Len())
+		for i := range names {
+			names[i] = string(enum.Values().Get(i).Name())
+		}
+		return "one of " + strings.Join(names, ", ")
+	case fieldMemory:
+		return "a memory size such as 16GB"
+	case fieldWeight:
+		return "a weight such as 1.5kg or 4lb"
+	default:
+		return "a number"
+	}
+}
+
+// comparisonNode compares a field with a literal
+type comparisonNode struct {
+	field exprField
+	op    string
+	value exprValue
+}
+
+func (node comparisonNode) eval(laptop *pb.Laptop) bool {
+	if node.field.kind == fieldWeight {
+		kg, ok := weightKg(laptop)
+		return ok && compareNumbers(kg, node.op, node.value.number)
+	}
+
+	return anyValue(laptop.ProtoReflect(), node.field.path, func(value protoreflect.Value) bool {
+		switch node.field.kind {
+		case fieldNumber:
+			return compareNumbers(numberOf(value), node.op, node.value.number)
+		case fieldMemory:
+			memory, _ := value.Message().Interface().(*pb.Memory)
+			return compareNumbers(float64(toBit(memory)), node.op, node.value.number)
+		case fieldString:
+			return (value.String() == node.value.text) == (node.op == "==")
+		case fieldBool:
+			return (value.Bool() == node.value.boolean) == (node.op == "==")
+		case fieldEnum:
+			return (value.Enum() == node.value.enum) == (node.op == "==")
+		default:
+			return false
+		}
+	})
+}
+
+// anyValue reports whether match returns true for any value at the end of the path,
+// a repeated field along the path yields one value per element
+func anyValue(message protoreflect.Message, path []protoreflect.FieldDescriptor, match func(protoreflect.Value) bool) bool {
+	field := path[0]
+	value := message.Get(field)
+
+	visit := func(value protoreflect.Value) bool {
+		if len(path) == 1 {
+			return match(value)
+		}
+		return anyValue(value.Message(), path[1:], match)
+	}
+
+	if field.IsList() {
+		list := value.List()
+		for i := 0; i < list.Len(); i++ {
+			if visit(list.Get(i)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return visit(value)
+}
+
+func numberOf(value protoreflect.Value) float64 {
+	switch v := value.Interface().(type) {
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		return 0
+	}
+}
+
+func compareNumbers(a float64, op string, b float64) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	default:
+		return false
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"github.com/treeforest/grpc-pcbook/sample"
+	"strings"
+	"testing"
+)
+
+func TestFilterExprMatch(t *testing.T) {
+	t.Parallel()
+
+	laptop := sample.NewLaptop()
+	laptop.Brand = "Dell"
+	laptop.PriceUsd = 2000
+	laptop.Cpu.NumberCores = 6
+	laptop.Ram = &pb.Memory{Value: 16, Unit: pb.Memory_GIGABYTE}
+	laptop.Gpus = []*pb.GPU{
+		{Brand: "AMD", Name: "RX 580"},
+		{Brand: "NVIDIA", Name: "RTX 2070", Memory: &pb.Memory{Value: 8, Unit: pb.Memory_GIGABYTE}},
+	}
+	laptop.Screen.SizeInch = 15.6
+	laptop.Keyboard = &pb.Keyboard{Layout: pb.Keyboard_QWERTZ, Backlit: true}
+	laptop.Weight = &pb.Laptop_WeightLb{WeightLb: 4.4}
+
+	testCases := []struct {
+		expr     string
+		expected bool
+	}{
+		{`cpu.number_cores >= 6 && ram >= 16GB && (brand == "Dell" || brand == "Apple") && price_usd < 2500`, true},
+		{`cpu.number_cores > 6`, false},
+		{`ram >= 16384MB && ram < 0.5tb`, true},
+		{`ram > 16GB`, false},
+		{`brand != "Dell"`, false},
+		{`!(brand == "Apple")`, true},
+		{`gpus.brand == "NVIDIA" && gpus.memory >= 8GB`, true},
+		{`gpus.name == "RTX 2060"`, false},
+		{`screen.size_inch == 15.6`, true},
+		{`keyboard.layout == QWERTZ && keyboard.backlit`, true},
+		{`keyboard.backlit == false`, false},
+		{`weight < 2kg && weight >= 4.4lb`, true},
+		{`weight > 2`, false},
+		{`brand == "Apple" || price_usd <= 2000 && cpu.number_cores == 6`, true},
+		{`(brand == "Apple" || price_usd <= 2000) && cpu.number_cores == 4`, false},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.expr, func(t *testing.T) {
+			t.Parallel()
+
+			expr, err := ParseFilterExpr(tc.expr)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, expr.Match(laptop))
+		})
+	}
+
+	var expr *FilterExpr
+	require.True(t, expr.Match(laptop))
+}
+
+func TestFilterExprError(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		expr     string
+		position int
+		message  string
+	}{
+		{`price_usd <`, 12, `expected a number, got end of expression`},
+		{`price_usd < 2500 &&`, 20, `expected a field, got end of expression`},
+		{`(brand == "Dell"`, 17, `expected ")" to close "(" at position 1`},
+		{`brand == "Dell")`, 16, `unexpected ")"`},
+		{`color == "red"`, 1, `unknown field color`},
+		{`brand.name == "x"`, 1, `brand has no field name`},
+		{`cpu == 1`, 1, `cannot compare message field cpu`},
+		{`brand < "Dell"`, 7, `operator < cannot compare a string`},
+		{`brand == Dell`, 10, `expected a string, got "Dell"`},
+		{`ram >= 16`, 8, `expected a memory size such as 16GB, got "16"`},
+		{`ram >= 16kg`, 8, `expected a memory size such as 16GB, got "16kg"`},
+		{`weight < 2GB`, 10, `expected a weight such as 1.5kg or 4lb, got "2GB"`},
+		{`price_usd < 25GB`, 13, `expected a number, got "25GB"`},
+		{`keyboard.layout == DVORAK`, 20, `expected one of UNKNOWN, QWERTY, QWERTZ, AZERTY, got "DVORAK"`},
+		{`price_usd`, 10, `expected a comparison operator after price_usd`},
+		{`brand == "Dell`, 10, `unterminated string`},
+		{`price_usd < 2500 & ram > 8GB`, 18, `unexpected character '&'`},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.expr, func(t *testing.T) {
+			t.Parallel()
+
+			expr, err := ParseFilterExpr(tc.expr)
+			require.Nil(t, expr)
+			require.Equal(t, &FilterExprError{Position: tc.position, Message: tc.message}, err)
+		})
+	}
+}
+
+func TestFilterExprLimits(t *testing.T) {
+	t.Parallel()
+
+	// nested within the limits
+	nested := strings.Repeat("(", maxFilterExprDepth-1) + "!price_usd < 2500" + strings.Repeat(")", maxFilterExprDepth-1)
+	_, err := ParseFilterExpr(nested)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		expr     string
+		position int
+		message  string
+	}{
+		{"too_long", strings.Repeat("(", 2000000), maxFilterExprLength + 1, "expression is longer than 4096 bytes"},
+		{"too_long_not", strings.Repeat("!", 3500000), maxFilterExprLength + 1, "expression is longer than 4096 bytes"},
+		{"parentheses", strings.Repeat("(", maxFilterExprLength), maxFilterExprDepth + 1, "expression is nested more than 64 levels deep"},
+		{"not", strings.Repeat("!", maxFilterExprLength), maxFilterExprDepth + 1, "expression is nested more than 64 levels deep"},
+		{"mixed", strings.Repeat("!(", maxFilterExprDepth), maxFilterExprDepth + 1, "expression is nested more than 64 levels deep"},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			expr, err := ParseFilterExpr(tc.expr)
+			require.Nil(t, expr)
+			require.Equal(t, &FilterExprError{Position: tc.position, Message: tc.message}, err)
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"io/ioutil"
+	"math/big"
+)
+
+// SigningKey is an RSA or ECDSA key that signs access tokens, or only verifies them
+// if it has no private key
+type SigningKey struct {
+	// ID is the kid header of the tokens signed with the key, the JWK thumbprint of the public key
+	ID         string
+	method     jwt.SigningMethod
+	privateKey crypto.Signer
+	publicKey  crypto.PublicKey
+}
+
+// NewSigningKey returns a key that signs tokens with RS256 for RSA keys,
+// or with ES256, ES384 or ES512 for ECDSA keys of the P-256, P-384 or P-521 curve
+func NewSigningKey(privateKey crypto.Signer) (*SigningKey, error) {
+	key, err := NewVerificationKey(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	key.privateKey = privateKey
+	return key, nil
+}
+
+// NewVerificationKey returns a key that verifies the tokens signed by the private key of the public key
+func NewVerificationKey(publicKey crypto.PublicKey) (*SigningKey, error) {
+	key := &SigningKey{publicKey: publicKey}
+
+	switch publicKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		key.method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		switch publicKey.Curve {
+		case elliptic.P256():
+			key.method = jwt.SigningMethodES256
+		case elliptic.P384():
+			key.method = jwt.SigningMethodES384
+		case elliptic.P521():
+			key.method = jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("unsupported elliptic curve %s", publicKey.Curve.Params().Name)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", publicKey)
+	}
+
+	thumbprint, err := json.Marshal(key.jwk(true))
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal key: %w", err)
+	}
+
+	hash := sha256.Sum256(thumbprint)
+	key.ID = base64.RawURLEncoding.EncodeToString(hash[:])
+	return key, nil
+}
+
+// LoadSigningKey loads a signing key from a PEM file with a PKCS #1, PKCS #8 or SEC 1 private key
+func LoadSigningKey(filename string) (*SigningKey, error) {
+	block, err := readPEMFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var privateKey interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s doesn't contain a private key but a %s", filename, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse private key of %s: %w", filename, err)
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", privateKey)
+	}
+
+	return NewSigningKey(signer)
+}
+
+// LoadVerificationKey loads a verification key from a PEM file with a public key or a certificate.
+// A file with a private key gives the verification key of its public key.
+func LoadVerificationKey(filename string) (*SigningKey, error) {
+	block, err := readPEMFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var publicKey interface{}
+	switch block.Type {
+	case "PUBLIC KEY":
+		publicKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		publicKey, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			publicKey = cert.PublicKey
+		}
+	default:
+		key, err := LoadSigningKey(filename)
+		if err != nil {
+			return nil, err
+		}
+		key.privateKey = nil
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse public key of %s: %w", filename, err)
+	}
+
+	return NewVerificationKey(publicKey)
+}
+
+func readPEMFile(filename string) (*pem.Block, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read key file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", filename)
+	}
+
+	return block, nil
+}
+
+// jsonWebKey is the JSON web key of a public key as defined by RFC 7517
+type jsonWebKey struct {
+	Crv string `json:"crv,omitempty"`
+	E   string `json:"e,omitempty"`
+	Kty string `json:"kty"`
+	N   string `json:"n,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+
+	// the members below are not part of the thumbprint
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+}
+
+// jwk returns the JSON web key of the public key. The thumbprint only has the required members,
+// which are then marshaled in lexicographic order as RFC 7638 needs.
+func (key *SigningKey) jwk(thumbprint bool) *jsonWebKey {
+	jwk := &jsonWebKey{}
+
+	switch publicKey := key.publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = publicKey.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(padBytes(publicKey.X.Bytes(), size))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(padBytes(publicKey.Y.Bytes(), size))
+	}
+
+	if !thumbprint {
+		jwk.Alg = key.method.Alg()
+		jwk.Kid = key.ID
+		jwk.Use = "sig"
+	}
+
+	return jwk
+}
+
+// padBytes left-pads a big-endian number with zeros up to the size
+func padBytes(data []byte, size int) []byte {
+	if len(data) >= size {
+		return data
+	}
+
+	padded := make([]byte, size)
+	copy(padded[size-len(data):], data)
+	return padded
+}
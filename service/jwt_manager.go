@@ -1,15 +1,23 @@
 package service
 
 import (
+	"encoding/json"
 	"fmt"
-	"time"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"sort"
+	"time"
 )
 
-// JWTManager is a JSON web token manager
+// JWTManager is a JSON web token manager, which signs the tokens either with a secret key shared
+// with the services that verify them, or with the private key of an RSA or ECDSA key pair
 type JWTManager struct {
-	secretKey     string
-	tokenDuration time.Duration
+	// secret key of HS256, empty if the tokens are signed with the signing key
+	secretKey  string
+	signingKey *SigningKey
+	// keys that verify the tokens by key ID, the signing key is one of them
+	verificationKeys map[string]*SigningKey
+	tokenDuration    time.Duration
 }
 
 // UserClaims is a custom JWT claims that contains same user's information
@@ -17,39 +25,102 @@ type UserClaims struct {
 	jwt.StandardClaims
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	// Family is the refresh token family that the token was issued with, if any
+	Family string `json:"fam,omitempty"`
 }
 
-// NewJWTManager returns a new JWT manager
+// NewJWTManager returns a new JWT manager that signs the tokens with the secret key using HS256
 func NewJWTManager(secretKey string, tokenDuration time.Duration) *JWTManager {
-	return &JWTManager{secretKey, tokenDuration}
+	return &JWTManager{secretKey: secretKey, tokenDuration: tokenDuration}
+}
+
+// NewKeyJWTManager returns a new JWT manager that signs the tokens with the signing key and puts its ID
+// in the kid header. The tokens signed with one of the verification keys are still valid,
+// so that the tokens signed with the previous signing key don't break when the keys are rotated.
+func NewKeyJWTManager(
+	signingKey *SigningKey,
+	verificationKeys []*SigningKey,
+	tokenDuration time.Duration,
+) (*JWTManager, error) {
+	if signingKey == nil || signingKey.privateKey == nil {
+		return nil, fmt.Errorf("signing key must have a private key")
+	}
+
+	manager := &JWTManager{
+		signingKey:       signingKey,
+		verificationKeys: map[string]*SigningKey{signingKey.ID: signingKey},
+		tokenDuration:    tokenDuration,
+	}
+
+	for _, key := range verificationKeys {
+		if manager.verificationKeys[key.ID] != nil {
+			return nil, fmt.Errorf("duplicate verification key %s", key.ID)
+		}
+		manager.verificationKeys[key.ID] = key
+	}
+
+	return manager, nil
 }
 
 // Generate generates and signs a new token for a user
 func (manager *JWTManager) Generate(user *User) (string, error) {
+	return manager.GenerateInFamily(user, "")
+}
+
+// GenerateInFamily generates and signs a new token for a user, which is revoked
+// together with the refresh token family
+func (manager *JWTManager) GenerateInFamily(user *User, family string) (string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", fmt.Errorf("cannot generate token ID: %w", err)
+	}
+
 	claims := UserClaims{
 		StandardClaims: jwt.StandardClaims{
+			Id:        id.String(),
 			ExpiresAt: time.Now().Add(manager.tokenDuration).Unix(),
 		},
 		Username: user.Username,
 		Role:     user.Role,
+		Family:   family,
+	}
+
+	if manager.signingKey == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(manager.secretKey))
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(manager.secretKey))
+	token := jwt.NewWithClaims(manager.signingKey.method, claims)
+	token.Header["kid"] = manager.signingKey.ID
+	return token.SignedString(manager.signingKey.privateKey)
 }
 
-// Verify verifies the access token string and return a user claim if the token is valid
+// Verify verifies the access token string and return a user claim if the token is valid.
+// The token must be signed with the exact algorithm of its key, so that a token signed with HS256
+// and a public key as the secret, or not signed at all, is rejected.
 func (manager *JWTManager) Verify(accessToken string) (*UserClaims, error) {
 	token, err := jwt.ParseWithClaims(
 		accessToken,
 		&UserClaims{},
 		func(token *jwt.Token) (interface{}, error) {
-			_, ok := token.Method.(*jwt.SigningMethodHMAC)
-			if !ok {
+			if manager.signingKey == nil {
+				if token.Method != jwt.SigningMethodHS256 {
+					return nil, fmt.Errorf("unexpected token signing method")
+				}
+
+				return []byte(manager.secretKey), nil
+			}
+
+			kid, _ := token.Header["kid"].(string)
+			key := manager.verificationKeys[kid]
+			if key == nil {
+				return nil, fmt.Errorf("unknown signing key %q", kid)
+			}
+			if token.Method != key.method {
 				return nil, fmt.Errorf("unexpected token signing method")
 			}
 
-			return []byte(manager.secretKey), nil
+			return key.publicKey, nil
 		},
 	)
 
@@ -63,4 +134,29 @@ func (manager *JWTManager) Verify(accessToken string) (*UserClaims, error) {
 	}
 
 	return claims, nil
-}
\ No newline at end of file
+}
+
+// JWKS returns the JSON web key set of the verification keys, which is empty if the tokens are signed
+// with a secret key
+func (manager *JWTManager) JWKS() ([]byte, error) {
+	keys := make([]*jsonWebKey, 0, len(manager.verificationKeys))
+	if manager.signingKey != nil {
+		keys = append(keys, manager.signingKey.jwk(false))
+	}
+
+	// the signing key comes first, then the other keys by ID
+	var others []*jsonWebKey
+	for id, key := range manager.verificationKeys {
+		if id != manager.signingKey.ID {
+			others = append(others, key.jwk(false))
+		}
+	}
+	sort.Slice(others, func(i, j int) bool {
+		return others[i].Kid < others[j].Kid
+	})
+	keys = append(keys, others...)
+
+	return json.Marshal(struct {
+		Keys []*jsonWebKey `json:"keys"`
+	}{keys})
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestRSAKey(t *testing.T) *SigningKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	key, err := NewSigningKey(privateKey)
+	require.NoError(t, err)
+	return key
+}
+
+func newTestECKey(t *testing.T) *SigningKey {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	key, err := NewSigningKey(privateKey)
+	require.NoError(t, err)
+	return key
+}
+
+func TestJWTManagerSigningKeys(t *testing.T) {
+	t.Parallel()
+
+	user := &User{Username: "alice", Role: userRole}
+
+	testCases := []struct {
+		name   string
+		key    *SigningKey
+		method jwt.SigningMethod
+	}{
+		{"rsa", newTestRSAKey(t), jwt.SigningMethodRS256},
+		{"ecdsa", newTestECKey(t), jwt.SigningMethodES256},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			manager, err := NewKeyJWTManager(tc.key, nil, time.Minute)
+			require.NoError(t, err)
+
+			accessToken, err := manager.Generate(user)
+			require.NoError(t, err)
+
+			token, _, err := new(jwt.Parser).ParseUnverified(accessToken, &UserClaims{})
+			require.NoError(t, err)
+			require.Equal(t, tc.method, token.Method)
+			require.Equal(t, tc.key.ID, token.Header["kid"])
+
+			claims, err := manager.Verify(accessToken)
+			require.NoError(t, err)
+			require.Equal(t, user.Username, claims.Username)
+			require.Equal(t, user.Role, claims.Role)
+		})
+	}
+}
+
+func TestJWTManagerKeyRotation(t *testing.T) {
+	t.Parallel()
+
+	user := &User{Username: "alice", Role: userRole}
+	oldKey := newTestRSAKey(t)
+	newKey := newTestECKey(t)
+
+	oldManager, err := NewKeyJWTManager(oldKey, nil, time.Minute)
+	require.NoError(t, err)
+	oldToken, err := oldManager.Generate(user)
+	require.NoError(t, err)
+
+	// the old key still verifies the tokens it signed once it only verifies them
+	verificationKey, err := NewVerificationKey(oldKey.publicKey)
+	require.NoError(t, err)
+	require.Equal(t, oldKey.ID, verificationKey.ID)
+
+	manager, err := NewKeyJWTManager(newKey, []*SigningKey{verificationKey}, time.Minute)
+	require.NoError(t, err)
+
+	claims, err := manager.Verify(oldToken)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, claims.Username)
+
+	newToken, err := manager.Generate(user)
+	require.NoError(t, err)
+	_, err = manager.Verify(newToken)
+	require.NoError(t, err)
+
+	// the tokens of a removed key are rejected
+	_, err = oldManager.Verify(newToken)
+	require.Error(t, err)
+	manager, err = NewKeyJWTManager(newKey, nil, time.Minute)
+	require.NoError(t, err)
+	_, err = manager.Verify(oldToken)
+	require.Error(t, err)
+
+	_, err = NewKeyJWTManager(newKey, []*SigningKey{newKey}, time.Minute)
+	require.Error(t, err)
+	_, err = NewKeyJWTManager(verificationKey, nil, time.Minute)
+	require.Error(t, err)
+}
+
+func TestJWTManagerAlgorithmConfusion(t *testing.T) {
+	t.Parallel()
+
+	rsaKey := newTestRSAKey(t)
+	ecKey := newTestECKey(t)
+	manager, err := NewKeyJWTManager(rsaKey, []*SigningKey{ecKey}, time.Minute)
+	require.NoError(t, err)
+
+	publicKey, err := x509.MarshalPKIXPublicKey(rsaKey.publicKey)
+	require.NoError(t, err)
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})
+
+	claims := UserClaims{
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Minute).Unix()},
+		Username:       "mallory",
+		Role:           adminRole,
+	}
+
+	testCases := []struct {
+		name   string
+		method jwt.SigningMethod
+		kid    string
+		key    interface{}
+	}{
+		{"hmac_with_public_key", jwt.SigningMethodHS256, rsaKey.ID, publicKeyPEM},
+		{"none", jwt.SigningMethodNone, rsaKey.ID, jwt.UnsafeAllowNoneSignatureType},
+		{"rsa_with_ec_key_id", jwt.SigningMethodRS256, ecKey.ID, rsaKey.privateKey},
+		{"no_key_id", jwt.SigningMethodRS256, "", rsaKey.privateKey},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			token := jwt.NewWithClaims(tc.method, claims)
+			if tc.kid != "" {
+				token.Header["kid"] = tc.kid
+			}
+			accessToken, err := token.SignedString(tc.key)
+			require.NoError(t, err)
+
+			_, err = manager.Verify(accessToken)
+			require.Error(t, err)
+		})
+	}
+
+	// a secret key manager rejects the tokens signed with another algorithm
+	secretManager := NewJWTManager("secret", time.Minute)
+	accessToken, err := manager.Generate(&User{Username: "alice", Role: userRole})
+	require.NoError(t, err)
+	_, err = secretManager.Verify(accessToken)
+	require.Error(t, err)
+}
+
+func TestJWTManagerJWKS(t *testing.T) {
+	t.Parallel()
+
+	rsaKey := newTestRSAKey(t)
+	ecKey := newTestECKey(t)
+
+	var jwks struct {
+		Keys []*jsonWebKey `json:"keys"`
+	}
+
+	data, err := NewJWTManager("secret", time.Minute).JWKS()
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &jwks))
+	require.Empty(t, jwks.Keys)
+
+	manager, err := NewKeyJWTManager(ecKey, []*SigningKey{rsaKey}, time.Minute)
+	require.NoError(t, err)
+	data, err = manager.JWKS()
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &jwks))
+	require.Len(t, jwks.Keys, 2)
+
+	ec := jwks.Keys[0]
+	require.Equal(t, "EC", ec.Kty)
+	require.Equal(t, "P-256", ec.Crv)
+	require.Equal(t, "ES256", ec.Alg)
+	require.Equal(t, ecKey.ID, ec.Kid)
+	require.Equal(t, "sig", ec.Use)
+	require.Len(t, ec.X, 43)
+	require.Len(t, ec.Y, 43)
+
+	rsa := jwks.Keys[1]
+	require.Equal(t, "RSA", rsa.Kty)
+	require.Equal(t, "RS256", rsa.Alg)
+	require.Equal(t, rsaKey.ID, rsa.Kid)
+	require.Equal(t, "AQAB", rsa.E)
+	require.NotEmpty(t, rsa.N)
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writePEM := func(name string, blockType string, bytes []byte) string {
+		filename := filepath.Join(dir, name)
+		data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: bytes})
+		require.NoError(t, ioutil.WriteFile(filename, data, 0600))
+		return filename
+	}
+
+	rsaKey := newTestRSAKey(t)
+	ecKey := newTestECKey(t)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(ecKey.privateKey)
+	require.NoError(t, err)
+	sec1, err := x509.MarshalECPrivateKey(ecKey.privateKey.(*ecdsa.PrivateKey))
+	require.NoError(t, err)
+	pkix, err := x509.MarshalPKIXPublicKey(ecKey.publicKey)
+	require.NoError(t, err)
+
+	rsaPrivate := writePEM("rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey.privateKey.(*rsa.PrivateKey)))
+	rsaPublic := writePEM("rsa.pub", "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(rsaKey.publicKey.(*rsa.PublicKey)))
+	ecPKCS8 := writePEM("ec-pkcs8.pem", "PRIVATE KEY", pkcs8)
+	ecSEC1 := writePEM("ec.pem", "EC PRIVATE KEY", sec1)
+	ecPublic := writePEM("ec.pub", "PUBLIC KEY", pkix)
+
+	for _, filename := range []string{rsaPrivate, ecPKCS8, ecSEC1} {
+		key, err := LoadSigningKey(filename)
+		require.NoError(t, err)
+		require.NotNil(t, key.privateKey)
+	}
+
+	key, err := LoadSigningKey(rsaPrivate)
+	require.NoError(t, err)
+	require.Equal(t, rsaKey.ID, key.ID)
+	key, err = LoadSigningKey(ecSEC1)
+	require.NoError(t, err)
+	require.Equal(t, ecKey.ID, key.ID)
+
+	_, err = LoadSigningKey(ecPublic)
+	require.Error(t, err)
+	_, err = LoadSigningKey(filepath.Join(dir, "unknown.pem"))
+	require.Error(t, err)
+
+	testCases := []struct {
+		filename string
+		id       string
+	}{
+		{rsaPrivate, rsaKey.ID},
+		{rsaPublic, rsaKey.ID},
+		{ecPKCS8, ecKey.ID},
+		{ecPublic, ecKey.ID},
+	}
+
+	for _, tc := range testCases {
+		key, err := LoadVerificationKey(tc.filename)
+		require.NoError(t, err)
+		require.Equal(t, tc.id, key.ID)
+		require.Nil(t, key.privateKey)
+	}
+}
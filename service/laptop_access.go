@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sort"
+)
+
+// laptopAccess is what a caller does to a laptop
+type laptopAccess int
+
+const (
+	// update the laptop or upload its images, which its editors can do as well
+	editLaptop laptopAccess = iota
+	// delete the laptop or share its edit rights, which only its owner can do
+	manageLaptop
+)
+
+// checkLaptopAccess returns a PermissionDenied error with the reason unless the caller is an admin,
+// the owner of the laptop, or one of its editors if the access only edits it.
+// A laptop without owner can only be changed by the admins. Errors are gRPC status errors.
+func checkLaptopAccess(ctx context.Context, laptop *pb.Laptop, access laptopAccess) error {
+	claims, ok := UserClaimsFromContext(ctx)
+	if !ok || len(claims.Username) == 0 {
+		return status.Error(codes.Unauthenticated, "user is not authenticated")
+	}
+
+	username := claims.Username
+	if claims.Role == adminRole || username == laptop.GetOwner() {
+		return nil
+	}
+
+	if len(laptop.GetOwner()) == 0 {
+		return status.Errorf(codes.PermissionDenied, "laptop %s has no owner, only admins can change it", laptop.GetId())
+	}
+
+	if access == manageLaptop {
+		return status.Errorf(codes.PermissionDenied, "laptop %s is owned by %s, not %s", laptop.GetId(), laptop.GetOwner(), username)
+	}
+
+	for _, editor := range laptop.GetEditors() {
+		if editor == username {
+			return nil
+		}
+	}
+
+	return status.Errorf(codes.PermissionDenied, "%s is neither the owner nor an editor of laptop %s", username, laptop.GetId())
+}
+
+// sharedEditors returns the editors of the laptop with the added ones and without the removed ones, sorted
+func sharedEditors(laptop *pb.Laptop, added []string, removed []string) ([]string, error) {
+	editors := make(map[string]bool, len(laptop.GetEditors())+len(added))
+	for _, editor := range laptop.GetEditors() {
+		editors[editor] = true
+	}
+
+	for _, editor := range added {
+		if len(editor) == 0 {
+			return nil, fmt.Errorf("editor username cannot be empty")
+		}
+		if editor == laptop.GetOwner() {
+			return nil, fmt.Errorf("%s already owns laptop %s", editor, laptop.GetId())
+		}
+		editors[editor] = true
+	}
+
+	for _, editor := range removed {
+		delete(editors, editor)
+	}
+
+	result := make([]string, 0, len(editors))
+	for editor := range editors {
+		result = append(result, editor)
+	}
+	sort.Strings(result)
+
+	return result, nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"sync"
+)
+
+// number of changes kept for watchers that reconnect
+const defaultChangeLogSize = 10000
+
+// ErrUnknownSequence is returned when the changes after a sequence number are no longer in the change log,
+// or when the sequence number is ahead of it
+var ErrUnknownSequence = errors.New("sequence number is not in the change log")
+
+// LaptopChange is a change of a laptop in a store
+type LaptopChange struct {
+	Event *pb.LaptopEvent
+	// Previous is the laptop before an update, it is nil for other events
+	Previous *pb.Laptop
+}
+
+// laptopChangeLog keeps the latest changes of a store in memory
+type laptopChangeLog struct {
+	mutex   sync.Mutex
+	size    int
+	changes []*LaptopChange
+	last    uint64
+	// changed is closed and replaced by a new channel on every change
+	changed chan struct{}
+}
+
+// newLaptopChangeLog returns an empty change log whose next change follows the last sequence number,
+// a store that persists its changes passes the last one it replayed so that the sequence numbers given
+// to watchers before a restart are not reused for other changes
+func newLaptopChangeLog(size int, last uint64) *laptopChangeLog {
+	return &laptopChangeLog{
+		size:    size,
+		last:    last,
+		changed: make(chan struct{}),
+	}
+}
+
+// lastSequence returns the sequence number of the last change
+func (changeLog *laptopChangeLog) lastSequence() uint64 {
+	changeLog.mutex.Lock()
+	defer changeLog.mutex.Unlock()
+
+	return changeLog.last
+}
+
+// append records a change, the laptops must not be modified afterwards
+func (changeLog *laptopChangeLog) append(eventType pb.LaptopEvent_Type, laptop *pb.Laptop, previous *pb.Laptop) {
+	changeLog.mutex.Lock()
+	defer changeLog.mutex.Unlock()
+
+	changeLog.last++
+	change := &LaptopChange{
+		Event: &pb.LaptopEvent{
+			Sequence: changeLog.last,
+			Type:     eventType,
+			Laptop:   laptop,
+			Time:     ptypes.TimestampNow(),
+		},
+		Previous: previous,
+	}
+
+	changeLog.changes = append(changeLog.changes, change)
+	if len(changeLog.changes) > changeLog.size {
+		changeLog.changes = changeLog.changes[1:]
+	}
+
+	close(changeLog.changed)
+	changeLog.changed = make(chan struct{})
+}
+
+// since returns the changes after the sequence number and a channel that is closed on the next change
+func (changeLog *laptopChangeLog) since(after uint64) ([]*LaptopChange, <-chan struct{}, error) {
+	changeLog.mutex.Lock()
+	defer changeLog.mutex.Unlock()
+
+	if after > changeLog.last {
+		return nil, nil, ErrUnknownSequence
+	}
+
+	missing := int(changeLog.last - after)
+	if missing > len(changeLog.changes) {
+		return nil, nil, ErrUnknownSequence
+	}
+
+	changes := changeLog.changes[len(changeLog.changes)-missing:]
+	return changes, changeLog.changed, nil
+}
+
+// watch calls fn with a copy of each change after the sequence number until the context is done,
+// a sequence number of zero only watches the changes to come
+func (changeLog *laptopChangeLog) watch(
+	ctx context.Context,
+	after uint64,
+	fn func(change *LaptopChange) error,
+) error {
+	if after == 0 {
+		after = changeLog.lastSequence()
+	}
+
+	for {
+		changes, changed, err := changeLog.since(after)
+		if err != nil {
+			return err
+		}
+
+		for _, change := range changes {
+			other, err := copyLaptopChange(change)
+			if err != nil {
+				return err
+			}
+
+			err = fn(other)
+			if err != nil {
+				return err
+			}
+			after = change.Event.Sequence
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func copyLaptopChange(change *LaptopChange) (*LaptopChange, error) {
+	event, ok := proto.Clone(change.Event).(*pb.LaptopEvent)
+	if !ok {
+		return nil, fmt.Errorf("cannot copy laptop event")
+	}
+
+	other := &LaptopChange{Event: event}
+	if change.Previous != nil {
+		previous, err := deepCopy(change.Previous)
+		if err != nil {
+			return nil, err
+		}
+		other.Previous = previous
+	}
+
+	return other, nil
+}
+
+// isWatched reports whether a watcher with the filter and the filter expression is interested in the laptop,
+// unlike a search a missing filter matches every laptop
+func isWatched(filter *pb.Filter, expr *FilterExpr, laptop *pb.Laptop) bool {
+	return laptop != nil && (filter == nil || isQualified(filter, laptop)) && expr.Match(laptop)
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"github.com/treeforest/grpc-pcbook/sample"
+	"testing"
+)
+
+func TestLaptopChangeLogSince(t *testing.T) {
+	t.Parallel()
+
+	changeLog := newLaptopChangeLog(3, 0)
+	for i := 0; i < 5; i++ {
+		changeLog.append(pb.LaptopEvent_CREATED, sample.NewLaptop(), nil)
+	}
+
+	changes, changed, err := changeLog.since(3)
+	require.NoError(t, err)
+	require.NotNil(t, changed)
+	require.Len(t, changes, 2)
+	require.Equal(t, uint64(4), changes[0].Event.GetSequence())
+	require.Equal(t, uint64(5), changes[1].Event.GetSequence())
+
+	changes, _, err = changeLog.since(2)
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+
+	changes, _, err = changeLog.since(5)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	_, _, err = changeLog.since(1)
+	require.Equal(t, ErrUnknownSequence, err)
+
+	_, _, err = changeLog.since(6)
+	require.Equal(t, ErrUnknownSequence, err)
+}
+
+func TestLaptopChangeLogWatch(t *testing.T) {
+	t.Parallel()
+
+	changeLog := newLaptopChangeLog(100, 0)
+	changeLog.append(pb.LaptopEvent_CREATED, sample.NewLaptop(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan *LaptopChange)
+	done := make(chan error)
+	go func() {
+		done <- changeLog.watch(ctx, 1, func(change *LaptopChange) error {
+			received <- change
+			return nil
+		})
+	}()
+
+	laptop := sample.NewLaptop()
+	changeLog.append(pb.LaptopEvent_DELETED, laptop, nil)
+
+	change := <-received
+	require.Equal(t, uint64(2), change.Event.GetSequence())
+	require.Equal(t, pb.LaptopEvent_DELETED, change.Event.GetType())
+	require.Equal(t, laptop.Id, change.Event.GetLaptop().GetId())
+
+	// the watcher gets a copy of the change
+	change.Event.Laptop.Id = "changed"
+	changes, _, err := changeLog.since(1)
+	require.NoError(t, err)
+	require.Equal(t, laptop.Id, changes[0].Event.GetLaptop().GetId())
+
+	cancel()
+	require.Equal(t, context.Canceled, <-done)
+}
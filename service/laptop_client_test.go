@@ -9,11 +9,16 @@ import (
 	"github.com/treeforest/grpc-pcbook/sample"
 	"github.com/treeforest/grpc-pcbook/serializer"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"io"
+	"math"
 	"net"
 	"os"
 	"path/filepath"
+	"sort"
 	"testing"
+	"time"
 )
 
 func TestClientCreateLaptop(t *testing.T) {
@@ -29,7 +34,11 @@ func TestClientCreateLaptop(t *testing.T) {
 		Laptop: laptop,
 	}
 
-	res, err := laptopClient.CreateLaptop(context.Background(), req)
+	_, err := laptopClient.CreateLaptop(newTestRoleContext(t, testJWTManager, "user1", userRole), req)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	seller := newTestRoleContext(t, testJWTManager, "seller1", sellerRole)
+	res, err := laptopClient.CreateLaptop(seller, req)
 	require.NoError(t, err)
 	require.NotNil(t, res)
 	require.Equal(t, expectedID, res.Id)
@@ -41,6 +50,7 @@ func TestClientCreateLaptop(t *testing.T) {
 
 	// check that the saved laptop is the same as the one we send
 	requireSameLaptop(t, laptop, other)
+	require.Equal(t, "seller1", other.GetOwner())
 }
 
 func TestClientSearchLaptop(t *testing.T) {
@@ -111,6 +121,44 @@ func TestClientSearchLaptop(t *testing.T) {
 	require.Equal(t, len(expectedIDs), found)
 }
 
+func TestClientSearchLaptopPage(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	for i := 0; i < 5; i++ {
+		laptop := sample.NewLaptop()
+		laptop.ReleaseYear = uint32(2015 + i)
+		require.NoError(t, laptopStore.Save(laptop))
+	}
+
+	serverAddress := startTestLaptopServer(t, laptopStore, nil, nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	req := &pb.SearchLaptopRequest{
+		Filter:   &pb.Filter{MaxPriceUsd: 5000},
+		OrderBy:  "release_year desc",
+		PageSize: 3,
+	}
+	stream, err := laptopClient.SearchLaptop(context.Background(), req)
+	require.NoError(t, err)
+
+	var years []uint32
+	nextPageToken := ""
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+		years = append(years, res.GetLaptop().GetReleaseYear())
+		nextPageToken = res.GetNextPageToken()
+	}
+
+	require.Equal(t, []uint32{2019, 2018, 2017}, years)
+	require.NotEmpty(t, nextPageToken)
+}
+
 func TestClientUploadImage(t *testing.T) {
 	t.Parallel()
 
@@ -120,22 +168,15 @@ func TestClientUploadImage(t *testing.T) {
 	imageStore := NewDiskImageStore(testImageFolder)
 
 	laptop := sample.NewLaptop()
+	laptop.Owner = "seller1"
 	err := laptopStore.Save(laptop)
 	require.NoError(t, err)
 
 	serverAddress := startTestLaptopServer(t, laptopStore, imageStore, nil)
 	laptopClient := newTestLaptopClient(t, serverAddress)
 
-	imagePath := filepath.Join(testImageFolder, "laptop.jpg")
-	file, err := os.Open(imagePath)
-	require.NoError(t, err)
-	defer file.Close()
-
-	stream, err := laptopClient.UploadImage(context.Background())
-	require.NoError(t, err)
-
-	imageType := filepath.Ext(imagePath)
-	req := &pb.UploadImageRequest{
+	imageType := filepath.Ext("laptop.jpg")
+	info := &pb.UploadImageRequest{
 		Data: &pb.UploadImageRequest_Info{
 			Info: &pb.ImageInfo{
 				LaptopId:  laptop.GetId(),
@@ -144,7 +185,22 @@ func TestClientUploadImage(t *testing.T) {
 		},
 	}
 
-	err = stream.Send(req)
+	// only the owner of the laptop can upload its images
+	stream, err := laptopClient.UploadImage(newTestRoleContext(t, testJWTManager, "seller2", sellerRole))
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(info))
+	_, err = stream.CloseAndRecv()
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	imagePath := filepath.Join(testImageFolder, "laptop.jpg")
+	file, err := os.Open(imagePath)
+	require.NoError(t, err)
+	defer file.Close()
+
+	stream, err = laptopClient.UploadImage(newTestRoleContext(t, testJWTManager, "seller1", sellerRole))
+	require.NoError(t, err)
+
+	err = stream.Send(info)
 	require.NoError(t, err)
 
 	reader := bufio.NewReader(file)
@@ -193,44 +249,98 @@ func TestClientRateLaptop(t *testing.T) {
 	serverAddress := startTestLaptopServer(t, laptopStore, nil, ratingStore)
 	laptopClient := newTestLaptopClient(t, serverAddress)
 
-	stream, err := laptopClient.RateLaptop(context.Background())
-	require.NoError(t, err)
+	rate := func(ctx context.Context, scores ...float64) []*pb.RateLaptopResponse {
+		stream, err := laptopClient.RateLaptop(ctx)
+		require.NoError(t, err)
 
-	scores := []float64{8, 7.5, 10}
-	averages := []float64{8, 7.75, 8.5}
+		for _, score := range scores {
+			req := &pb.RateLaptopRequest{
+				LaptopId: laptop.GetId(),
+				Score:    score,
+			}
 
-	n := len(scores)
-	for i := 0; i < n; i++ {
-		req := &pb.RateLaptopRequest{
-			LaptopId: laptop.GetId(),
-			Score:    scores[i],
+			err = stream.Send(req)
+			require.NoError(t, err)
 		}
 
-		err = stream.Send(req)
+		err = stream.CloseSend()
 		require.NoError(t, err)
+
+		var responses []*pb.RateLaptopResponse
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				return responses
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, laptop.GetId(), res.GetLaptopId())
+			responses = append(responses, res)
+		}
 	}
 
-	err = stream.CloseSend()
+	alice := newTestUserContext(t, testJWTManager, "alice")
+	bob := newTestUserContext(t, testJWTManager, "bob")
+
+	// rating a laptop again replaces the score of the user
+	responses := rate(alice, 8, 6)
+	require.Len(t, responses, 2)
+	require.Equal(t, uint32(1), responses[1].GetRatedCount())
+	require.Equal(t, 6.0, responses[1].GetAverageScore())
+
+	responses = rate(bob, 10)
+	require.Len(t, responses, 1)
+	require.Equal(t, uint32(2), responses[0].GetRatedCount())
+	require.Equal(t, 8.0, responses[0].GetAverageScore())
+
+	stream, err := laptopClient.RateLaptop(bob)
 	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pb.RateLaptopRequest{LaptopId: laptop.GetId(), Score: 11}))
+	_, err = stream.Recv()
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
 
-	for idx := 0; ; idx++ {
-		res, err := stream.Recv()
-		if err == io.EOF {
-			require.Equal(t, n, idx)
-			return
-		}
+	stream, err = laptopClient.RateLaptop(context.Background())
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
 
-		require.NoError(t, err)
-		require.Equal(t, laptop.GetId(), res.GetLaptopId())
-		require.Equal(t, uint32(idx + 1), res.GetRatedCount())
-		require.Equal(t, averages[idx], res.GetAverageScore())
-	}
+	res, err := laptopClient.GetLaptopRating(alice, &pb.GetLaptopRatingRequest{LaptopId: laptop.GetId()})
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), res.GetRatedCount())
+	require.Equal(t, 8.0, res.GetAverageScore())
+	require.Equal(t, []uint32{0, 0, 0, 0, 0, 1, 0, 0, 0, 1}, res.GetHistogram())
+	require.Equal(t, 6.0, res.GetMyScore())
+
+	res, err = laptopClient.GetLaptopRating(context.Background(), &pb.GetLaptopRatingRequest{LaptopId: laptop.GetId()})
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), res.GetRatedCount())
+	require.Zero(t, res.GetMyScore())
+
+	_, err = laptopClient.GetLaptopRating(alice, &pb.GetLaptopRatingRequest{LaptopId: sample.NewLaptop().GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err))
 }
 
+// testJWTManager signs the access tokens of the test laptop servers
+var testJWTManager = NewJWTManager("secret", time.Minute)
+
 func startTestLaptopServer(t *testing.T, laptopStore LaptopStore, imageStore ImageStore, ratingStore RatingStore) string {
-	laptopServer := NewLaptopServer(laptopStore, imageStore, ratingStore)
+	return startTestLaptopServerWith(t, NewLaptopServer(laptopStore, imageStore, ratingStore))
+}
 
-	grpcServer := grpc.NewServer()
+// startTestLaptopServerWith starts a gRPC server that serves the laptop server
+func startTestLaptopServerWith(t *testing.T, laptopServer *LaptopServer) string {
+	interceptor := NewAuthInterceptor(testJWTManager, nil, nil, nil, NewAuthPolicy(map[string][]string{
+		// the other methods are public
+		"/techschool.pcbook.LaptopService/*":            nil,
+		"/techschool.pcbook.LaptopService/RateLaptop":   {"admin", "user"},
+		"/techschool.pcbook.LaptopService/CreateLaptop": {"admin", "seller"},
+		"/techschool.pcbook.LaptopService/UploadImage":  {"admin", "seller", "user"},
+	}))
+
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(interceptor.Unary()),
+		grpc.StreamInterceptor(interceptor.Stream()),
+	)
 	pb.RegisterLaptopServiceServer(grpcServer, laptopServer)
 
 	listener, err := net.Listen("tcp", ":0") // random available port
@@ -255,4 +365,207 @@ func requireSameLaptop(t *testing.T, laptop1, laptop2 *pb.Laptop) {
 	require.NoError(t, err)
 
 	require.Equal(t, json1, json2)
-}
\ No newline at end of file
+}
+
+func TestClientTopRatedLaptops(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	ratingStore := NewInMemoryRatingStore()
+	serverAddress := startTestLaptopServer(t, laptopStore, nil, ratingStore)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	var laptops []*pb.Laptop
+	for i := 0; i < 5; i++ {
+		laptop := sample.NewLaptop()
+		laptop.PriceUsd = float64(1000 + i*1000)
+		require.NoError(t, laptopStore.Save(laptop))
+		laptops = append(laptops, laptop)
+	}
+
+	// the last laptop is not rated
+	scores := [][]float64{{6, 8}, {10}, {9, 9, 9}, {9}}
+	for i, laptopScores := range scores {
+		for j, score := range laptopScores {
+			_, err := ratingStore.Rate(fmt.Sprintf("user%d", j), laptops[i].Id, score)
+			require.NoError(t, err)
+		}
+	}
+
+	stream, err := laptopClient.TopRatedLaptops(context.Background(), &pb.TopRatedLaptopsRequest{})
+	require.NoError(t, err)
+
+	expected := []int{1, 2, 3, 0}
+	for i, index := range expected {
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, uint32(i+1), res.GetRank())
+		require.Equal(t, laptops[index].Id, res.GetLaptop().GetId())
+		require.Equal(t, uint32(len(scores[index])), res.GetRatedCount())
+		require.Equal(t, res.GetAverageScore(), res.GetScore())
+	}
+	_, err = stream.Recv()
+	require.Equal(t, io.EOF, err)
+
+	req := &pb.TopRatedLaptopsRequest{Filter: &pb.Filter{MaxPriceUsd: 3500}, Limit: 2}
+	stream, err = laptopClient.TopRatedLaptops(context.Background(), req)
+	require.NoError(t, err)
+
+	for _, index := range []int{1, 2} {
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, laptops[index].Id, res.GetLaptop().GetId())
+	}
+	_, err = stream.Recv()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestClientTopRatedLaptopsLimit(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	ratingStore := NewInMemoryRatingStore()
+	serverAddress := startTestLaptopServer(t, laptopStore, nil, ratingStore)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	var rated []*pb.TopRatedLaptopsResponse
+	for i := 0; i < 50; i++ {
+		laptop := sample.NewLaptop()
+		require.NoError(t, laptopStore.Save(laptop))
+
+		// the ties are broken by the number of scores, then by ID
+		score := float64(i%7 + 1)
+		count := i%3 + 1
+		for j := 0; j < count; j++ {
+			_, err := ratingStore.Rate(fmt.Sprintf("user%d", j), laptop.Id, score)
+			require.NoError(t, err)
+		}
+		rated = append(rated, &pb.TopRatedLaptopsResponse{Laptop: laptop, Score: score, RatedCount: uint32(count)})
+	}
+	sort.Slice(rated, func(i, j int) bool {
+		return isHigherRated(rated[i], rated[j])
+	})
+
+	stream, err := laptopClient.TopRatedLaptops(context.Background(), &pb.TopRatedLaptopsRequest{Limit: 5})
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, uint32(i+1), res.GetRank())
+		require.Equal(t, rated[i].GetLaptop().GetId(), res.GetLaptop().GetId())
+	}
+	_, err = stream.Recv()
+	require.Equal(t, io.EOF, err)
+
+	// without a rating store no laptop is rated
+	serverAddress = startTestLaptopServer(t, laptopStore, nil, nil)
+	laptopClient = newTestLaptopClient(t, serverAddress)
+
+	stream, err = laptopClient.TopRatedLaptops(context.Background(), &pb.TopRatedLaptopsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestClientTopRatedLaptopsBayesian(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	ratingStore := NewInMemoryRatingStore()
+
+	single := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(single))
+	_, err := ratingStore.Rate("user1", single.Id, 10)
+	require.NoError(t, err)
+
+	many := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(many))
+	for i := 0; i < 20; i++ {
+		_, err := ratingStore.Rate(fmt.Sprintf("user%d", i), many.Id, 9)
+		require.NoError(t, err)
+	}
+
+	server := NewLaptopServer(laptopStore, nil, ratingStore)
+	require.Error(t, server.SetRatingScorer(RatingScorer{PriorMean: 5, PriorWeight: -1}))
+	require.NoError(t, server.SetRatingScorer(RatingScorer{PriorMean: 5, PriorWeight: 10}))
+
+	serverAddress := startTestLaptopServerWith(t, server)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	stream, err := laptopClient.TopRatedLaptops(context.Background(), &pb.TopRatedLaptopsRequest{})
+	require.NoError(t, err)
+
+	res, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, many.Id, res.GetLaptop().GetId())
+	require.InDelta(t, 230.0/30, res.GetScore(), 1e-9)
+	require.Equal(t, 9.0, res.GetAverageScore())
+
+	res, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, single.Id, res.GetLaptop().GetId())
+	require.InDelta(t, 60.0/11, res.GetScore(), 1e-9)
+
+	// the searches ordered by rating use the same score
+	page, err := server.ListLaptops(context.Background(), &pb.ListLaptopsRequest{
+		Filter:   &pb.Filter{MaxPriceUsd: math.Inf(1)},
+		OrderBy:  "rating desc",
+		PageSize: 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, many.Id, page.GetLaptops()[0].GetId())
+}
+
+func TestClientWatchLaptops(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	serverAddress := startTestLaptopServer(t, laptopStore, nil, nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	newLaptop := func(price float64) *pb.Laptop {
+		laptop := sample.NewLaptop()
+		laptop.PriceUsd = price
+		require.NoError(t, laptopStore.Save(laptop))
+		return laptop
+	}
+
+	cheap := newLaptop(1000)
+	newLaptop(5000)
+	cheap.PriceUsd = 1200
+	require.NoError(t, laptopStore.Update(cheap))
+	require.NoError(t, laptopStore.Delete(cheap.Id, 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// resuming after the first event gets every missed event that matches the filter
+	resumed, err := laptopClient.WatchLaptops(ctx, &pb.WatchLaptopsRequest{
+		Filter:        &pb.Filter{MaxPriceUsd: 2000},
+		AfterSequence: 1,
+	})
+	require.NoError(t, err)
+
+	for _, expected := range []pb.LaptopEvent_Type{pb.LaptopEvent_UPDATED, pb.LaptopEvent_DELETED} {
+		res, err := resumed.Recv()
+		require.NoError(t, err)
+		require.Equal(t, expected, res.GetEvent().GetType())
+		require.Equal(t, cheap.Id, res.GetEvent().GetLaptop().GetId())
+	}
+
+	// a change that doesn't match the filter is skipped
+	newLaptop(6000)
+	other := newLaptop(1500)
+
+	res, err := resumed.Recv()
+	require.NoError(t, err)
+	require.Equal(t, pb.LaptopEvent_CREATED, res.GetEvent().GetType())
+	require.Equal(t, other.Id, res.GetEvent().GetLaptop().GetId())
+	require.Equal(t, uint64(6), res.GetEvent().GetSequence())
+
+	outOfRange, err := laptopClient.WatchLaptops(ctx, &pb.WatchLaptopsRequest{AfterSequence: 100})
+	require.NoError(t, err)
+	_, err = outOfRange.Recv()
+	require.Equal(t, codes.OutOfRange, status.Code(err))
+}
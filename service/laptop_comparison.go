@@ -0,0 +1,266 @@
+package service
+
+import (
+	"fmt"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"math"
+	"strconv"
+)
+
+// number of laptops that can be compared at once
+const (
+	minComparedLaptops = 2
+	maxComparedLaptops = 10
+)
+
+// bits per gigabyte
+const bitsPerGB = 8 << 30
+
+// comparedLaptop is a laptop with the information that is compared besides its specs
+type comparedLaptop struct {
+	laptop *pb.Laptop
+	// rating is nil if the laptop has not been rated
+	rating *Rating
+}
+
+// laptopSpec is a spec of a laptop that is compared
+type laptopSpec struct {
+	name   string
+	unit   string
+	better pb.SpecComparison_Better
+	// value returns the normalized value of the spec and false if it is unknown,
+	// it is nil if the spec is not a number
+	value func(compared *comparedLaptop) (float64, bool)
+	// display returns the value as displayed, it is nil if the spec is displayed as its value
+	display func(compared *comparedLaptop) string
+}
+
+var laptopSpecs = []*laptopSpec{
+	{
+		name: "brand",
+		display: func(compared *comparedLaptop) string {
+			return compared.laptop.GetBrand()
+		},
+	},
+	{
+		name: "name",
+		display: func(compared *comparedLaptop) string {
+			return compared.laptop.GetName()
+		},
+	},
+	{
+		name: "cpu",
+		display: func(compared *comparedLaptop) string {
+			return fmt.Sprintf("%s %s", compared.laptop.GetCpu().GetBrand(), compared.laptop.GetCpu().GetName())
+		},
+	},
+	{
+		name:   "cpu.number_cores",
+		better: pb.SpecComparison_HIGHER,
+		value: func(compared *comparedLaptop) (float64, bool) {
+			return float64(compared.laptop.GetCpu().GetNumberCores()), compared.laptop.GetCpu() != nil
+		},
+	},
+	{
+		name:   "cpu.number_threads",
+		better: pb.SpecComparison_HIGHER,
+		value: func(compared *comparedLaptop) (float64, bool) {
+			return float64(compared.laptop.GetCpu().GetNumberThreads()), compared.laptop.GetCpu() != nil
+		},
+	},
+	{
+		name:   "cpu.min_ghz",
+		unit:   "GHz",
+		better: pb.SpecComparison_HIGHER,
+		value: func(compared *comparedLaptop) (float64, bool) {
+			return compared.laptop.GetCpu().GetMinGhz(), compared.laptop.GetCpu() != nil
+		},
+	},
+	{
+		name:   "cpu.max_ghz",
+		unit:   "GHz",
+		better: pb.SpecComparison_HIGHER,
+		value: func(compared *comparedLaptop) (float64, bool) {
+			return compared.laptop.GetCpu().GetMaxGhz(), compared.laptop.GetCpu() != nil
+		},
+	},
+	{
+		name:   "ram",
+		unit:   "GB",
+		better: pb.SpecComparison_HIGHER,
+		value: func(compared *comparedLaptop) (float64, bool) {
+			return float64(toBit(compared.laptop.GetRam())) / bitsPerGB, compared.laptop.GetRam() != nil
+		},
+	},
+	{
+		name:   "storages.memory",
+		unit:   "GB",
+		better: pb.SpecComparison_HIGHER,
+		value: func(compared *comparedLaptop) (float64, bool) {
+			var bits uint64
+			for _, storage := range compared.laptop.GetStorages() {
+				bits += toBit(storage.GetMemory())
+			}
+			return float64(bits) / bitsPerGB, len(compared.laptop.GetStorages()) > 0
+		},
+	},
+	{
+		name:   "gpus.memory",
+		unit:   "GB",
+		better: pb.SpecComparison_HIGHER,
+		value: func(compared *comparedLaptop) (float64, bool) {
+			var bits uint64
+			for _, gpu := range compared.laptop.GetGpus() {
+				if toBit(gpu.GetMemory()) > bits {
+					bits = toBit(gpu.GetMemory())
+				}
+			}
+			return float64(bits) / bitsPerGB, len(compared.laptop.GetGpus()) > 0
+		},
+	},
+	{
+		name: "screen.size_inch",
+		unit: "inch",
+		value: func(compared *comparedLaptop) (float64, bool) {
+			return float64(compared.laptop.GetScreen().GetSizeInch()), compared.laptop.GetScreen() != nil
+		},
+	},
+	{
+		name:   "screen.resolution",
+		unit:   "pixels",
+		better: pb.SpecComparison_HIGHER,
+		value: func(compared *comparedLaptop) (float64, bool) {
+			resolution := compared.laptop.GetScreen().GetResolution()
+			return float64(resolution.GetWidth()) * float64(resolution.GetHeight()), resolution != nil
+		},
+		display: func(compared *comparedLaptop) string {
+			resolution := compared.laptop.GetScreen().GetResolution()
+			if resolution == nil {
+				return ""
+			}
+			return fmt.Sprintf("%dx%d", resolution.GetWidth(), resolution.GetHeight())
+		},
+	},
+	{
+		name:   "weight",
+		unit:   "kg",
+		better: pb.SpecComparison_LOWER,
+		value: func(compared *comparedLaptop) (float64, bool) {
+			return weightKg(compared.laptop)
+		},
+	},
+	{
+		name:   "price_usd",
+		unit:   "USD",
+		better: pb.SpecComparison_LOWER,
+		value: func(compared *comparedLaptop) (float64, bool) {
+			return compared.laptop.GetPriceUsd(), true
+		},
+	},
+	{
+		name:   "release_year",
+		better: pb.SpecComparison_HIGHER,
+		value: func(compared *comparedLaptop) (float64, bool) {
+			return float64(compared.laptop.GetReleaseYear()), compared.laptop.GetReleaseYear() > 0
+		},
+	},
+	{
+		name:   "rating",
+		better: pb.SpecComparison_HIGHER,
+		value: func(compared *comparedLaptop) (float64, bool) {
+			return compared.rating.Average(), compared.rating != nil && compared.rating.Count > 0
+		},
+	},
+}
+
+// compareLaptops returns the spec by spec comparison of the laptops
+func compareLaptops(laptops []*comparedLaptop) *pb.LaptopComparison {
+	comparison := &pb.LaptopComparison{
+		LaptopIds: make([]string, len(laptops)),
+		Specs:     make([]*pb.SpecComparison, len(laptopSpecs)),
+	}
+
+	for i, compared := range laptops {
+		comparison.LaptopIds[i] = compared.laptop.GetId()
+	}
+
+	for i, spec := range laptopSpecs {
+		comparison.Specs[i] = spec.compare(laptops)
+	}
+
+	return comparison
+}
+
+// compare returns the comparison of the laptops on the spec
+func (spec *laptopSpec) compare(laptops []*comparedLaptop) *pb.SpecComparison {
+	comparison := &pb.SpecComparison{
+		Spec:          spec.name,
+		Unit:          spec.unit,
+		Better:        spec.better,
+		DisplayValues: make([]string, len(laptops)),
+	}
+
+	if spec.value != nil {
+		comparison.Values = make([]float64, len(laptops))
+	}
+
+	for i, compared := range laptops {
+		value, known := math.NaN(), false
+		if spec.value != nil {
+			value, known = spec.value(compared)
+			if !known {
+				value = math.NaN()
+			}
+			comparison.Values[i] = value
+		}
+
+		switch {
+		case spec.display != nil:
+			comparison.DisplayValues[i] = spec.display(compared)
+		case known:
+			comparison.DisplayValues[i] = formatSpecValue(value)
+		}
+	}
+
+	comparison.WinnerIds = spec.winners(laptops, comparison.Values)
+	return comparison
+}
+
+// winners returns the IDs of the laptops with the best value,
+// or nil if the spec has no winner or every laptop has the best value
+func (spec *laptopSpec) winners(laptops []*comparedLaptop, values []float64) []string {
+	if spec.better == pb.SpecComparison_NONE {
+		return nil
+	}
+
+	best := math.NaN()
+	for _, value := range values {
+		if math.IsNaN(value) {
+			continue
+		}
+
+		if math.IsNaN(best) ||
+			spec.better == pb.SpecComparison_HIGHER && value > best ||
+			spec.better == pb.SpecComparison_LOWER && value < best {
+			best = value
+		}
+	}
+
+	var winnerIDs []string
+	for i, value := range values {
+		if value == best {
+			winnerIDs = append(winnerIDs, laptops[i].laptop.GetId())
+		}
+	}
+
+	if len(winnerIDs) == len(laptops) {
+		return nil
+	}
+
+	return winnerIDs
+}
+
+// formatSpecValue formats a spec value with at most 2 decimals
+func formatSpecValue(value float64) string {
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"fmt"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// facetCounter counts the laptops per value of a facet
+type facetCounter struct {
+	counts map[string]uint32
+	// keys orders the values of numeric facets, it is nil for facets ordered by count
+	keys map[string]float64
+}
+
+func newFacetCounter(numeric bool) *facetCounter {
+	counter := &facetCounter{counts: make(map[string]uint32)}
+	if numeric {
+		counter.keys = make(map[string]float64)
+	}
+	return counter
+}
+
+func (counter *facetCounter) add(value string) {
+	counter.counts[value]++
+}
+
+func (counter *facetCounter) addNumber(value string, key float64) {
+	counter.counts[value]++
+	counter.keys[value] = key
+}
+
+// result returns the counts by decreasing count, or by increasing value for a numeric facet
+func (counter *facetCounter) result() []*pb.FacetCount {
+	result := make([]*pb.FacetCount, 0, len(counter.counts))
+	for value, count := range counter.counts {
+		result = append(result, &pb.FacetCount{Value: value, Count: count})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if counter.keys != nil {
+			return counter.keys[a.Value] < counter.keys[b.Value]
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Value < b.Value
+	})
+
+	return result
+}
+
+// laptopFacets aggregates the laptops of a search
+type laptopFacets struct {
+	total          uint32
+	brands         *facetCounter
+	cpuBrands      *facetCounter
+	gpuBrands      *facetCounter
+	ramBuckets     *facetCounter
+	storageDrivers *facetCounter
+	screenPanels   *facetCounter
+	releaseYears   *facetCounter
+	minPrice       float64
+	maxPrice       float64
+	sumPrice       float64
+}
+
+func newLaptopFacets() *laptopFacets {
+	return &laptopFacets{
+		brands:         newFacetCounter(false),
+		cpuBrands:      newFacetCounter(false),
+		gpuBrands:      newFacetCounter(false),
+		ramBuckets:     newFacetCounter(true),
+		storageDrivers: newFacetCounter(false),
+		screenPanels:   newFacetCounter(false),
+		releaseYears:   newFacetCounter(true),
+		minPrice:       math.Inf(1),
+		maxPrice:       math.Inf(-1),
+	}
+}
+
+func (facets *laptopFacets) add(laptop *pb.Laptop) {
+	facets.total++
+	facets.brands.add(laptop.GetBrand())
+	facets.cpuBrands.add(laptop.GetCpu().GetBrand())
+
+	gpuBrands := make(map[string]bool)
+	for _, gpu := range laptop.GetGpus() {
+		gpuBrands[gpu.GetBrand()] = true
+	}
+	for brand := range gpuBrands {
+		facets.gpuBrands.add(brand)
+	}
+
+	bucket, gigabytes := ramBucket(laptop.GetRam())
+	facets.ramBuckets.addNumber(bucket, gigabytes)
+
+	drivers := make(map[pb.Storage_Driver]bool)
+	for _, storage := range laptop.GetStorages() {
+		drivers[storage.GetDriver()] = true
+	}
+	for driver := range drivers {
+		facets.storageDrivers.add(driver.String())
+	}
+
+	facets.screenPanels.add(laptop.GetScreen().GetPanel().String())
+
+	year := laptop.GetReleaseYear()
+	facets.releaseYears.addNumber(strconv.FormatUint(uint64(year), 10), float64(year))
+
+	price := laptop.GetPriceUsd()
+	facets.minPrice = math.Min(facets.minPrice, price)
+	facets.maxPrice = math.Max(facets.maxPrice, price)
+	facets.sumPrice += price
+}
+
+func (facets *laptopFacets) result() *pb.SearchFacets {
+	result := &pb.SearchFacets{
+		Total:          facets.total,
+		Brands:         facets.brands.result(),
+		CpuBrands:      facets.cpuBrands.result(),
+		GpuBrands:      facets.gpuBrands.result(),
+		RamBuckets:     facets.ramBuckets.result(),
+		StorageDrivers: facets.storageDrivers.result(),
+		ScreenPanels:   facets.screenPanels.result(),
+		ReleaseYears:   facets.releaseYears.result(),
+	}
+
+	if facets.total > 0 {
+		result.Price = &pb.PriceStats{
+			MinUsd: facets.minPrice,
+			MaxUsd: facets.maxPrice,
+			AvgUsd: facets.sumPrice / float64(facets.total),
+		}
+	}
+
+	return result
+}
+
+// ramBucket rounds the memory down to a power of two gigabytes
+func ramBucket(memory *pb.Memory) (string, float64) {
+	gigabytes := toBit(memory) >> 33
+	if gigabytes == 0 {
+		return "<1GB", 0
+	}
+
+	bucket := uint64(1)
+	for bucket*2 <= gigabytes {
+		bucket *= 2
+	}
+
+	return fmt.Sprintf("%dGB", bucket), float64(bucket)
+}
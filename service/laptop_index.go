@@ -0,0 +1,285 @@
+package service
+
+import (
+	"github.com/treeforest/grpc-pcbook/pb"
+	"math"
+	"sort"
+	"strings"
+)
+
+// maximum number of entries in a chunk of a sorted index
+const indexChunkSize = 512
+
+// indexEntry is a value of a laptop in a sorted index
+type indexEntry struct {
+	value float64
+	id    string
+}
+
+func (entry indexEntry) less(other indexEntry) bool {
+	if entry.value != other.value {
+		return entry.value < other.value
+	}
+	return entry.id < other.id
+}
+
+// sortedIndex keeps laptop IDs sorted by a value. Entries are split in small sorted chunks,
+// so that an insert or a delete only moves the entries of one chunk.
+type sortedIndex struct {
+	chunks [][]indexEntry
+}
+
+// chunkOf returns the position of the chunk that holds or should hold the entry
+func (index *sortedIndex) chunkOf(entry indexEntry) int {
+	i := sort.Search(len(index.chunks), func(i int) bool {
+		chunk := index.chunks[i]
+		return !chunk[len(chunk)-1].less(entry)
+	})
+	if i == len(index.chunks) && i > 0 {
+		i--
+	}
+	return i
+}
+
+func (index *sortedIndex) insert(value float64, id string) {
+	entry := indexEntry{value, id}
+	if len(index.chunks) == 0 {
+		index.chunks = [][]indexEntry{{entry}}
+		return
+	}
+
+	c := index.chunkOf(entry)
+	chunk := index.chunks[c]
+	i := sort.Search(len(chunk), func(i int) bool { return !chunk[i].less(entry) })
+	chunk = append(chunk, indexEntry{})
+	copy(chunk[i+1:], chunk[i:])
+	chunk[i] = entry
+	index.chunks[c] = chunk
+
+	if len(chunk) > indexChunkSize {
+		half := len(chunk) / 2
+		right := append([]indexEntry(nil), chunk[half:]...)
+		index.chunks[c] = chunk[:half:half]
+		index.chunks = append(index.chunks, nil)
+		copy(index.chunks[c+2:], index.chunks[c+1:])
+		index.chunks[c+1] = right
+	}
+}
+
+func (index *sortedIndex) delete(value float64, id string) {
+	if len(index.chunks) == 0 {
+		return
+	}
+
+	entry := indexEntry{value, id}
+	c := index.chunkOf(entry)
+	chunk := index.chunks[c]
+	i := sort.Search(len(chunk), func(i int) bool { return !chunk[i].less(entry) })
+	if i == len(chunk) || chunk[i] != entry {
+		return
+	}
+
+	chunk = append(chunk[:i], chunk[i+1:]...)
+	if len(chunk) == 0 {
+		index.chunks = append(index.chunks[:c], index.chunks[c+1:]...)
+		return
+	}
+	index.chunks[c] = chunk
+}
+
+// rank returns the number of entries with a value less than the given one,
+// or less than or equal to it if inclusive is true
+func (index *sortedIndex) rank(value float64, inclusive bool) int {
+	below := func(entry indexEntry) bool {
+		if inclusive {
+			return entry.value <= value
+		}
+		return entry.value < value
+	}
+
+	rank := 0
+	for _, chunk := range index.chunks {
+		if below(chunk[len(chunk)-1]) {
+			rank += len(chunk)
+			continue
+		}
+
+		return rank + sort.Search(len(chunk), func(i int) bool { return !below(chunk[i]) })
+	}
+
+	return rank
+}
+
+// count returns the number of entries with a value between min and max
+func (index *sortedIndex) count(min, max float64) int {
+	if min > max {
+		return 0
+	}
+	return index.rank(max, true) - index.rank(min, false)
+}
+
+// ascend calls fn with the ID of each entry with a value between min and max in ascending order,
+// until fn returns false
+func (index *sortedIndex) ascend(min, max float64, fn func(id string) bool) {
+	start := indexEntry{value: min}
+	for c := index.chunkOf(start); c < len(index.chunks); c++ {
+		chunk := index.chunks[c]
+		i := sort.Search(len(chunk), func(i int) bool { return chunk[i].value >= min })
+
+		for ; i < len(chunk); i++ {
+			if chunk[i].value > max {
+				return
+			}
+			if !fn(chunk[i].id) {
+				return
+			}
+		}
+	}
+}
+
+// laptopIndexes are the secondary indexes of the laptops of a store
+type laptopIndexes struct {
+	price    sortedIndex
+	cpuCores sortedIndex
+	cpuGhz   sortedIndex
+	ramBits  sortedIndex
+	brand    map[string]map[string]bool
+	text     *textIndex
+	vectors  *vectorIndex
+	size     int
+}
+
+func newLaptopIndexes() *laptopIndexes {
+	return &laptopIndexes{
+		brand:   make(map[string]map[string]bool),
+		text:    newTextIndex(),
+		vectors: newVectorIndex(),
+	}
+}
+
+func (indexes *laptopIndexes) add(laptop *pb.Laptop) {
+	id := laptop.GetId()
+	indexes.price.insert(laptop.GetPriceUsd(), id)
+	indexes.cpuCores.insert(float64(laptop.GetCpu().GetNumberCores()), id)
+	indexes.cpuGhz.insert(laptop.GetCpu().GetMinGhz(), id)
+	indexes.ramBits.insert(float64(toBit(laptop.GetRam())), id)
+
+	brand := strings.ToLower(laptop.GetBrand())
+	if indexes.brand[brand] == nil {
+		indexes.brand[brand] = make(map[string]bool)
+	}
+	indexes.brand[brand][id] = true
+
+	indexes.text.add(laptop)
+	indexes.vectors.add(laptop)
+	indexes.size++
+}
+
+func (indexes *laptopIndexes) remove(laptop *pb.Laptop) {
+	id := laptop.GetId()
+	indexes.price.delete(laptop.GetPriceUsd(), id)
+	indexes.cpuCores.delete(float64(laptop.GetCpu().GetNumberCores()), id)
+	indexes.cpuGhz.delete(laptop.GetCpu().GetMinGhz(), id)
+	indexes.ramBits.delete(float64(toBit(laptop.GetRam())), id)
+
+	brand := strings.ToLower(laptop.GetBrand())
+	delete(indexes.brand[brand], id)
+	if len(indexes.brand[brand]) == 0 {
+		delete(indexes.brand, brand)
+	}
+
+	indexes.text.remove(laptop)
+	indexes.vectors.remove(id)
+	indexes.size--
+}
+
+// indexRange is the set of laptops a filter accepts in an index: a range of values of a sorted index,
+// or the IDs of a brand
+type indexRange struct {
+	index    *sortedIndex
+	min, max float64
+	ids      map[string]bool
+	count    int
+}
+
+func (r *indexRange) ascend(fn func(id string) bool) {
+	if r.index == nil {
+		for id := range r.ids {
+			if !fn(id) {
+				return
+			}
+		}
+		return
+	}
+	r.index.ascend(r.min, r.max, fn)
+}
+
+// candidates calls fn with the ID of every laptop that may pass the filter, until fn returns false.
+// It walks the range that accepts the fewest laptops, or intersects it with the second fewest, the caller
+// checks the candidates against the whole filter. It returns false without calling fn if both would cost
+// more than checking every laptop of the store.
+func (indexes *laptopIndexes) candidates(filter *pb.Filter, fn func(id string) bool) bool {
+	minPrice := math.Inf(-1)
+	if filter.GetMinPriceUsd() > 0 {
+		minPrice = filter.GetMinPriceUsd()
+	}
+
+	ranges := []*indexRange{
+		{index: &indexes.price, min: minPrice, max: filter.GetMaxPriceUsd()},
+		{index: &indexes.cpuCores, min: float64(filter.GetMinCpuCores()), max: math.Inf(1)},
+		{index: &indexes.cpuGhz, min: filter.GetMinCpuGhz(), max: math.Inf(1)},
+		{index: &indexes.ramBits, min: float64(toBit(filter.GetMinRam())), max: math.Inf(1)},
+	}
+	for _, r := range ranges {
+		r.count = r.index.count(r.min, r.max)
+	}
+	if len(filter.GetBrand()) > 0 {
+		ids := indexes.brand[strings.ToLower(filter.GetBrand())]
+		ranges = append(ranges, &indexRange{ids: ids, count: len(ids)})
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].count < ranges[j].count })
+	best, next := ranges[0], ranges[1]
+	if best.count == 0 {
+		return true
+	}
+
+	// The costs are in laptops checked by a scan. Looking a candidate up by ID and checking it costs
+	// about twice as much, putting an ID in a set or probing a set with it about half as much.
+	// The ranges are intersected by probing the set of one with the IDs of the other, a brand
+	// is already a set.
+	set, walk := best, next
+	if next.ids != nil {
+		set, walk = next, best
+	}
+
+	scanCost := float64(indexes.size)
+	walkCost := 2 * float64(best.count)
+	intersection := float64(best.count) * float64(next.count) / float64(indexes.size)
+	intersectCost := 0.5*float64(walk.count) + 2*intersection
+	if set.ids == nil {
+		intersectCost += 0.5 * float64(set.count)
+	}
+
+	if scanCost <= walkCost && scanCost <= intersectCost {
+		return false
+	}
+	if walkCost <= intersectCost {
+		best.ascend(fn)
+		return true
+	}
+
+	ids := set.ids
+	if ids == nil {
+		ids = make(map[string]bool, set.count)
+		set.ascend(func(id string) bool {
+			ids[id] = true
+			return true
+		})
+	}
+
+	walk.ascend(func(id string) bool {
+		return !ids[id] || fn(id)
+	})
+	return true
+}
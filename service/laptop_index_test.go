@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"github.com/treeforest/grpc-pcbook/sample"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestSortedIndex(t *testing.T) {
+	t.Parallel()
+
+	index := sortedIndex{}
+	values := make(map[string]float64)
+	for i := 0; i < 5000; i++ {
+		id := fmt.Sprintf("laptop-%04d", i)
+		values[id] = float64(rand.Intn(100))
+		index.insert(values[id], id)
+	}
+	require.Greater(t, len(index.chunks), 1)
+
+	for i := 0; i < 5000; i += 3 {
+		id := fmt.Sprintf("laptop-%04d", i)
+		index.delete(values[id], id)
+		delete(values, id)
+	}
+	index.delete(1000, "laptop-unknown")
+
+	expected := func(min, max float64) []string {
+		var ids []string
+		for id, value := range values {
+			if value >= min && value <= max {
+				ids = append(ids, id)
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			a, b := values[ids[i]], values[ids[j]]
+			if a != b {
+				return a < b
+			}
+			return ids[i] < ids[j]
+		})
+		return ids
+	}
+
+	testCases := []struct {
+		name     string
+		min, max float64
+	}{
+		{"all", math.Inf(-1), math.Inf(1)},
+		{"range", 20, 40},
+		{"single value", 50, 50},
+		{"below", -10, -1},
+		{"above", 100, 200},
+		{"empty", 60, 30},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			want := expected(tc.min, tc.max)
+
+			var got []string
+			index.ascend(tc.min, tc.max, func(id string) bool {
+				got = append(got, id)
+				return true
+			})
+			require.Equal(t, want, got)
+			require.Equal(t, len(want), index.count(tc.min, tc.max))
+		})
+	}
+}
+
+func TestSortedIndexStop(t *testing.T) {
+	t.Parallel()
+
+	index := sortedIndex{}
+	for i := 0; i < 10; i++ {
+		index.insert(float64(i), fmt.Sprint(i))
+	}
+
+	var got []string
+	index.ascend(2, 8, func(id string) bool {
+		got = append(got, id)
+		return len(got) < 3
+	})
+	require.Equal(t, []string{"2", "3", "4"}, got)
+}
+
+func TestInMemoryLaptopStoreIndexes(t *testing.T) {
+	t.Parallel()
+
+	store := NewInMemoryLaptopStore()
+	laptop := sample.NewLaptop()
+	laptop.PriceUsd = 1000
+	require.NoError(t, store.Save(laptop))
+
+	search := func(filter *pb.Filter) []string {
+		var ids []string
+		err := store.Search(context.Background(), filter, nil, func(laptop *pb.Laptop) error {
+			ids = append(ids, laptop.GetId())
+			return nil
+		})
+		require.NoError(t, err)
+		return ids
+	}
+
+	filter := &pb.Filter{MinPriceUsd: 1500, MaxPriceUsd: 2500}
+	require.Empty(t, search(filter))
+
+	laptop.PriceUsd = 2000
+	require.NoError(t, store.Update(laptop))
+	require.Equal(t, []string{laptop.Id}, search(filter))
+	require.Empty(t, search(&pb.Filter{MaxPriceUsd: 1500}))
+
+	require.NoError(t, store.Delete(laptop.Id, 0))
+	require.Empty(t, search(filter))
+	require.Zero(t, store.indexes.price.count(math.Inf(-1), math.Inf(1)))
+	require.Empty(t, store.indexes.brand)
+}
+
+func TestInMemoryLaptopStoreCandidates(t *testing.T) {
+	t.Parallel()
+
+	store := NewInMemoryLaptopStore()
+	for i := 0; i < 2000; i++ {
+		require.NoError(t, store.Save(sample.NewLaptop()))
+	}
+
+	ids := func(search func(found func(laptop *pb.Laptop) error) error) []string {
+		var ids []string
+		err := search(func(laptop *pb.Laptop) error {
+			ids = append(ids, laptop.GetId())
+			return nil
+		})
+		require.NoError(t, err)
+		sort.Strings(ids)
+		return ids
+	}
+
+	testCases := []struct {
+		name    string
+		filter  *pb.Filter
+		indexed bool
+	}{
+		{"walk", benchmarkFilter(), true},
+		{"intersect", &pb.Filter{MaxPriceUsd: 1750, MinCpuCores: 7}, true},
+		{"intersect brand", &pb.Filter{MaxPriceUsd: 1600, Brand: "dell"}, true},
+		{"scan", &pb.Filter{MaxPriceUsd: 3000}, false},
+		{"no match", &pb.Filter{MaxPriceUsd: 100}, true},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			indexed := store.indexes.candidates(tc.filter, func(id string) bool { return true })
+			require.Equal(t, tc.indexed, indexed)
+
+			want := ids(func(found func(laptop *pb.Laptop) error) error {
+				return scanLaptops(store, tc.filter, found)
+			})
+			got := ids(func(found func(laptop *pb.Laptop) error) error {
+				return store.Search(context.Background(), tc.filter, nil, found)
+			})
+			require.Equal(t, want, got)
+		})
+	}
+}
+
+// scanLaptops is the search without indexes: it checks every laptop of the store
+func scanLaptops(store *InMemoryLaptopStore, filter *pb.Filter, found func(laptop *pb.Laptop) error) error {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for _, laptop := range store.data {
+		if isQualified(filter, laptop) {
+			other, err := deepCopy(laptop)
+			if err != nil {
+				return err
+			}
+
+			err = found(other)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func newBenchmarkLaptopStore(b *testing.B, n int) *InMemoryLaptopStore {
+	if n >= 1000000 && testing.Short() {
+		b.Skip("skipping 1M laptops in short mode")
+	}
+
+	store := NewInMemoryLaptopStore()
+	for i := 0; i < n; i++ {
+		err := store.Save(sample.NewLaptop())
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	return store
+}
+
+func benchmarkFilter() *pb.Filter {
+	return &pb.Filter{
+		MaxPriceUsd: 2000,
+		MinCpuCores: 7,
+		MinCpuGhz:   3.3,
+		MinRam:      &pb.Memory{Value: 32, Unit: pb.Memory_GIGABYTE},
+		Brand:       "Apple",
+	}
+}
+
+func benchmarkUnselectiveFilter() *pb.Filter {
+	return &pb.Filter{
+		MaxPriceUsd: 2250,
+		MinCpuCores: 5,
+		MinCpuGhz:   2.75,
+		MinRam:      &pb.Memory{Value: 34, Unit: pb.Memory_GIGABYTE},
+	}
+}
+
+func BenchmarkLaptopStoreSearch(b *testing.B) {
+	filters := []struct {
+		name   string
+		filter *pb.Filter
+	}{
+		{"selective", benchmarkFilter()},
+		// every range accepts about half of the laptops, but few laptops are in all of them
+		{"unselective", benchmarkUnselectiveFilter()},
+	}
+
+	for _, n := range []int{10000, 100000, 1000000} {
+		n := n
+		b.Run(fmt.Sprintf("%d", n), func(b *testing.B) {
+			store := newBenchmarkLaptopStore(b, n)
+			found := func(laptop *pb.Laptop) error { return nil }
+
+			for _, f := range filters {
+				filter := f.filter
+				b.Run(f.name, func(b *testing.B) {
+					b.Run("index", func(b *testing.B) {
+						for i := 0; i < b.N; i++ {
+							err := store.Search(context.Background(), filter, nil, found)
+							if err != nil {
+								b.Fatal(err)
+							}
+						}
+					})
+
+					b.Run("scan", func(b *testing.B) {
+						for i := 0; i < b.N; i++ {
+							err := scanLaptops(store, filter, found)
+							if err != nil {
+								b.Fatal(err)
+							}
+						}
+					})
+				})
+			}
+		})
+	}
+}
+
+func BenchmarkLaptopStoreFindSimilar(b *testing.B) {
+	for _, n := range []int{10000, 100000} {
+		n := n
+		b.Run(fmt.Sprintf("%d", n), func(b *testing.B) {
+			store := newBenchmarkLaptopStore(b, n)
+			laptop := sample.NewLaptop()
+			err := store.Save(laptop)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			found := func(laptop *pb.Laptop, distance float64) error { return nil }
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				err := store.FindSimilar(context.Background(), laptop.Id, nil, defaultSimilarLaptops, found)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
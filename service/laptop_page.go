@@ -0,0 +1,343 @@
+package service
+
+import (
+	"container/heap"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"hash/crc32"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// page size of ListLaptops when the request doesn't set one
+	defaultPageSize = 50
+	maxPageSize     = 1000
+)
+
+// laptopOrder is the order of a laptop search, laptops with the same value are ordered by ID
+type laptopOrder struct {
+	field string
+	desc  bool
+}
+
+// parseLaptopOrder parses an order_by value such as "price_usd" or "price_usd desc".
+// A text search is ordered by decreasing relevance by default.
+func parseLaptopOrder(orderBy string, query string) (laptopOrder, error) {
+	words := strings.Fields(orderBy)
+	if len(words) == 0 {
+		if isTextQuery(query) {
+			return laptopOrder{field: "relevance", desc: true}, nil
+		}
+		return laptopOrder{field: "id"}, nil
+	}
+
+	order := laptopOrder{field: words[0]}
+	switch order.field {
+	case "price_usd", "release_year", "cpu.min_ghz", "ram", "rating":
+	case "relevance":
+		if !isTextQuery(query) {
+			return order, fmt.Errorf("cannot order by relevance without a query")
+		}
+	default:
+		return order, fmt.Errorf("cannot order by %q", order.field)
+	}
+
+	if len(words) > 1 {
+		switch strings.ToLower(words[1]) {
+		case "asc":
+		case "desc":
+			order.desc = true
+		default:
+			return order, fmt.Errorf("unknown order direction %q", words[1])
+		}
+	}
+
+	if len(words) > 2 {
+		return order, fmt.Errorf("invalid order_by %q", orderBy)
+	}
+
+	return order, nil
+}
+
+func (order laptopOrder) String() string {
+	if order.desc {
+		return order.field + " desc"
+	}
+	return order.field
+}
+
+// pageToken is the position after which the next page starts
+type pageToken struct {
+	Order  string  `json:"o"`
+	Filter uint32  `json:"f"`
+	Value  float64 `json:"v"`
+	ID     string  `json:"i"`
+	// Time is the time in nanoseconds since the Unix epoch at which the first page scored the ratings,
+	// so that the ratings which decay with time keep the value they had for the cursor
+	Time int64 `json:"t,omitempty"`
+}
+
+// encodePageToken encodes the token, it fails if the value is not finite since JSON has no such number
+func encodePageToken(token pageToken) (string, error) {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", fmt.Errorf("cannot encode page token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodePageToken(s string) (pageToken, error) {
+	token := pageToken{}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return token, fmt.Errorf("invalid page token")
+	}
+
+	err = json.Unmarshal(data, &token)
+	if err != nil {
+		return token, fmt.Errorf("invalid page token")
+	}
+
+	return token, nil
+}
+
+// filterChecksum identifies a filter, a query and a filter expression so that a page token
+// cannot be used with other ones
+func filterChecksum(filter *pb.Filter, query string, filterExpr string) uint32 {
+	data, _ := proto.Marshal(filter)
+	checksum := crc32.ChecksumIEEE(data)
+	checksum = crc32.Update(checksum, crc32.IEEETable, []byte(query))
+	return crc32.Update(checksum, crc32.IEEETable, []byte("\x00"+filterExpr))
+}
+
+// parseSearchFilter parses the filter expression of a search. A search by expression alone
+// doesn't need a filter, so a missing filter then accepts every laptop. Errors are gRPC status errors.
+func parseSearchFilter(filter *pb.Filter, filterExpr string) (*pb.Filter, *FilterExpr, error) {
+	if len(strings.TrimSpace(filterExpr)) == 0 {
+		return filter, nil, nil
+	}
+
+	expr, err := ParseFilterExpr(filterExpr)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "invalid filter_expr: %v", err)
+	}
+
+	if filter == nil {
+		filter = &pb.Filter{MaxPriceUsd: math.Inf(1)}
+	}
+
+	return filter, expr, nil
+}
+
+// sortedLaptop is a laptop with the value it is sorted by
+type sortedLaptop struct {
+	laptop *pb.Laptop
+	value  float64
+}
+
+// before reports whether a comes before b in the order
+func (order laptopOrder) before(a, b sortedLaptop) bool {
+	if a.value != b.value {
+		return (a.value < b.value) != order.desc
+	}
+	return a.laptop.GetId() < b.laptop.GetId()
+}
+
+// sortValue returns the value of the laptop that the order sorts by, the ratings are scored at the given time
+func (server *LaptopServer) sortValue(order laptopOrder, laptop *pb.Laptop, now time.Time) (float64, error) {
+	switch order.field {
+	case "price_usd":
+		return laptop.GetPriceUsd(), nil
+	case "release_year":
+		return float64(laptop.GetReleaseYear()), nil
+	case "cpu.min_ghz":
+		return laptop.GetCpu().GetMinGhz(), nil
+	case "ram":
+		return float64(toBit(laptop.GetRam())), nil
+	case "rating":
+		if server.ratingStore == nil {
+			return 0, nil
+		}
+
+		score, _, err := server.ratingScorer.Score(server.ratingStore, laptop.GetId(), now)
+		return score, err
+	default:
+		return 0, nil
+	}
+}
+
+// pageRequest is a request for a page of laptops
+type pageRequest struct {
+	filter *pb.Filter
+	// text query, if any, that the brand, name, CPU or GPU names must match
+	query string
+	// filter expression, if any, that the laptops must match as well as the filter
+	filterExpr string
+	orderBy    string
+	pageSize   int
+	token      string
+}
+
+// searchPage returns a page of the laptops that pass the filter in the given order,
+// together with the token of the next page. A page size of zero returns every laptop.
+// The page token holds the position of the last laptop of the previous page,
+// so laptops saved in the meantime don't shift the pages. It also holds the time at which
+// the first page scored the ratings, so their decay doesn't move the laptops across the cursor.
+// The relevance and rating orders are still not stable across pages when the catalog or the ratings
+// change in the meantime: the relevance of a laptop depends on how many laptops have each word,
+// and a new score changes the rating of a laptop, so such a laptop can be skipped or listed twice.
+// Errors are gRPC status errors.
+func (server *LaptopServer) searchPage(ctx context.Context, req pageRequest) ([]*pb.Laptop, string, error) {
+	filter, expr, err := parseSearchFilter(req.filter, req.filterExpr)
+	if err != nil {
+		return nil, "", err
+	}
+
+	order, err := parseLaptopOrder(req.orderBy, req.query)
+	if err != nil {
+		return nil, "", status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	pageSize := req.pageSize
+	if pageSize < 0 {
+		return nil, "", status.Error(codes.InvalidArgument, "page size cannot be negative")
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	checksum := filterChecksum(req.filter, req.query, req.filterExpr)
+
+	// without its monotonic clock reading, the time scores the ratings as it does once read from the token
+	now := time.Now().Round(0)
+	var after *sortedLaptop
+	if len(req.token) > 0 {
+		position, err := decodePageToken(req.token)
+		if err != nil {
+			return nil, "", status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+		if position.Order != order.String() || position.Filter != checksum {
+			return nil, "", status.Error(codes.InvalidArgument, "page token doesn't match the request")
+		}
+
+		after = &sortedLaptop{
+			laptop: &pb.Laptop{Id: position.ID},
+			value:  position.Value,
+		}
+		if position.Time != 0 {
+			now = time.Unix(0, position.Time)
+		}
+	}
+
+	// a page keeps one more laptop than its size, which tells whether there is a next page,
+	// a page size of zero keeps every laptop
+	laptops := &pageHeap{order: order}
+	add := func(laptop *pb.Laptop, score float64) error {
+		value := score
+		if order.field != "relevance" {
+			var err error
+			value, err = server.sortValue(order, laptop, now)
+			if err != nil {
+				return err
+			}
+		}
+
+		item := sortedLaptop{laptop: laptop, value: value}
+		if after != nil && !order.before(*after, item) {
+			return nil
+		}
+
+		if pageSize == 0 {
+			laptops.items = append(laptops.items, item)
+		} else if laptops.Len() <= pageSize {
+			heap.Push(laptops, item)
+		} else if order.before(item, laptops.items[0]) {
+			laptops.items[0] = item
+			heap.Fix(laptops, 0)
+		}
+		return nil
+	}
+
+	if isTextQuery(req.query) {
+		err = server.laptopStore.SearchText(ctx, req.query, filter, expr, add)
+	} else {
+		err = server.laptopStore.Search(ctx, filter, expr, func(laptop *pb.Laptop) error {
+			return add(laptop, 0)
+		})
+	}
+	if err != nil {
+		return nil, "", status.Errorf(codes.Internal, "unexpected error: %v", err)
+	}
+
+	items := laptops.items
+	sort.Slice(items, func(i, j int) bool {
+		return order.before(items[i], items[j])
+	})
+
+	nextToken := ""
+	if pageSize > 0 && len(items) > pageSize {
+		items = items[:pageSize]
+
+		last := items[pageSize-1]
+		nextToken, err = encodePageToken(pageToken{
+			Order:  order.String(),
+			Filter: checksum,
+			Value:  last.value,
+			ID:     last.laptop.GetId(),
+			Time:   now.UnixNano(),
+		})
+		if err != nil {
+			return nil, "", status.Errorf(codes.Internal, "%v", err)
+		}
+	}
+
+	page := make([]*pb.Laptop, len(items))
+	for i, item := range items {
+		page[i] = item.laptop
+	}
+
+	return page, nextToken, nil
+}
+
+// pageHeap is a max-heap of sorted laptops with the last one in the order on top,
+// it keeps a page of laptops without sorting every laptop after the cursor
+type pageHeap struct {
+	order laptopOrder
+	items []sortedLaptop
+}
+
+func (laptops *pageHeap) Len() int { return len(laptops.items) }
+
+func (laptops *pageHeap) Less(i, j int) bool {
+	return laptops.order.before(laptops.items[j], laptops.items[i])
+}
+
+func (laptops *pageHeap) Swap(i, j int) {
+	laptops.items[i], laptops.items[j] = laptops.items[j], laptops.items[i]
+}
+
+func (laptops *pageHeap) Push(x interface{}) {
+	laptops.items = append(laptops.items, x.(sortedLaptop))
+}
+
+func (laptops *pageHeap) Pop() interface{} {
+	old := laptops.items
+	item := old[len(old)-1]
+	laptops.items = old[:len(old)-1]
+	return item
+}
+
+// isPagingRequest reports whether a search needs sorting, which a text search always does
+func isPagingRequest(query string, orderBy string, pageSize int32, pageToken string) bool {
+	return isTextQuery(query) || len(orderBy) > 0 || pageSize != 0 || len(pageToken) > 0
+}
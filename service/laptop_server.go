@@ -2,28 +2,42 @@ package service
 
 import (
 	"bytes"
+	"container/heap"
 	"context"
 	"errors"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
 	"github.com/google/uuid"
 	"github.com/treeforest/grpc-pcbook/pb"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"io"
 	"log"
+	"math"
+	"sort"
+	"time"
 )
 
 // maximum 1 megabyte
 const maxImageSize = 1 << 20
 
+const (
+	// number of laptops of TopRatedLaptops when the request doesn't set it
+	defaultTopRatedLaptops = 10
+	maxTopRatedLaptops     = 100
+)
+
 // LaptopServer is the server that provide laptop service
 type LaptopServer struct {
 	pb.UnimplementedLaptopServiceServer
 	laptopStore LaptopStore
 	imageStore  ImageStore
 	ratingStore RatingStore
+	// ranks the laptops of TopRatedLaptops and of the searches ordered by rating
+	ratingScorer RatingScorer
 }
 
-// NewLaptopServer returns a new LaptopServer
+// NewLaptopServer returns a new LaptopServer, which ranks rated laptops by their average score
 func NewLaptopServer(laptopStore LaptopStore, imageStore ImageStore, ratingStore RatingStore) *LaptopServer {
 	return &LaptopServer{
 		laptopStore: laptopStore,
@@ -32,13 +46,29 @@ func NewLaptopServer(laptopStore LaptopStore, imageStore ImageStore, ratingStore
 	}
 }
 
+// SetRatingScorer sets how the server ranks rated laptops, it must be called before the server starts
+func (server *LaptopServer) SetRatingScorer(scorer RatingScorer) error {
+	err := scorer.Validate()
+	if err != nil {
+		return fmt.Errorf("invalid rating scorer: %w", err)
+	}
+
+	server.ratingScorer = scorer
+	return nil
+}
+
 // CreateLaptop is a unary RPC to create a new laptop
 func (server *LaptopServer) CreateLaptop(
 	ctx context.Context,
 	req *pb.CreateLaptopRequest,
 	) (*pb.CreateLaptopResponse, error) {
+	username, err := authenticatedUsername(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	laptop := req.GetLaptop()
-	log.Printf("receive a create-laptop request with id: %s", laptop.Id)
+	log.Printf("receive a create-laptop request from %s with id: %s", username, laptop.Id)
 
 	if len(laptop.Id) > 0 {
 		// check if it's a valid UUID
@@ -54,6 +84,10 @@ func (server *LaptopServer) CreateLaptop(
 		laptop.Id = id.String()
 	}
 
+	// the creator owns the laptop, and shares it with ShareLaptop
+	laptop.Owner = username
+	laptop.Editors = nil
+
 	// some heavy processing
 	//time.Sleep(time.Second * 6)
 
@@ -62,7 +96,7 @@ func (server *LaptopServer) CreateLaptop(
 	}
 
 	// save the laptop to laptopStore
-	err := server.laptopStore.Save(laptop)
+	err = server.laptopStore.Save(laptop)
 	if err != nil {
 		code := codes.Internal
 		if errors.Is(err, ErrAlreadyExists) {
@@ -73,6 +107,7 @@ func (server *LaptopServer) CreateLaptop(
 	}
 
 	log.Printf("saved laptop with id: %s", laptop.Id)
+	sendETag(ctx, laptop.Revision)
 
 	res := &pb.CreateLaptopResponse{
 		Id: laptop.Id,
@@ -94,11 +129,24 @@ func contextError(ctx context.Context) error {
 // SearchLaptop is a server-streaming RPC to search for laptop
 func (server *LaptopServer) SearchLaptop(req *pb.SearchLaptopRequest, stream pb.LaptopService_SearchLaptopServer) error {
 	filter := req.GetFilter()
-	log.Printf("receive a search-laptop request with filter: %v", filter)
+	log.Printf(
+		"receive a search-laptop request with filter: %v, query: %q, filter expression: %q",
+		filter, req.GetQuery(), req.GetFilterExpr(),
+	)
+
+	if isPagingRequest(req.GetQuery(), req.GetOrderBy(), req.GetPageSize(), req.GetPageToken()) {
+		return server.searchLaptopPage(req, stream)
+	}
 
-	err := server.laptopStore.Search(
+	filter, expr, err := parseSearchFilter(filter, req.GetFilterExpr())
+	if err != nil {
+		return logError(err)
+	}
+
+	err = server.laptopStore.Search(
 		stream.Context(),
 		filter,
+		expr,
 		func(laptop *pb.Laptop) error {
 			res := &pb.SearchLaptopResponse{Laptop: laptop}
 
@@ -118,6 +166,423 @@ func (server *LaptopServer) SearchLaptop(req *pb.SearchLaptopRequest, stream pb.
 	return nil
 }
 
+// searchLaptopPage streams a sorted page of laptops, the last one carries the token of the next page
+func (server *LaptopServer) searchLaptopPage(req *pb.SearchLaptopRequest, stream pb.LaptopService_SearchLaptopServer) error {
+	laptops, nextPageToken, err := server.searchPage(stream.Context(), pageRequest{
+		filter:     req.GetFilter(),
+		query:      req.GetQuery(),
+		filterExpr: req.GetFilterExpr(),
+		orderBy:    req.GetOrderBy(),
+		pageSize:   int(req.GetPageSize()),
+		token:      req.GetPageToken(),
+	})
+	if err != nil {
+		return logError(err)
+	}
+
+	for i, laptop := range laptops {
+		res := &pb.SearchLaptopResponse{Laptop: laptop}
+		if i == len(laptops)-1 {
+			res.NextPageToken = nextPageToken
+		}
+
+		err := stream.Send(res)
+		if err != nil {
+			return logError(status.Errorf(codes.Unknown, "cannot send stream response: %v", err))
+		}
+
+		log.Printf("sent laptop with id: %s", laptop.GetId())
+	}
+
+	return nil
+}
+
+// ListLaptops is a unary RPC to list a sorted page of laptops
+func (server *LaptopServer) ListLaptops(
+	ctx context.Context,
+	req *pb.ListLaptopsRequest,
+) (*pb.ListLaptopsResponse, error) {
+	log.Printf("receive a list-laptops request with filter: %v, order by: %s", req.GetFilter(), req.GetOrderBy())
+
+	pageSize := int(req.GetPageSize())
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+
+	laptops, nextPageToken, err := server.searchPage(ctx, pageRequest{
+		filter:     req.GetFilter(),
+		query:      req.GetQuery(),
+		filterExpr: req.GetFilterExpr(),
+		orderBy:    req.GetOrderBy(),
+		pageSize:   pageSize,
+		token:      req.GetPageToken(),
+	})
+	if err != nil {
+		return nil, logError(err)
+	}
+
+	res := &pb.ListLaptopsResponse{
+		Laptops:       laptops,
+		NextPageToken: nextPageToken,
+	}
+	return res, nil
+}
+
+// GetSearchFacets is a unary RPC to count the laptops of a search by brand, CPU, GPU, RAM and more
+func (server *LaptopServer) GetSearchFacets(
+	ctx context.Context,
+	req *pb.GetSearchFacetsRequest,
+) (*pb.GetSearchFacetsResponse, error) {
+	log.Printf(
+		"receive a get-search-facets request with filter: %v, query: %q, filter expression: %q",
+		req.GetFilter(), req.GetQuery(), req.GetFilterExpr(),
+	)
+
+	filter, expr, err := parseSearchFilter(req.GetFilter(), req.GetFilterExpr())
+	if err != nil {
+		return nil, logError(err)
+	}
+
+	facets, err := server.laptopStore.Facets(ctx, req.GetQuery(), filter, expr)
+	if err != nil {
+		if err := contextError(ctx); err != nil {
+			return nil, err
+		}
+		return nil, logError(status.Errorf(codes.Internal, "cannot compute search facets: %v", err))
+	}
+
+	res := &pb.GetSearchFacetsResponse{Facets: facets}
+	return res, nil
+}
+
+// WatchLaptops is a server-streaming RPC to watch the changes of the laptops that match a filter
+func (server *LaptopServer) WatchLaptops(req *pb.WatchLaptopsRequest, stream pb.LaptopService_WatchLaptopsServer) error {
+	log.Printf(
+		"receive a watch-laptops request with filter: %v, filter expression: %q, after sequence: %d",
+		req.GetFilter(), req.GetFilterExpr(), req.GetAfterSequence(),
+	)
+
+	filter, expr, err := parseSearchFilter(req.GetFilter(), req.GetFilterExpr())
+	if err != nil {
+		return logError(err)
+	}
+
+	ctx := stream.Context()
+	err = server.laptopStore.Watch(ctx, req.GetAfterSequence(), func(change *LaptopChange) error {
+		if !isWatched(filter, expr, change.Event.GetLaptop()) && !isWatched(filter, expr, change.Previous) {
+			return nil
+		}
+
+		err := stream.Send(&pb.WatchLaptopsResponse{Event: change.Event})
+		if err != nil {
+			return status.Errorf(codes.Unknown, "cannot send stream response: %v", err)
+		}
+
+		log.Printf("sent %s event of laptop with id: %s", change.Event.GetType(), change.Event.GetLaptop().GetId())
+		return nil
+	})
+
+	if errors.Is(err, ErrUnknownSequence) {
+		return logError(status.Errorf(codes.OutOfRange, "cannot resume after sequence %d: %v", req.GetAfterSequence(), err))
+	}
+	if err := contextError(ctx); err != nil {
+		return err
+	}
+	if _, ok := status.FromError(err); ok {
+		return logError(err)
+	}
+	return logError(status.Errorf(codes.Internal, "cannot watch laptops: %v", err))
+}
+
+// GetLaptop is a unary RPC to get a laptop by ID
+func (server *LaptopServer) GetLaptop(
+	ctx context.Context,
+	req *pb.GetLaptopRequest,
+) (*pb.GetLaptopResponse, error) {
+	laptopID := req.GetId()
+	log.Printf("receive a get-laptop request with id: %s", laptopID)
+
+	laptop, err := server.laptopStore.Find(laptopID)
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot find laptop: %v", err))
+	}
+	if laptop == nil {
+		return nil, logError(status.Errorf(codes.NotFound, "laptop %s doesn't exist", laptopID))
+	}
+
+	sendETag(ctx, laptop.Revision)
+
+	res := &pb.GetLaptopResponse{
+		Laptop: laptop,
+	}
+	return res, nil
+}
+
+// CompareLaptops is a unary RPC to compare the specs of several laptops
+func (server *LaptopServer) CompareLaptops(
+	ctx context.Context,
+	req *pb.CompareLaptopsRequest,
+) (*pb.CompareLaptopsResponse, error) {
+	laptopIDs := req.GetIds()
+	log.Printf("receive a compare-laptops request with ids: %v", laptopIDs)
+
+	if len(laptopIDs) < minComparedLaptops || len(laptopIDs) > maxComparedLaptops {
+		return nil, logError(status.Errorf(
+			codes.InvalidArgument,
+			"cannot compare %d laptops, must be between %d and %d",
+			len(laptopIDs), minComparedLaptops, maxComparedLaptops,
+		))
+	}
+
+	laptops := make([]*comparedLaptop, len(laptopIDs))
+	seen := make(map[string]bool, len(laptopIDs))
+
+	for i, laptopID := range laptopIDs {
+		if seen[laptopID] {
+			return nil, logError(status.Errorf(codes.InvalidArgument, "laptop %s is compared more than once", laptopID))
+		}
+		seen[laptopID] = true
+
+		laptop, err := server.laptopStore.Find(laptopID)
+		if err != nil {
+			return nil, logError(status.Errorf(codes.Internal, "cannot find laptop: %v", err))
+		}
+		if laptop == nil {
+			return nil, logError(status.Errorf(codes.NotFound, "laptop %s doesn't exist", laptopID))
+		}
+
+		laptops[i] = &comparedLaptop{laptop: laptop}
+		if server.ratingStore != nil {
+			laptops[i].rating, err = server.ratingStore.Find(laptopID)
+			if err != nil {
+				return nil, logError(status.Errorf(codes.Internal, "cannot find laptop rating: %v", err))
+			}
+		}
+	}
+
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	res := &pb.CompareLaptopsResponse{
+		Comparison: compareLaptops(laptops),
+	}
+	return res, nil
+}
+
+// FindSimilarLaptops is a unary RPC to find the laptops with the nearest specs to a laptop
+func (server *LaptopServer) FindSimilarLaptops(
+	ctx context.Context,
+	req *pb.FindSimilarLaptopsRequest,
+) (*pb.FindSimilarLaptopsResponse, error) {
+	laptopID := req.GetId()
+	log.Printf("receive a find-similar-laptops request with id: %s, k: %d", laptopID, req.GetK())
+
+	// k is checked before it is converted, since a large uint32 is a negative int on 32-bit platforms
+	if req.GetK() > maxSimilarLaptops {
+		return nil, logError(status.Errorf(codes.InvalidArgument, "k must be at most %d", maxSimilarLaptops))
+	}
+	k := int(req.GetK())
+	if k == 0 {
+		k = defaultSimilarLaptops
+	}
+
+	err := validateSimilarityWeights(req.GetWeights())
+	if err != nil {
+		return nil, logError(err)
+	}
+
+	res := &pb.FindSimilarLaptopsResponse{}
+	err = server.laptopStore.FindSimilar(ctx, laptopID, req.GetWeights(), k, func(laptop *pb.Laptop, distance float64) error {
+		res.Laptops = append(res.Laptops, &pb.SimilarLaptop{Laptop: laptop, Distance: distance})
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, logError(status.Errorf(codes.NotFound, "laptop %s doesn't exist", laptopID))
+		}
+		if err := contextError(ctx); err != nil {
+			return nil, err
+		}
+		return nil, logError(status.Errorf(codes.Internal, "cannot find similar laptops: %v", err))
+	}
+
+	return res, nil
+}
+
+// UpdateLaptop is a unary RPC to update the fields of an existing laptop listed in the update mask
+func (server *LaptopServer) UpdateLaptop(
+	ctx context.Context,
+	req *pb.UpdateLaptopRequest,
+) (*pb.UpdateLaptopResponse, error) {
+	laptopID := req.GetLaptop().GetId()
+	log.Printf("receive an update-laptop request with id: %s, mask: %v", laptopID, req.GetUpdateMask().GetPaths())
+
+	revision, err := expectedRevision(ctx, req.GetLaptop().GetRevision())
+	if err != nil {
+		return nil, logError(status.Errorf(codes.InvalidArgument, "cannot parse revision: %v", err))
+	}
+
+	laptop, err := server.laptopStore.Find(laptopID)
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot find laptop: %v", err))
+	}
+	if laptop == nil {
+		return nil, logError(status.Errorf(codes.NotFound, "laptop %s doesn't exist", laptopID))
+	}
+
+	err = checkLaptopAccess(ctx, laptop, editLaptop)
+	if err != nil {
+		return nil, logError(err)
+	}
+
+	err = applyFieldMask(laptop, req.GetLaptop(), req.GetUpdateMask())
+	if err != nil {
+		return nil, logError(status.Errorf(codes.InvalidArgument, "cannot apply update mask: %v", err))
+	}
+	laptop.UpdateAt = ptypes.TimestampNow()
+	if revision != 0 {
+		laptop.Revision = revision
+	}
+
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	err = server.laptopStore.Update(laptop)
+	if err != nil {
+		code := codes.Internal
+		if errors.Is(err, ErrNotFound) {
+			code = codes.NotFound
+		} else if errors.Is(err, ErrRevisionMismatch) {
+			code = codes.Aborted
+		}
+
+		return nil, logError(status.Errorf(code, "cannot update laptop in the laptopStore: %v", err))
+	}
+
+	log.Printf("updated laptop with id: %s to revision %d", laptopID, laptop.Revision)
+	sendETag(ctx, laptop.Revision)
+
+	res := &pb.UpdateLaptopResponse{
+		Laptop: laptop,
+	}
+	return res, nil
+}
+
+// DeleteLaptop is a unary RPC to delete a laptop by ID
+func (server *LaptopServer) DeleteLaptop(
+	ctx context.Context,
+	req *pb.DeleteLaptopRequest,
+) (*pb.DeleteLaptopResponse, error) {
+	laptopID := req.GetId()
+	log.Printf("receive a delete-laptop request with id: %s", laptopID)
+
+	revision, err := expectedRevision(ctx, req.GetRevision())
+	if err != nil {
+		return nil, logError(status.Errorf(codes.InvalidArgument, "cannot parse revision: %v", err))
+	}
+
+	laptop, err := server.laptopStore.Find(laptopID)
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot find laptop: %v", err))
+	}
+	if laptop == nil {
+		return nil, logError(status.Errorf(codes.NotFound, "laptop %s doesn't exist", laptopID))
+	}
+
+	err = checkLaptopAccess(ctx, laptop, manageLaptop)
+	if err != nil {
+		return nil, logError(err)
+	}
+
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	err = server.laptopStore.Delete(laptopID, revision)
+	if err != nil {
+		code := codes.Internal
+		if errors.Is(err, ErrNotFound) {
+			code = codes.NotFound
+		} else if errors.Is(err, ErrRevisionMismatch) {
+			code = codes.Aborted
+		}
+
+		return nil, logError(status.Errorf(code, "cannot delete laptop from the laptopStore: %v", err))
+	}
+
+	log.Printf("deleted laptop with id: %s", laptopID)
+
+	res := &pb.DeleteLaptopResponse{
+		Id: laptopID,
+	}
+	return res, nil
+}
+
+// ShareLaptop is a unary RPC to share the edit rights of a laptop with other users or to take them back,
+// only the owner of the laptop and the admins can share it
+func (server *LaptopServer) ShareLaptop(
+	ctx context.Context,
+	req *pb.ShareLaptopRequest,
+) (*pb.ShareLaptopResponse, error) {
+	laptopID := req.GetLaptopId()
+	log.Printf("receive a share-laptop request with id: %s, add: %v, remove: %v",
+		laptopID, req.GetAddEditors(), req.GetRemoveEditors())
+
+	revision, err := expectedRevision(ctx, req.GetRevision())
+	if err != nil {
+		return nil, logError(status.Errorf(codes.InvalidArgument, "cannot parse revision: %v", err))
+	}
+
+	laptop, err := server.laptopStore.Find(laptopID)
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot find laptop: %v", err))
+	}
+	if laptop == nil {
+		return nil, logError(status.Errorf(codes.NotFound, "laptop %s doesn't exist", laptopID))
+	}
+
+	err = checkLaptopAccess(ctx, laptop, manageLaptop)
+	if err != nil {
+		return nil, logError(err)
+	}
+
+	editors, err := sharedEditors(laptop, req.GetAddEditors(), req.GetRemoveEditors())
+	if err != nil {
+		return nil, logError(status.Errorf(codes.InvalidArgument, "cannot share laptop: %v", err))
+	}
+	laptop.Editors = editors
+	laptop.UpdateAt = ptypes.TimestampNow()
+	if revision != 0 {
+		laptop.Revision = revision
+	}
+
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	err = server.laptopStore.Update(laptop)
+	if err != nil {
+		code := codes.Internal
+		if errors.Is(err, ErrNotFound) {
+			code = codes.NotFound
+		} else if errors.Is(err, ErrRevisionMismatch) {
+			code = codes.Aborted
+		}
+
+		return nil, logError(status.Errorf(code, "cannot update laptop in the laptopStore: %v", err))
+	}
+
+	log.Printf("shared laptop with id: %s with editors: %v", laptopID, laptop.Editors)
+	sendETag(ctx, laptop.Revision)
+
+	res := &pb.ShareLaptopResponse{
+		Laptop: laptop,
+	}
+	return res, nil
+}
+
 // UploadImage is a client-streaming RPC to upload a laptop image
 func (server *LaptopServer) UploadImage(stream pb.LaptopService_UploadImageServer) error {
 	req, err := stream.Recv()
@@ -137,6 +602,11 @@ func (server *LaptopServer) UploadImage(stream pb.LaptopService_UploadImageServe
 		return logError(status.Errorf(codes.InvalidArgument, "laptop %s doesn't exist", laptopID))
 	}
 
+	err = checkLaptopAccess(stream.Context(), laptop, editLaptop)
+	if err != nil {
+		return logError(err)
+	}
+
 	imageData := bytes.Buffer{}
 	imageSize := 0
 
@@ -196,7 +666,13 @@ func (server *LaptopServer) UploadImage(stream pb.LaptopService_UploadImageServe
 
 // RateLaptop is a bidirectional-streaming RPC that allows client to rate a stream of laptops
 // with a score, and returns a stream of average score for each of them.
+// A user has one score per laptop, rating a laptop again replaces the score.
 func (server *LaptopServer) RateLaptop(stream pb.LaptopService_RateLaptopServer) error {
+	username, err := authenticatedUsername(stream.Context())
+	if err != nil {
+		return err
+	}
+
 	for {
 		err := contextError(stream.Context())
 		if err != nil {
@@ -215,7 +691,7 @@ func (server *LaptopServer) RateLaptop(stream pb.LaptopService_RateLaptopServer)
 		laptopID := req.GetLaptopId()
 		score := req.GetScore()
 
-		log.Printf("receive a rete-laptop request: id = %s, score = %.2f", laptopID, score)
+		log.Printf("receive a rete-laptop request from %s: id = %s, score = %.2f", username, laptopID, score)
 
 		found, err := server.laptopStore.Find(laptopID)
 		if err != nil {
@@ -225,15 +701,19 @@ func (server *LaptopServer) RateLaptop(stream pb.LaptopService_RateLaptopServer)
 			return logError(status.Errorf(codes.Unknown, "laptopID %s is not found", laptopID))
 		}
 
-		rating, err := server.ratingStore.Add(laptopID, score)
+		rating, err := server.ratingStore.Rate(username, laptopID, score)
 		if err != nil {
-			return logError(status.Errorf(codes.Internal, "cannot add rating to the score: %v", err))
+			code := codes.Internal
+			if errors.Is(err, ErrInvalidScore) {
+				code = codes.InvalidArgument
+			}
+			return logError(status.Errorf(code, "cannot rate laptop: %v", err))
 		}
 
 		res := &pb.RateLaptopResponse{
 			LaptopId:     laptopID,
 			RatedCount:   rating.Count,
-			AverageScore: rating.Sum / float64(rating.Count),
+			AverageScore: rating.Average(),
 		}
 
 		err = stream.Send(res)
@@ -245,6 +725,164 @@ func (server *LaptopServer) RateLaptop(stream pb.LaptopService_RateLaptopServer)
 	return nil
 }
 
+// GetLaptopRating is a unary RPC to get the rating of a laptop and the score the caller gave to it
+func (server *LaptopServer) GetLaptopRating(
+	ctx context.Context,
+	req *pb.GetLaptopRatingRequest,
+) (*pb.GetLaptopRatingResponse, error) {
+	laptopID := req.GetLaptopId()
+	log.Printf("receive a get-laptop-rating request with id: %s", laptopID)
+
+	laptop, err := server.laptopStore.Find(laptopID)
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot find laptop: %v", err))
+	}
+	if laptop == nil {
+		return nil, logError(status.Errorf(codes.NotFound, "laptop %s doesn't exist", laptopID))
+	}
+
+	rating, err := server.ratingStore.Find(laptopID)
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot find laptop rating: %v", err))
+	}
+	if rating == nil {
+		rating = &Rating{}
+	}
+
+	res := &pb.GetLaptopRatingResponse{
+		LaptopId:     laptopID,
+		RatedCount:   rating.Count,
+		AverageScore: rating.Average(),
+		Histogram:    rating.Histogram[:],
+	}
+
+	if claims, ok := UserClaimsFromContext(ctx); ok {
+		res.MyScore, _, err = server.ratingStore.FindScore(claims.Username, laptopID)
+		if err != nil {
+			return nil, logError(status.Errorf(codes.Internal, "cannot find score: %v", err))
+		}
+	}
+
+	return res, nil
+}
+
+// TopRatedLaptops is a server-streaming RPC to stream the best rated laptops that pass the filter,
+// from the highest score to the lowest
+func (server *LaptopServer) TopRatedLaptops(
+	req *pb.TopRatedLaptopsRequest,
+	stream pb.LaptopService_TopRatedLaptopsServer,
+) error {
+	log.Printf("receive a top-rated-laptops request with filter: %v, limit: %d", req.GetFilter(), req.GetLimit())
+
+	if err := contextError(stream.Context()); err != nil {
+		return err
+	}
+
+	limit := int(req.GetLimit())
+	if limit == 0 {
+		limit = defaultTopRatedLaptops
+	}
+	if limit > maxTopRatedLaptops {
+		limit = maxTopRatedLaptops
+	}
+
+	// without a rating store no laptop is rated
+	if server.ratingStore == nil {
+		return nil
+	}
+
+	filter := req.GetFilter()
+	if filter == nil {
+		filter = &pb.Filter{MaxPriceUsd: math.Inf(1)}
+	}
+
+	now := time.Now()
+	leaders := &leaderboardHeap{}
+	err := server.laptopStore.Search(stream.Context(), filter, nil, func(laptop *pb.Laptop) error {
+		score, count, err := server.ratingScorer.Score(server.ratingStore, laptop.GetId(), now)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+
+		rating, err := server.ratingStore.Find(laptop.GetId())
+		if err != nil {
+			return err
+		}
+
+		res := &pb.TopRatedLaptopsResponse{
+			Laptop:       laptop,
+			Score:        score,
+			AverageScore: rating.Average(),
+			RatedCount:   rating.Count,
+		}
+		if leaders.Len() < limit {
+			heap.Push(leaders, res)
+		} else if isHigherRated(res, (*leaders)[0]) {
+			(*leaders)[0] = res
+			heap.Fix(leaders, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		if err := contextError(stream.Context()); err != nil {
+			return err
+		}
+		return logError(status.Errorf(codes.Internal, "unexpected error: %v", err))
+	}
+
+	laptops := []*pb.TopRatedLaptopsResponse(*leaders)
+	sort.Slice(laptops, func(i, j int) bool {
+		return isHigherRated(laptops[i], laptops[j])
+	})
+
+	for i, res := range laptops {
+		res.Rank = uint32(i + 1)
+
+		err := stream.Send(res)
+		if err != nil {
+			return logError(status.Errorf(codes.Unknown, "cannot send stream response: %v", err))
+		}
+	}
+
+	return nil
+}
+
+// leaderboardHeap is a min-heap of rated laptops with the lowest ranked one on top,
+// it keeps the leaders of a leaderboard without sorting every rated laptop
+type leaderboardHeap []*pb.TopRatedLaptopsResponse
+
+func (leaders leaderboardHeap) Len() int { return len(leaders) }
+
+func (leaders leaderboardHeap) Less(i, j int) bool { return isHigherRated(leaders[j], leaders[i]) }
+
+func (leaders leaderboardHeap) Swap(i, j int) { leaders[i], leaders[j] = leaders[j], leaders[i] }
+
+func (leaders *leaderboardHeap) Push(x interface{}) {
+	*leaders = append(*leaders, x.(*pb.TopRatedLaptopsResponse))
+}
+
+func (leaders *leaderboardHeap) Pop() interface{} {
+	old := *leaders
+	leader := old[len(old)-1]
+	*leaders = old[:len(old)-1]
+	return leader
+}
+
+// isHigherRated reports whether laptop a ranks before laptop b in the leaderboard,
+// ties go to the laptop with more scores, then to the smaller ID
+func isHigherRated(a, b *pb.TopRatedLaptopsResponse) bool {
+	if a.GetScore() != b.GetScore() {
+		return a.GetScore() > b.GetScore()
+	}
+	if a.GetRatedCount() != b.GetRatedCount() {
+		return a.GetRatedCount() > b.GetRatedCount()
+	}
+	return a.GetLaptop().GetId() < b.GetLaptop().GetId()
+}
+
 func logError(err error) error {
 	if err != nil {
 		log.Print(err)
@@ -6,8 +6,12 @@ import (
 	"github.com/treeforest/grpc-pcbook/pb"
 	"github.com/treeforest/grpc-pcbook/sample"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"math"
 	"testing"
+	"time"
 )
 
 func TestServerCreateLaptop(t *testing.T) {
@@ -28,32 +32,44 @@ func TestServerCreateLaptop(t *testing.T) {
 		name        string
 		laptop      *pb.Laptop
 		laptopStore LaptopStore
+		ctx         context.Context
 		code        codes.Code
 	} {
 		{
 			name:        "success_with_id",
 			laptop:      sample.NewLaptop(),
 			laptopStore: NewInMemoryLaptopStore(),
+			ctx:         newTestClaimsContext("seller1", sellerRole),
 			code:        codes.OK,
 		},
 		{
 			name:        "success_no_id",
 			laptop:      laptopNoID,
 			laptopStore: NewInMemoryLaptopStore(),
+			ctx:         newTestClaimsContext("seller1", sellerRole),
 			code:        codes.OK,
 		},
 		{
 			name:        "failure_invalid_id",
 			laptop:      laptopInvalidID,
 			laptopStore: NewInMemoryLaptopStore(),
+			ctx:         newTestClaimsContext("seller1", sellerRole),
 			code:        codes.InvalidArgument,
 		},
 		{
 			name:        "failure_duplicate_id",
 			laptop:      laptopDuplicateID,
 			laptopStore: storeDuplicateID,
+			ctx:         newTestClaimsContext("seller1", sellerRole),
 			code:        codes.AlreadyExists,
 		},
+		{
+			name:        "failure_unauthenticated",
+			laptop:      sample.NewLaptop(),
+			laptopStore: NewInMemoryLaptopStore(),
+			ctx:         context.Background(),
+			code:        codes.Unauthenticated,
+		},
 	}
 
 	for i := range testCases {
@@ -67,7 +83,7 @@ func TestServerCreateLaptop(t *testing.T) {
 			}
 
 			server := NewLaptopServer(tc.laptopStore, nil, nil)
-			res, err := server.CreateLaptop(context.Background(), req)
+			res, err := server.CreateLaptop(tc.ctx, req)
 			if tc.code == codes.OK {
 				require.NoError(t, err)
 				require.NotNil(t, res)
@@ -75,6 +91,10 @@ func TestServerCreateLaptop(t *testing.T) {
 				if len(tc.laptop.Id) > 0 {
 					require.Equal(t, tc.laptop.Id, res.Id)
 				}
+
+				other, err := tc.laptopStore.Find(res.Id)
+				require.NoError(t, err)
+				require.Equal(t, "seller1", other.GetOwner())
 			} else {
 				require.Error(t, err)
 				require.Nil(t, res)
@@ -84,4 +104,722 @@ func TestServerCreateLaptop(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestServerGetLaptop(t *testing.T) {
+	t.Parallel()
+
+	laptop := sample.NewLaptop()
+	laptopStore := NewInMemoryLaptopStore()
+	err := laptopStore.Save(laptop)
+	require.NoError(t, err)
+
+	server := NewLaptopServer(laptopStore, nil, nil)
+
+	res, err := server.GetLaptop(context.Background(), &pb.GetLaptopRequest{Id: laptop.Id})
+	require.NoError(t, err)
+	requireSameLaptop(t, laptop, res.GetLaptop())
+
+	res, err = server.GetLaptop(context.Background(), &pb.GetLaptopRequest{Id: sample.NewLaptop().Id})
+	require.Nil(t, res)
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServerUpdateLaptop(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct{
+		name     string
+		paths    []string
+		revision uint64
+		ifMatch  string
+		code     codes.Code
+	} {
+		{
+			name:  "success_price",
+			paths: []string{"price_usd"},
+			code:  codes.OK,
+		},
+		{
+			name:  "success_nested_field",
+			paths: []string{"cpu.min_ghz", "weight_lb"},
+			code:  codes.OK,
+		},
+		{
+			name:  "success_empty_mask",
+			paths: nil,
+			code:  codes.OK,
+		},
+		{
+			name:     "success_current_revision",
+			paths:    []string{"price_usd"},
+			revision: 1,
+			code:     codes.OK,
+		},
+		{
+			name:    "success_if_match",
+			paths:   []string{"price_usd"},
+			ifMatch: `"1"`,
+			code:    codes.OK,
+		},
+		{
+			name:     "failure_stale_revision",
+			paths:    []string{"price_usd"},
+			revision: 2,
+			code:     codes.Aborted,
+		},
+		{
+			name:    "failure_stale_if_match",
+			paths:   []string{"price_usd"},
+			ifMatch: `"2"`,
+			code:    codes.Aborted,
+		},
+		{
+			name:  "failure_unknown_field",
+			paths: []string{"color"},
+			code:  codes.InvalidArgument,
+		},
+		{
+			name:  "failure_id_field",
+			paths: []string{"id"},
+			code:  codes.InvalidArgument,
+		},
+		{
+			name:  "failure_owner_field",
+			paths: []string{"owner"},
+			code:  codes.InvalidArgument,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			laptop := sample.NewLaptop()
+			laptop.Owner = "seller1"
+			laptopStore := NewInMemoryLaptopStore()
+			err := laptopStore.Save(laptop)
+			require.NoError(t, err)
+
+			update := &pb.Laptop{
+				Id:       laptop.Id,
+				Owner:    "seller2",
+				PriceUsd: 999,
+				Cpu:      &pb.CPU{MinGhz: 1.1},
+				Weight:   &pb.Laptop_WeightLb{WeightLb: 4.4},
+				Revision: tc.revision,
+			}
+			req := &pb.UpdateLaptopRequest{
+				Laptop:     update,
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: tc.paths},
+			}
+
+			ctx := newTestClaimsContext("seller1", sellerRole)
+			if len(tc.ifMatch) > 0 {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(IfMatchHeader, tc.ifMatch))
+			}
+
+			server := NewLaptopServer(laptopStore, nil, nil)
+			res, err := server.UpdateLaptop(ctx, req)
+			if tc.code != codes.OK {
+				require.Nil(t, res)
+				require.Equal(t, tc.code, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+
+			other, err := laptopStore.Find(laptop.Id)
+			require.NoError(t, err)
+			require.Equal(t, laptop.Id, other.Id)
+			require.Equal(t, "seller1", other.GetOwner())
+			require.Equal(t, uint64(2), other.Revision)
+			require.Equal(t, other.Revision, res.GetLaptop().GetRevision())
+
+			switch tc.name {
+			case "success_price", "success_current_revision", "success_if_match":
+				require.Equal(t, 999.0, other.GetPriceUsd())
+				require.Equal(t, laptop.GetName(), other.GetName())
+				require.Equal(t, laptop.GetCpu().GetMinGhz(), other.GetCpu().GetMinGhz())
+			case "success_nested_field":
+				require.Equal(t, 1.1, other.GetCpu().GetMinGhz())
+				require.Equal(t, laptop.GetCpu().GetName(), other.GetCpu().GetName())
+				require.Equal(t, 4.4, other.GetWeightLb())
+				require.Equal(t, laptop.GetPriceUsd(), other.GetPriceUsd())
+			case "success_empty_mask":
+				require.Equal(t, 999.0, other.GetPriceUsd())
+				require.Empty(t, other.GetName())
+				require.Equal(t, 4.4, other.GetWeightLb())
+			}
+		})
+	}
+}
+
+func TestServerDeleteLaptop(t *testing.T) {
+	t.Parallel()
+
+	laptop := sample.NewLaptop()
+	laptop.Owner = "seller1"
+	laptopStore := NewInMemoryLaptopStore()
+	err := laptopStore.Save(laptop)
+	require.NoError(t, err)
+
+	server := NewLaptopServer(laptopStore, nil, nil)
+	ctx := newTestClaimsContext("seller1", sellerRole)
+
+	res, err := server.DeleteLaptop(ctx, &pb.DeleteLaptopRequest{Id: laptop.Id, Revision: 2})
+	require.Nil(t, res)
+	require.Equal(t, codes.Aborted, status.Code(err))
+
+	res, err = server.DeleteLaptop(ctx, &pb.DeleteLaptopRequest{Id: laptop.Id, Revision: 1})
+	require.NoError(t, err)
+	require.Equal(t, laptop.Id, res.GetId())
+
+	other, err := laptopStore.Find(laptop.Id)
+	require.NoError(t, err)
+	require.Nil(t, other)
+
+	res, err = server.DeleteLaptop(ctx, &pb.DeleteLaptopRequest{Id: laptop.Id})
+	require.Nil(t, res)
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServerLaptopAccess(t *testing.T) {
+	t.Parallel()
+
+	owner := newTestClaimsContext("seller1", sellerRole)
+	editor := newTestClaimsContext("editor1", userRole)
+	admin := newTestClaimsContext("admin1", adminRole)
+	otherSeller := newTestClaimsContext("seller2", sellerRole)
+	anonymous := context.Background()
+
+	testCases := []struct {
+		name      string
+		ctx       context.Context
+		noOwner   bool
+		update    codes.Code
+		share     codes.Code
+		deleteRes codes.Code
+	}{
+		{"owner", owner, false, codes.OK, codes.OK, codes.OK},
+		{"editor", editor, false, codes.OK, codes.PermissionDenied, codes.PermissionDenied},
+		{"admin", admin, false, codes.OK, codes.OK, codes.OK},
+		{"other_seller", otherSeller, false, codes.PermissionDenied, codes.PermissionDenied, codes.PermissionDenied},
+		{"anonymous", anonymous, false, codes.Unauthenticated, codes.Unauthenticated, codes.Unauthenticated},
+		{"no_owner", owner, true, codes.PermissionDenied, codes.PermissionDenied, codes.PermissionDenied},
+		{"no_owner_admin", admin, true, codes.OK, codes.OK, codes.OK},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			laptop := sample.NewLaptop()
+			if !tc.noOwner {
+				laptop.Owner = "seller1"
+				laptop.Editors = []string{"editor1"}
+			}
+			laptopStore := NewInMemoryLaptopStore()
+			require.NoError(t, laptopStore.Save(laptop))
+
+			server := NewLaptopServer(laptopStore, nil, nil)
+
+			update := &pb.UpdateLaptopRequest{
+				Laptop:     &pb.Laptop{Id: laptop.Id, PriceUsd: 999},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"price_usd"}},
+			}
+			_, err := server.UpdateLaptop(tc.ctx, update)
+			require.Equal(t, tc.update, status.Code(err))
+
+			share := &pb.ShareLaptopRequest{LaptopId: laptop.Id, AddEditors: []string{"editor2"}}
+			_, err = server.ShareLaptop(tc.ctx, share)
+			require.Equal(t, tc.share, status.Code(err))
+
+			_, err = server.DeleteLaptop(tc.ctx, &pb.DeleteLaptopRequest{Id: laptop.Id})
+			require.Equal(t, tc.deleteRes, status.Code(err))
+			if tc.deleteRes == codes.PermissionDenied {
+				// the denial tells why
+				require.NotEmpty(t, status.Convert(err).Message())
+			}
+		})
+	}
+}
+
+func TestServerShareLaptop(t *testing.T) {
+	t.Parallel()
+
+	laptop := sample.NewLaptop()
+	laptop.Owner = "seller1"
+	laptopStore := NewInMemoryLaptopStore()
+	err := laptopStore.Save(laptop)
+	require.NoError(t, err)
+
+	server := NewLaptopServer(laptopStore, nil, nil)
+	owner := newTestClaimsContext("seller1", sellerRole)
+	editor := newTestClaimsContext("editor1", userRole)
+
+	share := func(add []string, remove []string) ([]string, error) {
+		res, err := server.ShareLaptop(owner, &pb.ShareLaptopRequest{
+			LaptopId:      laptop.Id,
+			AddEditors:    add,
+			RemoveEditors: remove,
+		})
+		return res.GetLaptop().GetEditors(), err
+	}
+
+	editors, err := share([]string{"editor2", "editor1", "editor2"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"editor1", "editor2"}, editors)
+
+	// an editor can update the laptop, but an empty update mask keeps the owner and the editors
+	res, err := server.UpdateLaptop(editor, &pb.UpdateLaptopRequest{
+		Laptop: &pb.Laptop{Id: laptop.Id, PriceUsd: 999, Owner: "editor1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "seller1", res.GetLaptop().GetOwner())
+	require.Equal(t, []string{"editor1", "editor2"}, res.GetLaptop().GetEditors())
+
+	editors, err = share(nil, []string{"editor1", "unknown"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"editor2"}, editors)
+
+	_, err = server.UpdateLaptop(editor, &pb.UpdateLaptopRequest{
+		Laptop: &pb.Laptop{Id: laptop.Id, PriceUsd: 888},
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = share([]string{"seller1"}, nil)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = share([]string{""}, nil)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = server.ShareLaptop(owner, &pb.ShareLaptopRequest{LaptopId: sample.NewLaptop().Id})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// newTestClaimsContext returns the context of a call that the AuthInterceptor authorized for the user
+func newTestClaimsContext(username string, role string) context.Context {
+	return contextWithUserClaims(context.Background(), &UserClaims{Username: username, Role: role})
+}
+
+func TestServerCompareLaptops(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	ratingStore := NewInMemoryRatingStore()
+
+	laptop1 := sample.NewLaptop()
+	laptop1.Cpu.NumberCores = 8
+	laptop1.Ram = &pb.Memory{Value: 16, Unit: pb.Memory_GIGABYTE}
+	laptop1.Weight = &pb.Laptop_WeightKg{WeightKg: 2}
+	laptop1.PriceUsd = 2000
+	require.NoError(t, laptopStore.Save(laptop1))
+
+	laptop2 := sample.NewLaptop()
+	laptop2.Cpu.NumberCores = 8
+	laptop2.Ram = &pb.Memory{Value: 32768, Unit: pb.Memory_MEGABYTE}
+	laptop2.Weight = &pb.Laptop_WeightLb{WeightLb: 4}
+	laptop2.PriceUsd = 1500
+	require.NoError(t, laptopStore.Save(laptop2))
+
+	laptop3 := sample.NewLaptop()
+	laptop3.Cpu.NumberCores = 4
+	laptop3.Ram = &pb.Memory{Value: 8, Unit: pb.Memory_GIGABYTE}
+	laptop3.Weight = nil
+	laptop3.PriceUsd = 1500
+	require.NoError(t, laptopStore.Save(laptop3))
+
+	_, err := ratingStore.Rate("user1", laptop3.Id, 9)
+	require.NoError(t, err)
+
+	server := NewLaptopServer(laptopStore, nil, ratingStore)
+
+	req := &pb.CompareLaptopsRequest{Ids: []string{laptop1.Id, laptop2.Id, laptop3.Id}}
+	res, err := server.CompareLaptops(context.Background(), req)
+	require.NoError(t, err)
+
+	comparison := res.GetComparison()
+	require.Equal(t, req.Ids, comparison.GetLaptopIds())
+
+	specs := make(map[string]*pb.SpecComparison)
+	for _, spec := range comparison.GetSpecs() {
+		specs[spec.GetSpec()] = spec
+	}
+
+	cores := specs["cpu.number_cores"]
+	require.Equal(t, []float64{8, 8, 4}, cores.GetValues())
+	require.Equal(t, []string{laptop1.Id, laptop2.Id}, cores.GetWinnerIds())
+
+	ram := specs["ram"]
+	require.Equal(t, "GB", ram.GetUnit())
+	require.Equal(t, []float64{16, 32, 8}, ram.GetValues())
+	require.Equal(t, []string{laptop2.Id}, ram.GetWinnerIds())
+
+	weight := specs["weight"]
+	require.Equal(t, pb.SpecComparison_LOWER, weight.GetBetter())
+	require.InDelta(t, 4*kgPerLb, weight.GetValues()[1], 1e-9)
+	require.True(t, math.IsNaN(weight.GetValues()[2]))
+	require.Equal(t, []string{"2", "1.81", ""}, weight.GetDisplayValues())
+	require.Equal(t, []string{laptop2.Id}, weight.GetWinnerIds())
+
+	price := specs["price_usd"]
+	require.Equal(t, []string{laptop2.Id, laptop3.Id}, price.GetWinnerIds())
+
+	rating := specs["rating"]
+	require.Equal(t, []string{laptop3.Id}, rating.GetWinnerIds())
+
+	brand := specs["brand"]
+	require.Empty(t, brand.GetValues())
+	require.Empty(t, brand.GetWinnerIds())
+	require.Equal(t, laptop1.Brand, brand.GetDisplayValues()[0])
+
+	testCases := []struct {
+		name string
+		ids  []string
+		code codes.Code
+	}{
+		{"one_laptop", []string{laptop1.Id}, codes.InvalidArgument},
+		{"too_many_laptops", make([]string, maxComparedLaptops+1), codes.InvalidArgument},
+		{"duplicate_laptop", []string{laptop1.Id, laptop1.Id}, codes.InvalidArgument},
+		{"unknown_laptop", []string{laptop1.Id, "unknown"}, codes.NotFound},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := server.CompareLaptops(context.Background(), &pb.CompareLaptopsRequest{Ids: tc.ids})
+			require.Nil(t, res)
+			require.Equal(t, tc.code, status.Code(err))
+		})
+	}
+}
+
+func TestServerFindSimilarLaptops(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+	for i := 0; i < 20; i++ {
+		require.NoError(t, laptopStore.Save(sample.NewLaptop()))
+	}
+
+	server := NewLaptopServer(laptopStore, nil, nil)
+
+	testCases := []struct {
+		name    string
+		id      string
+		k       uint32
+		weights *pb.SimilarityWeights
+		count   int
+		code    codes.Code
+	}{
+		{"default_k", laptop.Id, 0, nil, defaultSimilarLaptops, codes.OK},
+		{"k", laptop.Id, 3, &pb.SimilarityWeights{Price: 2, Ram: 1}, 3, codes.OK},
+		{"k_above_count", laptop.Id, maxSimilarLaptops, nil, 20, codes.OK},
+		{"k_too_large", laptop.Id, maxSimilarLaptops + 1, nil, 0, codes.InvalidArgument},
+		{"k_negative_as_int", laptop.Id, math.MaxUint32, nil, 0, codes.InvalidArgument},
+		{"negative_weight", laptop.Id, 3, &pb.SimilarityWeights{Price: -1}, 0, codes.InvalidArgument},
+		{"zero_weights", laptop.Id, 3, &pb.SimilarityWeights{}, 0, codes.InvalidArgument},
+		{"unknown_laptop", sample.NewLaptop().Id, 3, nil, 0, codes.NotFound},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := &pb.FindSimilarLaptopsRequest{Id: tc.id, K: tc.k, Weights: tc.weights}
+			res, err := server.FindSimilarLaptops(context.Background(), req)
+			if tc.code != codes.OK {
+				require.Nil(t, res)
+				require.Equal(t, tc.code, status.Code(err))
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, res.GetLaptops(), tc.count)
+			for i, similar := range res.GetLaptops() {
+				require.NotEqual(t, laptop.Id, similar.GetLaptop().GetId())
+				if i > 0 {
+					require.LessOrEqual(t, res.GetLaptops()[i-1].GetDistance(), similar.GetDistance())
+				}
+			}
+		})
+	}
+}
+
+func TestServerListLaptops(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	ratingStore := NewInMemoryRatingStore()
+	for i := 0; i < 10; i++ {
+		laptop := sample.NewLaptop()
+		laptop.PriceUsd = float64(1000 + i%5*100)
+		require.NoError(t, laptopStore.Save(laptop))
+
+		_, err := ratingStore.Rate("user1", laptop.Id, float64(i+1))
+		require.NoError(t, err)
+	}
+
+	server := NewLaptopServer(laptopStore, nil, ratingStore)
+	filter := &pb.Filter{MaxPriceUsd: 5000}
+
+	listAll := func(orderBy string, pageSize int32, insert bool) []*pb.Laptop {
+		var laptops []*pb.Laptop
+		pageToken := ""
+
+		for {
+			req := &pb.ListLaptopsRequest{
+				Filter:    filter,
+				OrderBy:   orderBy,
+				PageSize:  pageSize,
+				PageToken: pageToken,
+			}
+
+			res, err := server.ListLaptops(context.Background(), req)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(res.GetLaptops()), int(pageSize))
+			laptops = append(laptops, res.GetLaptops()...)
+
+			if insert {
+				// laptops saved between pages go before or after the cursor, never shift it
+				laptop := sample.NewLaptop()
+				laptop.PriceUsd = 500
+				require.NoError(t, laptopStore.Save(laptop))
+			}
+
+			pageToken = res.GetNextPageToken()
+			if pageToken == "" {
+				return laptops
+			}
+		}
+	}
+
+	laptops := listAll("price_usd", 3, true)
+	require.Len(t, laptops, 10)
+	seen := make(map[string]bool)
+	for i, laptop := range laptops {
+		require.False(t, seen[laptop.Id])
+		seen[laptop.Id] = true
+
+		if i > 0 {
+			prev := laptops[i-1]
+			require.True(t, prev.PriceUsd < laptop.PriceUsd ||
+				prev.PriceUsd == laptop.PriceUsd && prev.Id < laptop.Id)
+		}
+	}
+
+	laptops = listAll("rating desc", 4, false)
+	for i := 1; i < len(laptops); i++ {
+		prev, err := ratingStore.Find(laptops[i-1].Id)
+		require.NoError(t, err)
+		next, err := ratingStore.Find(laptops[i].Id)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, prev.Average(), next.Average())
+	}
+
+	res, err := server.ListLaptops(context.Background(), &pb.ListLaptopsRequest{Filter: filter, OrderBy: "color"})
+	require.Nil(t, res)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	res, err = server.ListLaptops(context.Background(), &pb.ListLaptopsRequest{Filter: filter, PageSize: 1})
+	require.NoError(t, err)
+	require.NotEmpty(t, res.GetNextPageToken())
+
+	req := &pb.ListLaptopsRequest{Filter: filter, OrderBy: "ram", PageSize: 1, PageToken: res.GetNextPageToken()}
+	res, err = server.ListLaptops(context.Background(), req)
+	require.Nil(t, res)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServerListLaptopsInfinitePrice(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	for i := 0; i < 2; i++ {
+		laptop := sample.NewLaptop()
+		laptop.PriceUsd = math.Inf(1)
+		require.NoError(t, laptopStore.Save(laptop))
+	}
+
+	// a page token cannot hold the price, so the page fails instead of ending the listing
+	server := NewLaptopServer(laptopStore, nil, nil)
+	req := &pb.ListLaptopsRequest{Filter: &pb.Filter{MaxPriceUsd: math.Inf(1)}, OrderBy: "price_usd", PageSize: 1}
+	res, err := server.ListLaptops(context.Background(), req)
+	require.Nil(t, res)
+	require.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestServerListLaptopsDecayingRating(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	ratingStore := NewInMemoryRatingStore()
+	for i := 0; i < 10; i++ {
+		laptop := sample.NewLaptop()
+		require.NoError(t, laptopStore.Save(laptop))
+
+		_, err := ratingStore.Rate("user1", laptop.Id, float64(i+1))
+		require.NoError(t, err)
+	}
+
+	server := NewLaptopServer(laptopStore, nil, ratingStore)
+	halfLife := 10 * time.Millisecond
+	require.NoError(t, server.SetRatingScorer(RatingScorer{PriorMean: 5, PriorWeight: 1, HalfLife: halfLife}))
+	filter := &pb.Filter{MaxPriceUsd: 5000}
+
+	// the ratings move towards the prior mean between pages, but the pages score them when the first one did
+	seen := make(map[string]bool)
+	pageToken := ""
+	for {
+		req := &pb.ListLaptopsRequest{Filter: filter, OrderBy: "rating desc", PageSize: 3, PageToken: pageToken}
+		res, err := server.ListLaptops(context.Background(), req)
+		require.NoError(t, err)
+
+		for _, laptop := range res.GetLaptops() {
+			require.False(t, seen[laptop.Id])
+			seen[laptop.Id] = true
+		}
+
+		pageToken = res.GetNextPageToken()
+		if pageToken == "" {
+			break
+		}
+		time.Sleep(2 * halfLife)
+	}
+	require.Len(t, seen, 10)
+}
+
+func TestServerListLaptopsQuery(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	for i := 0; i < 6; i++ {
+		laptop := sample.NewLaptop()
+		laptop.Name = "Legion"
+		laptop.Cpu.Name = "Core i5-1050F"
+		if i%2 == 0 {
+			laptop.Cpu.Name = "Ryzen 7 PRO 2700U"
+		}
+		if i == 0 {
+			laptop.Name = "Ryzen Edition"
+		}
+		laptop.PriceUsd = float64(1000 + i*100)
+		require.NoError(t, laptopStore.Save(laptop))
+	}
+
+	server := NewLaptopServer(laptopStore, nil, nil)
+	filter := &pb.Filter{MaxPriceUsd: 5000}
+
+	req := &pb.ListLaptopsRequest{Filter: filter, Query: "ryzen", PageSize: 2}
+	res, err := server.ListLaptops(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, res.GetLaptops(), 2)
+	require.Equal(t, "Ryzen Edition", res.GetLaptops()[0].GetName())
+	require.NotEmpty(t, res.GetNextPageToken())
+
+	req.PageToken = res.GetNextPageToken()
+	res, err = server.ListLaptops(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, res.GetLaptops(), 1)
+	require.Empty(t, res.GetNextPageToken())
+
+	req.Query = "ryzen 7"
+	res, err = server.ListLaptops(context.Background(), req)
+	require.Nil(t, res)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	req = &pb.ListLaptopsRequest{Filter: filter, Query: "ryz", OrderBy: "price_usd desc"}
+	res, err = server.ListLaptops(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, res.GetLaptops(), 3)
+	require.Equal(t, 1400.0, res.GetLaptops()[0].GetPriceUsd())
+
+	req = &pb.ListLaptopsRequest{Filter: filter, OrderBy: "relevance"}
+	res, err = server.ListLaptops(context.Background(), req)
+	require.Nil(t, res)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// a query without a word to search lists the laptops as an empty one
+	req = &pb.ListLaptopsRequest{Filter: filter, Query: " - ", PageSize: 10}
+	res, err = server.ListLaptops(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, res.GetLaptops(), 6)
+
+	req = &pb.ListLaptopsRequest{Filter: filter, Query: "   ", OrderBy: "relevance"}
+	res, err = server.ListLaptops(context.Background(), req)
+	require.Nil(t, res)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServerListLaptopsFilterExpr(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	for i := 0; i < 5; i++ {
+		laptop := sample.NewLaptop()
+		laptop.Cpu.NumberCores = uint32(2 + i*2)
+		require.NoError(t, laptopStore.Save(laptop))
+	}
+
+	server := NewLaptopServer(laptopStore, nil, nil)
+
+	req := &pb.ListLaptopsRequest{FilterExpr: "cpu.number_cores >= 6", OrderBy: "cpu.min_ghz"}
+	res, err := server.ListLaptops(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, res.GetLaptops(), 3)
+
+	req = &pb.ListLaptopsRequest{FilterExpr: "cpu.number_cores >= 6", PageSize: 2}
+	res, err = server.ListLaptops(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, res.GetLaptops(), 2)
+
+	req.FilterExpr = "cpu.number_cores >= 4"
+	req.PageToken = res.GetNextPageToken()
+	res, err = server.ListLaptops(context.Background(), req)
+	require.Nil(t, res)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	req = &pb.ListLaptopsRequest{FilterExpr: "cpu.number_cores >= 6GB"}
+	res, err = server.ListLaptops(context.Background(), req)
+	require.Nil(t, res)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Contains(t, status.Convert(err).Message(), "position 21")
+}
+
+func TestServerGetSearchFacets(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	for i := 0; i < 5; i++ {
+		laptop := sample.NewLaptop()
+		laptop.PriceUsd = float64(1000 + i*500)
+		require.NoError(t, laptopStore.Save(laptop))
+	}
+
+	server := NewLaptopServer(laptopStore, nil, nil)
+
+	req := &pb.GetSearchFacetsRequest{Filter: &pb.Filter{MaxPriceUsd: 2000}}
+	res, err := server.GetSearchFacets(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), res.GetFacets().GetTotal())
+	require.Equal(t, 1500.0, res.GetFacets().GetPrice().GetAvgUsd())
+
+	req = &pb.GetSearchFacetsRequest{FilterExpr: "price_usd >= 2000"}
+	res, err = server.GetSearchFacets(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), res.GetFacets().GetTotal())
+	require.Equal(t, 3000.0, res.GetFacets().GetPrice().GetMaxUsd())
+
+	req = &pb.GetSearchFacetsRequest{FilterExpr: "price_usd >="}
+	res, err = server.GetSearchFacets(context.Background(), req)
+	require.Nil(t, res)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -4,39 +4,84 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/jinzhu/copier"
+	"github.com/golang/protobuf/proto"
 	"github.com/treeforest/grpc-pcbook/pb"
 	"log"
+	"sort"
+	"strings"
 	"sync"
 )
 
 // ErrAlreadyExists is returned when a record with the same ID already exists in the laptopStore
 var ErrAlreadyExists = errors.New("record already exists")
 
+// ErrNotFound is returned when no record with the given ID exists in the laptopStore
+var ErrNotFound = errors.New("record not found")
+
+// ErrRevisionMismatch is returned when a write expects a revision other than the stored one
+var ErrRevisionMismatch = errors.New("record revision mismatch")
+
 // LaptopStore is an interface to laptopStore laptop
 type LaptopStore interface {
-	// Save saves the laptop to the laptopStore
+	// Save saves the laptop to the laptopStore and assigns its first revision
 	Save(laptop *pb.Laptop) error
 	// Find finds a laptop by ID
 	Find(id string) (*pb.Laptop, error)
-	// Search searches for laptop with filter, returns onw by one via the found function
-	Search(ctx context.Context, filter *pb.Filter, found func(laptop *pb.Laptop) error) error
+	// Update replaces an existing laptop in the laptopStore if laptop.Revision is zero or
+	// matches the stored revision, then assigns the next revision to the laptop
+	Update(laptop *pb.Laptop) error
+	// Delete deletes a laptop by ID if revision is zero or matches the stored revision
+	Delete(id string, revision uint64) error
+	// Search searches for laptop with filter and the filter expression if it is not nil,
+	// returns onw by one via the found function
+	Search(ctx context.Context, filter *pb.Filter, expr *FilterExpr, found func(laptop *pb.Laptop) error) error
+	// SearchText searches for laptops with filter and the filter expression whose brand, name, CPU or GPU
+	// names match every word of the query, returns them by decreasing relevance score via the found function
+	SearchText(
+		ctx context.Context,
+		query string,
+		filter *pb.Filter,
+		expr *FilterExpr,
+		found func(laptop *pb.Laptop, score float64) error,
+	) error
+	// Facets counts the laptops that Search, or SearchText if the query has a word to search, would return
+	// by brand, CPU brand, GPU brand, RAM size, storage driver, screen panel and release year
+	Facets(ctx context.Context, query string, filter *pb.Filter, expr *FilterExpr) (*pb.SearchFacets, error)
+	// FindSimilar returns the k laptops nearest to the laptop with the given ID via the found function,
+	// by increasing weighted distance over their normalized specs. A nil weights gives the same weight
+	// to every spec. It returns ErrNotFound if the laptop doesn't exist.
+	FindSimilar(
+		ctx context.Context,
+		id string,
+		weights *pb.SimilarityWeights,
+		k int,
+		found func(laptop *pb.Laptop, distance float64) error,
+	) error
+	// Watch calls fn with each change after the sequence number until the context is done or fn fails.
+	// A sequence number of zero only watches the changes to come. It returns ErrUnknownSequence if
+	// the changes after the sequence number are no longer kept.
+	Watch(ctx context.Context, after uint64, fn func(change *LaptopChange) error) error
 }
 
 // InMemoryLaptopStore stores laptop in memory
 type InMemoryLaptopStore struct {
-	mutex sync.RWMutex
-	data  map[string]*pb.Laptop
+	mutex   sync.RWMutex
+	data    map[string]*pb.Laptop
+	indexes *laptopIndexes
+	changes *laptopChangeLog
 }
 
-// NewInMemoryLaptopStore returns a new InMemoryLaptopStore
+// NewInMemoryLaptopStore returns a new InMemoryLaptopStore, its sequence numbers start from zero
+// as the laptops don't outlive the process
 func NewInMemoryLaptopStore() *InMemoryLaptopStore {
 	return &InMemoryLaptopStore{
-		data: make(map[string]*pb.Laptop),
+		data:    make(map[string]*pb.Laptop),
+		indexes: newLaptopIndexes(),
+		changes: newLaptopChangeLog(defaultChangeLogSize, 0),
 	}
 }
 
-// Save saves the laptop to the laptopStore
+// Save saves the laptop to the laptopStore and assigns its first revision
 func (store *InMemoryLaptopStore) Save(laptop *pb.Laptop) error {
 	store.mutex.Lock()
 	defer store.mutex.Unlock()
@@ -50,10 +95,13 @@ func (store *InMemoryLaptopStore) Save(laptop *pb.Laptop) error {
 		return err
 	}
 
-	store.data[other.Id] = other
+	other.Revision = 1
+	store.set(other)
+	laptop.Revision = other.Revision
 	return nil
 }
 
+// Find finds a laptop by ID
 func (store *InMemoryLaptopStore) Find(id string) (*pb.Laptop, error) {
 	store.mutex.RLock()
 	defer store.mutex.RUnlock()
@@ -66,46 +114,309 @@ func (store *InMemoryLaptopStore) Find(id string) (*pb.Laptop, error) {
 	return deepCopy(laptop)
 }
 
-// Search searches for laptop with filter, returns onw by one via the found function
+// Update replaces an existing laptop in the laptopStore if laptop.Revision is zero or
+// matches the stored revision, then assigns the next revision to the laptop
+func (store *InMemoryLaptopStore) Update(laptop *pb.Laptop) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	stored := store.data[laptop.Id]
+	if stored == nil {
+		return ErrNotFound
+	}
+	if laptop.Revision != 0 && laptop.Revision != stored.Revision {
+		return ErrRevisionMismatch
+	}
+
+	other, err := deepCopy(laptop)
+	if err != nil {
+		return err
+	}
+
+	other.Revision = stored.Revision + 1
+	store.set(other)
+	laptop.Revision = other.Revision
+	return nil
+}
+
+// Delete deletes a laptop by ID if revision is zero or matches the stored revision
+func (store *InMemoryLaptopStore) Delete(id string, revision uint64) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	stored := store.data[id]
+	if stored == nil {
+		return ErrNotFound
+	}
+	if revision != 0 && revision != stored.Revision {
+		return ErrRevisionMismatch
+	}
+
+	store.unset(id)
+	return nil
+}
+
+// revision returns the revision of the laptop with the given ID and whether it exists
+func (store *InMemoryLaptopStore) revision(id string) (uint64, bool) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	laptop := store.data[id]
+	if laptop == nil {
+		return 0, false
+	}
+
+	return laptop.Revision, true
+}
+
+// put stores a copy of the laptop as is, replacing the laptop with the same ID
+func (store *InMemoryLaptopStore) put(laptop *pb.Laptop) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	other, err := deepCopy(laptop)
+	if err != nil {
+		return err
+	}
+
+	store.set(other)
+	return nil
+}
+
+// remove removes the laptop with the given ID if there is one
+func (store *InMemoryLaptopStore) remove(id string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.unset(id)
+}
+
+// set stores the laptop and updates the indexes, the caller must hold the write lock
+func (store *InMemoryLaptopStore) set(laptop *pb.Laptop) {
+	old := store.data[laptop.Id]
+	if old != nil {
+		store.indexes.remove(old)
+	}
+
+	store.data[laptop.Id] = laptop
+	store.indexes.add(laptop)
+
+	if old == nil {
+		store.changes.append(pb.LaptopEvent_CREATED, laptop, nil)
+	} else {
+		store.changes.append(pb.LaptopEvent_UPDATED, laptop, old)
+	}
+}
+
+// unset removes the laptop from the store and the indexes, the caller must hold the write lock
+func (store *InMemoryLaptopStore) unset(id string) {
+	if old := store.data[id]; old != nil {
+		store.indexes.remove(old)
+		delete(store.data, id)
+		store.changes.append(pb.LaptopEvent_DELETED, old, nil)
+	}
+}
+
+// Search searches for laptop with filter and the filter expression if it is not nil,
+// returns onw by one via the found function
 func (store *InMemoryLaptopStore) Search(
 	ctx context.Context,
 	filter *pb.Filter,
+	expr *FilterExpr,
 	found func(laptop *pb.Laptop) error,
 	) error {
 	store.mutex.RLock()
 	defer store.mutex.RUnlock()
 
-	for _, laptop := range store.data {
+	var err error
+	store.candidates(filter, func(laptop *pb.Laptop) bool {
 		// heavy processing
 		//time.Sleep(time.Second)
-		//log.Print("checking laptop id: ", laptop.GetId())
+		//log.Print("checking laptop id: ", laptop.Id)
+
+		if ctx.Err() == context.Canceled || ctx.Err() == context.DeadlineExceeded {
+			log.Print("context is canceled")
+			err = errors.New("context is canceled")
+			return false
+		}
+
+		if !isQualified(filter, laptop) || !expr.Match(laptop) {
+			return true
+		}
+
+		other, copyErr := deepCopy(laptop)
+		if copyErr != nil {
+			err = copyErr
+			return false
+		}
+
+		err = found(other)
+		return err == nil
+	})
+
+	return err
+}
+
+// candidates calls fn with every laptop that may pass the filter until fn returns false, from the indexes
+// or from every laptop if no index is selective enough, the caller must hold the lock
+func (store *InMemoryLaptopStore) candidates(filter *pb.Filter, fn func(laptop *pb.Laptop) bool) {
+	indexed := store.indexes.candidates(filter, func(id string) bool {
+		return fn(store.data[id])
+	})
+	if indexed {
+		return
+	}
+
+	for _, laptop := range store.data {
+		if !fn(laptop) {
+			return
+		}
+	}
+}
 
+// SearchText searches for laptops with filter and the filter expression whose brand, name, CPU or GPU
+// names match every word of the query, returns them by decreasing relevance score via the found function
+func (store *InMemoryLaptopStore) SearchText(
+	ctx context.Context,
+	query string,
+	filter *pb.Filter,
+	expr *FilterExpr,
+	found func(laptop *pb.Laptop, score float64) error,
+) error {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	scores := store.indexes.text.search(query)
+
+	var laptops []*pb.Laptop
+	for id := range scores {
 		if ctx.Err() == context.Canceled || ctx.Err() == context.DeadlineExceeded {
 			log.Print("context is canceled")
 			return errors.New("context is canceled")
 		}
 
-		if isQualified(filter, laptop) {
-			other, err := deepCopy(laptop)
-			if err != nil {
-				return err
-			}
+		laptop := store.data[id]
+		if isQualified(filter, laptop) && expr.Match(laptop) {
+			laptops = append(laptops, laptop)
+		}
+	}
 
-			err = found(other)
-			if err != nil {
-				return err
+	sort.Slice(laptops, func(i, j int) bool {
+		a, b := scores[laptops[i].Id], scores[laptops[j].Id]
+		if a != b {
+			return a > b
+		}
+		return laptops[i].Id < laptops[j].Id
+	})
+
+	for _, laptop := range laptops {
+		other, err := deepCopy(laptop)
+		if err != nil {
+			return err
+		}
+
+		err = found(other, scores[laptop.Id])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Facets counts the laptops that Search, or SearchText if the query has a word to search, would return
+// by brand, CPU brand, GPU brand, RAM size, storage driver, screen panel and release year
+func (store *InMemoryLaptopStore) Facets(
+	ctx context.Context,
+	query string,
+	filter *pb.Filter,
+	expr *FilterExpr,
+) (*pb.SearchFacets, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	candidates := store.candidates
+	if isTextQuery(query) {
+		scores := store.indexes.text.search(query)
+		candidates = func(_ *pb.Filter, fn func(laptop *pb.Laptop) bool) {
+			for id := range scores {
+				if !fn(store.data[id]) {
+					return
+				}
 			}
 		}
 	}
 
+	facets := newLaptopFacets()
+	var err error
+	candidates(filter, func(laptop *pb.Laptop) bool {
+		if ctx.Err() == context.Canceled || ctx.Err() == context.DeadlineExceeded {
+			log.Print("context is canceled")
+			err = errors.New("context is canceled")
+			return false
+		}
+
+		if isQualified(filter, laptop) && expr.Match(laptop) {
+			facets.add(laptop)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return facets.result(), nil
+}
+
+// FindSimilar returns the k laptops nearest to the laptop with the given ID via the found function,
+// by increasing weighted distance over their normalized specs. A nil weights gives the same weight
+// to every spec. It returns ErrNotFound if the laptop doesn't exist.
+func (store *InMemoryLaptopStore) FindSimilar(
+	ctx context.Context,
+	id string,
+	weights *pb.SimilarityWeights,
+	k int,
+	found func(laptop *pb.Laptop, distance float64) error,
+) error {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	neighbors, err := store.indexes.vectors.nearest(ctx, id, newVectorWeights(weights), k)
+	if err != nil {
+		return err
+	}
+
+	for _, neighbor := range neighbors {
+		other, err := deepCopy(store.data[neighbor.id])
+		if err != nil {
+			return err
+		}
+
+		err = found(other, neighbor.distance)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// Watch calls fn with each change after the sequence number until the context is done or fn fails.
+// A sequence number of zero only watches the changes to come. It returns ErrUnknownSequence if
+// the changes after the sequence number are no longer kept.
+func (store *InMemoryLaptopStore) Watch(ctx context.Context, after uint64, fn func(change *LaptopChange) error) error {
+	return store.changes.watch(ctx, after, fn)
+}
+
 func isQualified(filter *pb.Filter, laptop *pb.Laptop) bool {
 	if laptop.GetPriceUsd() > filter.GetMaxPriceUsd() {
 		return false
 	}
 
+	if laptop.GetPriceUsd() < filter.GetMinPriceUsd() {
+		return false
+	}
+
 	if laptop.GetCpu().GetNumberCores() < filter.GetMinCpuCores() {
 		return false
 	}
@@ -118,9 +429,125 @@ func isQualified(filter *pb.Filter, laptop *pb.Laptop) bool {
 		return false
 	}
 
+	if len(filter.GetBrand()) > 0 && !strings.EqualFold(laptop.GetBrand(), filter.GetBrand()) {
+		return false
+	}
+
+	if len(filter.GetName()) > 0 &&
+		!strings.Contains(strings.ToLower(laptop.GetName()), strings.ToLower(filter.GetName())) {
+		return false
+	}
+
+	if !hasQualifiedGPU(filter, laptop) || !hasQualifiedStorage(filter, laptop) {
+		return false
+	}
+
+	if !isQualifiedScreen(filter, laptop.GetScreen()) || !isQualifiedKeyboard(filter, laptop.GetKeyboard()) {
+		return false
+	}
+
+	if filter.GetMaxWeightKg() > 0 {
+		weight, ok := weightKg(laptop)
+		if !ok || weight > filter.GetMaxWeightKg() {
+			return false
+		}
+	}
+
+	if laptop.GetReleaseYear() < filter.GetMinReleaseYear() {
+		return false
+	}
+
+	if filter.GetMaxReleaseYear() > 0 && laptop.GetReleaseYear() > filter.GetMaxReleaseYear() {
+		return false
+	}
+
 	return true
 }
 
+func hasQualifiedGPU(filter *pb.Filter, laptop *pb.Laptop) bool {
+	if len(filter.GetGpuBrand()) == 0 && filter.GetMinGpuMemory() == nil {
+		return true
+	}
+
+	for _, gpu := range laptop.GetGpus() {
+		if len(filter.GetGpuBrand()) > 0 && !strings.EqualFold(gpu.GetBrand(), filter.GetGpuBrand()) {
+			continue
+		}
+
+		if toBit(gpu.GetMemory()) >= toBit(filter.GetMinGpuMemory()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasQualifiedStorage(filter *pb.Filter, laptop *pb.Laptop) bool {
+	if filter.GetStorageDriver() == pb.Storage_UNKNOWN && filter.GetMinStorage() == nil {
+		return true
+	}
+
+	for _, storage := range laptop.GetStorages() {
+		if filter.GetStorageDriver() != pb.Storage_UNKNOWN && storage.GetDriver() != filter.GetStorageDriver() {
+			continue
+		}
+
+		if toBit(storage.GetMemory()) >= toBit(filter.GetMinStorage()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isQualifiedScreen(filter *pb.Filter, screen *pb.Screen) bool {
+	if screen.GetSizeInch() < filter.GetMinScreenSizeInch() {
+		return false
+	}
+
+	if filter.GetMaxScreenSizeInch() > 0 && screen.GetSizeInch() > filter.GetMaxScreenSizeInch() {
+		return false
+	}
+
+	if screen.GetResolution().GetWidth() < filter.GetMinScreenResolution().GetWidth() ||
+		screen.GetResolution().GetHeight() < filter.GetMinScreenResolution().GetHeight() {
+		return false
+	}
+
+	if filter.GetScreenPanel() != pb.Screen_UNKNOWN && screen.GetPanel() != filter.GetScreenPanel() {
+		return false
+	}
+
+	return true
+}
+
+func isQualifiedKeyboard(filter *pb.Filter, keyboard *pb.Keyboard) bool {
+	if filter.GetKeyboardLayout() != pb.Keyboard_UNKNOWN && keyboard.GetLayout() != filter.GetKeyboardLayout() {
+		return false
+	}
+
+	if filter.GetKeyboardBacklit() && !keyboard.GetBacklit() {
+		return false
+	}
+
+	return true
+}
+
+// kilograms per pound
+const kgPerLb = 0.45359237
+
+// weightKg returns the weight of the laptop in kilograms, and false if the weight is unknown
+func weightKg(laptop *pb.Laptop) (float64, bool) {
+	switch weight := laptop.GetWeight().(type) {
+	case *pb.Laptop_WeightKg:
+		return weight.WeightKg, true
+	case *pb.Laptop_WeightLb:
+		return weight.WeightLb * kgPerLb, true
+	default:
+		return 0, false
+	}
+}
+
 func toBit(memory *pb.Memory) uint64 {
 	value := memory.GetValue()
 
@@ -142,11 +569,11 @@ func toBit(memory *pb.Memory) uint64 {
 	}
 }
 
+// deepCopy returns a copy of the laptop that shares nothing with it
 func deepCopy(laptop *pb.Laptop) (*pb.Laptop, error) {
-	other := &pb.Laptop{}
-	err := copier.Copy(other, laptop)
-	if err != nil {
-		return nil, fmt.Errorf("cannot copy laptop data: %v", err)
+	other, ok := proto.Clone(laptop).(*pb.Laptop)
+	if !ok {
+		return nil, fmt.Errorf("cannot copy laptop data")
 	}
 
 	return other, nil
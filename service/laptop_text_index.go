@@ -0,0 +1,163 @@
+package service
+
+import (
+	"github.com/treeforest/grpc-pcbook/pb"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// weights of the laptop fields in the relevance score of a text search
+const (
+	nameWeight  = 3.0
+	brandWeight = 2.0
+	cpuWeight   = 2.0
+	gpuWeight   = 1.5
+)
+
+// tokenize splits a text in lower case words of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// isTextQuery reports whether a query has a word to search, a query of blanks and symbols
+// such as "   " or "-" is the same as an empty one
+func isTextQuery(query string) bool {
+	return len(tokenize(query)) > 0
+}
+
+// laptopTerms returns the weighted terms of the searchable fields of a laptop,
+// a term found in several fields gets the sum of their weights
+func laptopTerms(laptop *pb.Laptop) map[string]float64 {
+	terms := make(map[string]float64)
+	addTerms := func(text string, weight float64) {
+		for _, term := range tokenize(text) {
+			terms[term] += weight
+		}
+	}
+
+	addTerms(laptop.GetName(), nameWeight)
+	addTerms(laptop.GetBrand(), brandWeight)
+	addTerms(laptop.GetCpu().GetName(), cpuWeight)
+	for _, gpu := range laptop.GetGpus() {
+		addTerms(gpu.GetName(), gpuWeight)
+	}
+
+	return terms
+}
+
+// textIndex is an inverted index from the terms of the laptops to their IDs
+type textIndex struct {
+	// postings maps a term to the weight of the term in each laptop that contains it
+	postings map[string]map[string]float64
+	// terms holds every indexed term in sorted order, for prefix matching
+	terms []string
+	// documents maps a laptop ID to its terms, so that a laptop can be removed
+	documents map[string][]string
+}
+
+func newTextIndex() *textIndex {
+	return &textIndex{
+		postings:  make(map[string]map[string]float64),
+		documents: make(map[string][]string),
+	}
+}
+
+func (index *textIndex) add(laptop *pb.Laptop) {
+	id := laptop.GetId()
+	terms := laptopTerms(laptop)
+
+	document := make([]string, 0, len(terms))
+	for term, weight := range terms {
+		if index.postings[term] == nil {
+			index.postings[term] = make(map[string]float64)
+
+			i := sort.SearchStrings(index.terms, term)
+			index.terms = append(index.terms, "")
+			copy(index.terms[i+1:], index.terms[i:])
+			index.terms[i] = term
+		}
+
+		index.postings[term][id] = weight
+		document = append(document, term)
+	}
+
+	index.documents[id] = document
+}
+
+func (index *textIndex) remove(laptop *pb.Laptop) {
+	id := laptop.GetId()
+	for _, term := range index.documents[id] {
+		delete(index.postings[term], id)
+		if len(index.postings[term]) > 0 {
+			continue
+		}
+
+		delete(index.postings, term)
+		i := sort.SearchStrings(index.terms, term)
+		index.terms = append(index.terms[:i], index.terms[i+1:]...)
+	}
+
+	delete(index.documents, id)
+}
+
+// search returns the relevance score of every laptop that matches all the words of the query.
+// A word matches a term it is a prefix of. Rare terms score higher than common ones,
+// and a prefix match scores half as much as a whole word.
+func (index *textIndex) search(query string) map[string]float64 {
+	words := tokenize(query)
+	if len(words) == 0 {
+		return nil
+	}
+
+	var scores map[string]float64
+	for _, word := range words {
+		wordScores := index.searchWord(word)
+
+		if scores == nil {
+			scores = wordScores
+			continue
+		}
+
+		for id := range scores {
+			if score, ok := wordScores[id]; ok {
+				scores[id] += score
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	return scores
+}
+
+// searchWord returns the score of every laptop with a term that starts with the word,
+// keeping the best term of each laptop
+func (index *textIndex) searchWord(word string) map[string]float64 {
+	scores := make(map[string]float64)
+	total := float64(len(index.documents))
+
+	for i := sort.SearchStrings(index.terms, word); i < len(index.terms); i++ {
+		term := index.terms[i]
+		if !strings.HasPrefix(term, word) {
+			break
+		}
+
+		postings := index.postings[term]
+		idf := math.Log(1 + total/float64(len(postings)))
+		if term != word {
+			idf /= 2
+		}
+
+		for id, weight := range postings {
+			if score := weight * idf; score > scores[id] {
+				scores[id] = score
+			}
+		}
+	}
+
+	return scores
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"sort"
+)
+
+// number of similar laptops returned by default and at most
+const (
+	defaultSimilarLaptops = 10
+	maxSimilarLaptops     = 100
+)
+
+// number of specs in the vector of a laptop
+const vectorSize = 8
+
+// number of vectors compared between two checks of the context
+const vectorCheckInterval = 4096
+
+// laptopVector holds the specs of a laptop that its similarity is computed from, NaN for an unknown spec.
+// Memory sizes are on a logarithmic scale so that doubling them is the same step at any size.
+type laptopVector [vectorSize]float64
+
+func newLaptopVector(laptop *pb.Laptop) laptopVector {
+	vector := laptopVector{
+		float64(laptop.GetCpu().GetNumberCores()),
+		laptop.GetCpu().GetMinGhz(),
+		logGB(toBit(laptop.GetRam())),
+		math.NaN(),
+		float64(laptop.GetScreen().GetSizeInch()),
+		0,
+		math.NaN(),
+		laptop.GetPriceUsd(),
+	}
+
+	if laptop.GetCpu() == nil {
+		vector[0], vector[1] = math.NaN(), math.NaN()
+	}
+	if laptop.GetRam() == nil {
+		vector[2] = math.NaN()
+	}
+
+	if len(laptop.GetStorages()) > 0 {
+		var bits uint64
+		for _, storage := range laptop.GetStorages() {
+			bits += toBit(storage.GetMemory())
+		}
+		vector[3] = logGB(bits)
+	}
+
+	if laptop.GetScreen() == nil {
+		vector[4] = math.NaN()
+	}
+
+	// a laptop without GPU has no GPU memory
+	var gpuBits uint64
+	for _, gpu := range laptop.GetGpus() {
+		if toBit(gpu.GetMemory()) > gpuBits {
+			gpuBits = toBit(gpu.GetMemory())
+		}
+	}
+	vector[5] = logGB(gpuBits)
+
+	if weight, ok := weightKg(laptop); ok {
+		vector[6] = weight
+	}
+
+	return vector
+}
+
+// logGB returns the base 2 logarithm of one plus the memory size in gigabytes
+func logGB(bits uint64) float64 {
+	return math.Log2(1 + float64(bits)/bitsPerGB)
+}
+
+// newVectorWeights returns the weights of the specs of a laptop vector, every spec weighs one if weights is nil
+func newVectorWeights(weights *pb.SimilarityWeights) laptopVector {
+	if weights == nil {
+		return laptopVector{1, 1, 1, 1, 1, 1, 1, 1}
+	}
+
+	return laptopVector{
+		weights.GetCpuCores(),
+		weights.GetCpuGhz(),
+		weights.GetRam(),
+		weights.GetStorage(),
+		weights.GetScreenSize(),
+		weights.GetGpuMemory(),
+		weights.GetWeight(),
+		weights.GetPrice(),
+	}
+}
+
+// validateSimilarityWeights returns an InvalidArgument error if a weight is negative or every weight is zero
+func validateSimilarityWeights(weights *pb.SimilarityWeights) error {
+	if weights == nil {
+		return nil
+	}
+
+	total := 0.0
+	for _, weight := range newVectorWeights(weights) {
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return status.Errorf(codes.InvalidArgument, "similarity weights must be finite and not negative: %v", weights)
+		}
+		total += weight
+	}
+
+	if total == 0 {
+		return status.Errorf(codes.InvalidArgument, "at least one similarity weight must be positive")
+	}
+
+	return nil
+}
+
+// vectorIndex keeps the vectors of the laptops next to each other so that they can all be compared quickly.
+// The specs are normalized by their standard deviation over every laptop, which is kept up to date
+// on every change.
+type vectorIndex struct {
+	ids       []string
+	vectors   []laptopVector
+	positions map[string]int
+	// number, sum and sum of squares of the known values of each spec
+	count      laptopVector
+	sum        laptopVector
+	sumSquares laptopVector
+}
+
+func newVectorIndex() *vectorIndex {
+	return &vectorIndex{
+		positions: make(map[string]int),
+	}
+}
+
+func (index *vectorIndex) add(laptop *pb.Laptop) {
+	index.remove(laptop.GetId())
+
+	vector := newLaptopVector(laptop)
+	index.positions[laptop.GetId()] = len(index.ids)
+	index.ids = append(index.ids, laptop.GetId())
+	index.vectors = append(index.vectors, vector)
+	index.account(vector, 1)
+}
+
+func (index *vectorIndex) remove(id string) {
+	position, ok := index.positions[id]
+	if !ok {
+		return
+	}
+
+	index.account(index.vectors[position], -1)
+
+	// move the last vector to the free position
+	last := len(index.ids) - 1
+	index.ids[position] = index.ids[last]
+	index.vectors[position] = index.vectors[last]
+	index.positions[index.ids[position]] = position
+
+	index.ids = index.ids[:last]
+	index.vectors = index.vectors[:last]
+	delete(index.positions, id)
+}
+
+// account adds the known values of the vector to the statistics of the specs, sign is 1 or -1
+func (index *vectorIndex) account(vector laptopVector, sign float64) {
+	for i, value := range vector {
+		if math.IsNaN(value) {
+			continue
+		}
+		index.count[i] += sign
+		index.sum[i] += sign * value
+		index.sumSquares[i] += sign * value * value
+	}
+}
+
+// vectorNeighbor is a laptop near the searched vector
+type vectorNeighbor struct {
+	id       string
+	distance float64
+}
+
+// nearest returns the k laptops nearest to the laptop with the given ID by increasing weighted distance,
+// it returns ErrNotFound if there is no such laptop
+func (index *vectorIndex) nearest(
+	ctx context.Context,
+	id string,
+	weights laptopVector,
+	k int,
+) ([]vectorNeighbor, error) {
+	position, ok := index.positions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if k <= 0 {
+		return nil, nil
+	}
+	target := index.vectors[position]
+
+	// weight of the squared difference of each spec, the difference between a known and
+	// an unknown value counts as one standard deviation
+	var factors laptopVector
+	for i := range factors {
+		variance := 0.0
+		if index.count[i] > 0 {
+			mean := index.sum[i] / index.count[i]
+			variance = index.sumSquares[i]/index.count[i] - mean*mean
+		}
+		if variance > 0 {
+			factors[i] = weights[i] / variance
+		}
+	}
+
+	neighbors := &neighborHeap{}
+	for i := range index.vectors {
+		if i%vectorCheckInterval == 0 && (ctx.Err() == context.Canceled || ctx.Err() == context.DeadlineExceeded) {
+			return nil, errors.New("context is canceled")
+		}
+		if i == position {
+			continue
+		}
+
+		full := neighbors.Len() == k
+		worst := math.Inf(1)
+		if full {
+			worst = (*neighbors)[0].distance
+		}
+
+		squared := 0.0
+		vector := &index.vectors[i]
+		for j := range target {
+			a, b := target[j], vector[j]
+			switch {
+			case math.IsNaN(a) && math.IsNaN(b):
+			case math.IsNaN(a) || math.IsNaN(b):
+				squared += weights[j]
+			default:
+				squared += (a - b) * (a - b) * factors[j]
+			}
+		}
+
+		neighbor := vectorNeighbor{id: index.ids[i], distance: squared}
+		if !full {
+			heap.Push(neighbors, neighbor)
+		} else if squared < worst || squared == worst && neighbor.id < (*neighbors)[0].id {
+			(*neighbors)[0] = neighbor
+			heap.Fix(neighbors, 0)
+		}
+	}
+
+	result := []vectorNeighbor(*neighbors)
+	sort.Slice(result, func(i, j int) bool {
+		return result[j].isFurther(result[i])
+	})
+	for i := range result {
+		result[i].distance = math.Sqrt(result[i].distance)
+	}
+
+	return result, nil
+}
+
+// isFurther reports whether the neighbor is further than the other one, ties are broken by ID
+func (neighbor vectorNeighbor) isFurther(other vectorNeighbor) bool {
+	if neighbor.distance != other.distance {
+		return neighbor.distance > other.distance
+	}
+	return neighbor.id > other.id
+}
+
+// neighborHeap is a max-heap of neighbors with the furthest one on top
+type neighborHeap []vectorNeighbor
+
+func (neighbors neighborHeap) Len() int { return len(neighbors) }
+
+func (neighbors neighborHeap) Less(i, j int) bool { return neighbors[i].isFurther(neighbors[j]) }
+
+func (neighbors neighborHeap) Swap(i, j int) { neighbors[i], neighbors[j] = neighbors[j], neighbors[i] }
+
+func (neighbors *neighborHeap) Push(x interface{}) {
+	*neighbors = append(*neighbors, x.(vectorNeighbor))
+}
+
+func (neighbors *neighborHeap) Pop() interface{} {
+	old := *neighbors
+	neighbor := old[len(old)-1]
+	*neighbors = old[:len(old)-1]
+	return neighbor
+}
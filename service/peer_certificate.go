@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"crypto/x509"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// certificateUsernamePrefix prefixes the identity of a client certificate in the username
+// that the client has, no user can have such a username
+const certificateUsernamePrefix = "cert:"
+
+// commonNamePrefix prefixes the subject common name of a certificate in its identity,
+// so that it cannot be mistaken for a SAN URI
+const commonNamePrefix = "CN="
+
+// peerCertificate returns the verified certificate of the client,
+// nil if the connection doesn't use TLS or the client didn't send a certificate
+func peerCertificate(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+
+	// the chains are only set once the certificate is verified against the client CA
+	chains := tlsInfo.State.VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return nil
+	}
+
+	return chains[0][0]
+}
+
+// certificateIdentities returns the identities of a certificate: its SAN URIs,
+// then its subject common name prefixed by CN=
+func certificateIdentities(cert *x509.Certificate) []string {
+	identities := make([]string, 0, len(cert.URIs)+1)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, commonNamePrefix+cert.Subject.CommonName)
+	}
+
+	return identities
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// RatingScorer ranks rated laptops by the Bayesian average of their scores, which starts from
+// a prior mean so that a few high scores don't outrank many slightly lower ones.
+// The zero RatingScorer ranks laptops by their average score.
+type RatingScorer struct {
+	// PriorMean is the score a laptop is assumed to have before it is rated
+	PriorMean float64
+	// PriorWeight is the number of scores the prior mean counts as
+	PriorWeight float64
+	// HalfLife is the time after which a score counts half, the scores don't decay if it is zero
+	HalfLife time.Duration
+}
+
+// Validate returns an error if the prior or the half life of the scorer is invalid
+func (scorer RatingScorer) Validate() error {
+	if scorer.PriorWeight < 0 || math.IsNaN(scorer.PriorWeight) || math.IsInf(scorer.PriorWeight, 0) {
+		return fmt.Errorf("prior weight must be a non-negative number")
+	}
+	if scorer.PriorWeight > 0 && (scorer.PriorMean < minScore || scorer.PriorMean > maxScore) {
+		return fmt.Errorf("prior mean must be from %d to %d", minScore, maxScore)
+	}
+	if scorer.HalfLife < 0 {
+		return fmt.Errorf("half life cannot be negative")
+	}
+
+	return nil
+}
+
+// Score returns the score of the laptop at the given time and the weighted number of scores it has,
+// both are zero if the laptop has not been rated
+func (scorer RatingScorer) Score(ratingStore RatingStore, laptopID string, now time.Time) (float64, float64, error) {
+	var count, sum float64
+
+	if scorer.HalfLife == 0 {
+		rating, err := ratingStore.Find(laptopID)
+		if err != nil {
+			return 0, 0, err
+		}
+		if rating == nil || rating.Count == 0 {
+			return 0, 0, nil
+		}
+
+		count, sum = float64(rating.Count), rating.Sum
+	} else {
+		rating, err := ratingStore.FindWeighted(laptopID, func(ratedAt time.Time) float64 {
+			return scorer.decay(ratedAt, now)
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+		if rating == nil || rating.Count == 0 {
+			return 0, 0, nil
+		}
+
+		count, sum = rating.Count, rating.Sum
+	}
+
+	score := (scorer.PriorWeight*scorer.PriorMean + sum) / (scorer.PriorWeight + count)
+	return score, count, nil
+}
+
+// decay returns the weight of a score given at ratedAt, which halves every half life.
+// The scores given at an unknown time or in the future weigh 1.
+func (scorer RatingScorer) decay(ratedAt time.Time, now time.Time) float64 {
+	if ratedAt.IsZero() || !ratedAt.Before(now) {
+		return 1
+	}
+
+	return math.Exp2(-float64(now.Sub(ratedAt)) / float64(scorer.HalfLife))
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRatingScorerScore(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	halfLife := 30 * 24 * time.Hour
+
+	store := NewInMemoryRatingStore()
+	// one perfect score against many slightly lower ones
+	store.put("user1", "single", 10, now)
+	for i := 0; i < 500; i++ {
+		score := 9.0
+		if i%2 == 0 {
+			score = 10
+		}
+		store.put(fmt.Sprintf("user%d", i), "many", score, now)
+	}
+	// a recent low score and an old high one
+	store.put("user1", "aged", 2, now)
+	store.put("user2", "aged", 10, now.Add(-halfLife))
+	// ratings recorded before the times were recorded don't decay
+	store.put("user1", "untimed", 10, time.Time{})
+	store.putAnonymous("untimed", &Rating{Count: 1, Sum: 4})
+
+	testCases := []struct {
+		name     string
+		scorer   RatingScorer
+		laptopID string
+		score    float64
+		count    float64
+	}{
+		{"average_single", RatingScorer{}, "single", 10, 1},
+		{"average_many", RatingScorer{}, "many", 9.5, 500},
+		{"bayesian_single", RatingScorer{PriorMean: 5, PriorWeight: 10}, "single", 60.0 / 11, 1},
+		{"bayesian_many", RatingScorer{PriorMean: 5, PriorWeight: 10}, "many", 4800.0 / 510, 500},
+		{"unrated", RatingScorer{PriorMean: 5, PriorWeight: 10}, "unknown", 0, 0},
+		{"no_decay", RatingScorer{}, "aged", 6, 2},
+		{"decay", RatingScorer{HalfLife: halfLife}, "aged", 7.0 / 1.5, 1.5},
+		{"decay_bayesian", RatingScorer{PriorMean: 5, PriorWeight: 1, HalfLife: halfLife}, "aged", 12.0 / 2.5, 1.5},
+		{"decay_untimed", RatingScorer{HalfLife: halfLife}, "untimed", 7, 2},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			score, count, err := tc.scorer.Score(store, tc.laptopID, now)
+			require.NoError(t, err)
+			require.InDelta(t, tc.score, score, 1e-9)
+			require.InDelta(t, tc.count, count, 1e-9)
+		})
+	}
+
+	// the Bayesian average ranks many slightly lower scores above a single perfect one
+	scorer := RatingScorer{PriorMean: 5, PriorWeight: 10}
+	single, _, err := scorer.Score(store, "single", now)
+	require.NoError(t, err)
+	many, _, err := scorer.Score(store, "many", now)
+	require.NoError(t, err)
+	require.Greater(t, many, single)
+}
+
+func TestRatingScorerValidate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		scorer RatingScorer
+		valid  bool
+	}{
+		{"zero", RatingScorer{}, true},
+		{"bayesian", RatingScorer{PriorMean: 5.5, PriorWeight: 10, HalfLife: time.Hour}, true},
+		{"negative_weight", RatingScorer{PriorMean: 5.5, PriorWeight: -1}, false},
+		{"infinite_weight", RatingScorer{PriorMean: 5.5, PriorWeight: math.Inf(1)}, false},
+		{"mean_out_of_range", RatingScorer{PriorMean: 11, PriorWeight: 1}, false},
+		{"negative_half_life", RatingScorer{HalfLife: -time.Hour}, false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.scorer.Validate()
+			if tc.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
@@ -1,48 +1,213 @@
 package service
 
-import "sync"
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// range of the scores a user can give to a laptop
+const (
+	minScore = 1
+	maxScore = 10
+)
+
+// ErrInvalidScore is returned when a score is out of the range of scores
+var ErrInvalidScore = fmt.Errorf("score must be from %d to %d", minScore, maxScore)
 
 // RatingStore ia an interface to store laptop rating
 type RatingStore interface {
-	// Add adds a new laptop score to the store and returns its rating
-	Add(laptopID string, score float64) (*Rating, error)
+	// Rate sets the score a user gives to a laptop, replacing the previous score of the user,
+	// and returns the rating of the laptop. It returns ErrInvalidScore if the score is out of range.
+	Rate(username string, laptopID string, score float64) (*Rating, error)
+	// Find finds the rating of a laptop, returns nil if it has not been rated
+	Find(laptopID string) (*Rating, error)
+	// FindScore finds the score a user gives to a laptop, returns false if the user has not rated it
+	FindScore(username string, laptopID string) (float64, bool, error)
+	// FindWeighted finds the rating of a laptop where each score counts as weight(ratedAt) scores,
+	// returns nil if it has not been rated. The time is zero for the scores recorded before
+	// the times were recorded, the ratings recorded before the ratings were recorded per user weigh 1.
+	FindWeighted(laptopID string, weight func(ratedAt time.Time) float64) (*WeightedRating, error)
 }
 
 // Rating contains the rating information of a laptop
 type Rating struct {
 	Count uint32
 	Sum   float64
+	// Histogram[i] is the number of scores from i+1 up to but excluding i+2,
+	// it doesn't count the ratings recorded before they were recorded per user
+	Histogram [maxScore]uint32
+}
+
+// Average returns the average score of the rating, zero if there is no score
+func (rating *Rating) Average() float64 {
+	if rating == nil || rating.Count == 0 {
+		return 0
+	}
+
+	return rating.Sum / float64(rating.Count)
+}
+
+// WeightedRating is the rating of a laptop where the scores have different weights
+type WeightedRating struct {
+	Count float64
+	Sum   float64
+}
+
+// userScore is the score a user gives to a laptop
+type userScore struct {
+	score   float64
+	ratedAt time.Time
+}
+
+// isValidScore reports whether a user can give the score to a laptop
+func isValidScore(score float64) bool {
+	return score >= minScore && score <= maxScore
+}
+
+// histogramBucket returns the index of the histogram bucket that counts the score
+func histogramBucket(score float64) int {
+	return int(math.Floor(score)) - minScore
 }
 
 // InMemoryRatingStore stores laptop rating in memory
 type InMemoryRatingStore struct {
 	mutex  sync.RWMutex
 	rating map[string]*Rating
+	// scores of each laptop by username
+	scores map[string]map[string]userScore
+	// anonymous ratings recorded before the ratings were recorded per user
+	anonymous map[string]*Rating
 }
 
 // NewInMemoryRatingStore returns a new InMemoryRatingStore
 func NewInMemoryRatingStore() *InMemoryRatingStore {
 	return &InMemoryRatingStore{
-		rating: make(map[string]*Rating),
+		rating:    make(map[string]*Rating),
+		scores:    make(map[string]map[string]userScore),
+		anonymous: make(map[string]*Rating),
 	}
 }
 
-// Add adds a new laptop score to the store and returns its rating
-func (store *InMemoryRatingStore) Add(laptopID string, score float64) (*Rating, error) {
+// Rate sets the score a user gives to a laptop, replacing the previous score of the user,
+// and returns the rating of the laptop. It returns ErrInvalidScore if the score is out of range.
+func (store *InMemoryRatingStore) Rate(username string, laptopID string, score float64) (*Rating, error) {
+	if !isValidScore(score) {
+		return nil, ErrInvalidScore
+	}
+
 	store.mutex.Lock()
 	defer store.mutex.Unlock()
 
+	store.setScore(username, laptopID, userScore{score: score, ratedAt: time.Now()})
+
+	other := *store.rating[laptopID]
+	return &other, nil
+}
+
+// Find finds the rating of a laptop, returns nil if it has not been rated
+func (store *InMemoryRatingStore) Find(laptopID string) (*Rating, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
 	rating := store.rating[laptopID]
 	if rating == nil {
-		rating = &Rating{
-			Count: 1,
-			Sum:   score,
-		}
-	} else {
-		rating.Count++
-		rating.Sum += score
+		return nil, nil
+	}
+
+	other := *rating
+	return &other, nil
+}
+
+// FindScore finds the score a user gives to a laptop, returns false if the user has not rated it
+func (store *InMemoryRatingStore) FindScore(username string, laptopID string) (float64, bool, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	score, ok := store.scores[laptopID][username]
+	return score.score, ok, nil
+}
+
+// FindWeighted finds the rating of a laptop where each score counts as weight(ratedAt) scores,
+// returns nil if it has not been rated
+func (store *InMemoryRatingStore) FindWeighted(
+	laptopID string,
+	weight func(ratedAt time.Time) float64,
+) (*WeightedRating, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	if store.rating[laptopID] == nil {
+		return nil, nil
+	}
+
+	rating := &WeightedRating{}
+	if anonymous := store.anonymous[laptopID]; anonymous != nil {
+		rating.Count = float64(anonymous.Count)
+		rating.Sum = anonymous.Sum
+	}
+
+	for _, score := range store.scores[laptopID] {
+		w := weight(score.ratedAt)
+		rating.Count += w
+		rating.Sum += w * score.score
 	}
 
-	store.rating[laptopID] = rating
 	return rating, nil
-}
\ No newline at end of file
+}
+
+// setScore sets the score of a user and updates the rating of the laptop, the caller must hold the write lock
+func (store *InMemoryRatingStore) setScore(username string, laptopID string, score userScore) {
+	rating := store.rating[laptopID]
+	if rating == nil {
+		rating = &Rating{}
+		store.rating[laptopID] = rating
+	}
+
+	scores := store.scores[laptopID]
+	if scores == nil {
+		scores = make(map[string]userScore)
+		store.scores[laptopID] = scores
+	}
+
+	if old, ok := scores[username]; ok {
+		rating.Count--
+		rating.Sum -= old.score
+		rating.Histogram[histogramBucket(old.score)]--
+	}
+
+	scores[username] = score
+	rating.Count++
+	rating.Sum += score.score
+	rating.Histogram[histogramBucket(score.score)]++
+}
+
+// putAnonymous sets the anonymous rating of a laptop that was recorded before the ratings were recorded per user
+func (store *InMemoryRatingStore) putAnonymous(laptopID string, anonymous *Rating) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	rating := store.rating[laptopID]
+	if rating == nil {
+		rating = &Rating{}
+		store.rating[laptopID] = rating
+	}
+
+	if old := store.anonymous[laptopID]; old != nil {
+		rating.Count -= old.Count
+		rating.Sum -= old.Sum
+	}
+
+	rating.Count += anonymous.Count
+	rating.Sum += anonymous.Sum
+	store.anonymous[laptopID] = &Rating{Count: anonymous.Count, Sum: anonymous.Sum}
+}
+
+// put sets the score of a user given at the time without returning the rating
+func (store *InMemoryRatingStore) put(username string, laptopID string, score float64, ratedAt time.Time) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.setScore(username, laptopID, userScore{score: score, ratedAt: ratedAt})
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInMemoryRatingStore(t *testing.T) {
+	t.Parallel()
+
+	newStore := func(t *testing.T) RatingStore {
+		return NewInMemoryRatingStore()
+	}
+
+	t.Run("rate", func(t *testing.T) {
+		store := newStore(t)
+
+		rating, err := store.Rate("user1", "laptop1", 8)
+		require.NoError(t, err)
+		require.Equal(t, uint32(1), rating.Count)
+		require.Equal(t, 8.0, rating.Sum)
+
+		rating, err = store.Rate("user2", "laptop1", 7)
+		require.NoError(t, err)
+		require.Equal(t, uint32(2), rating.Count)
+		require.Equal(t, 15.0, rating.Sum)
+
+		rating, err = store.Rate("user1", "laptop2", 10)
+		require.NoError(t, err)
+		require.Equal(t, uint32(1), rating.Count)
+		require.Equal(t, 10.0, rating.Sum)
+	})
+
+	t.Run("rate_again", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.Rate("user1", "laptop1", 8)
+		require.NoError(t, err)
+		_, err = store.Rate("user2", "laptop1", 4)
+		require.NoError(t, err)
+
+		rating, err := store.Rate("user1", "laptop1", 2.5)
+		require.NoError(t, err)
+		require.Equal(t, uint32(2), rating.Count)
+		require.Equal(t, 6.5, rating.Sum)
+		require.Equal(t, [maxScore]uint32{0, 1, 0, 1}, rating.Histogram)
+
+		score, ok, err := store.FindScore("user1", "laptop1")
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, 2.5, score)
+	})
+
+	t.Run("invalid_score", func(t *testing.T) {
+		store := newStore(t)
+
+		for _, score := range []float64{0, 0.5, 10.5, -1} {
+			rating, err := store.Rate("user1", "laptop1", score)
+			require.Nil(t, rating)
+			require.Equal(t, ErrInvalidScore, err)
+		}
+
+		rating, err := store.Find("laptop1")
+		require.NoError(t, err)
+		require.Nil(t, rating)
+	})
+
+	t.Run("find", func(t *testing.T) {
+		store := newStore(t)
+
+		rating, err := store.Find("laptop1")
+		require.NoError(t, err)
+		require.Nil(t, rating)
+		require.Zero(t, rating.Average())
+
+		_, ok, err := store.FindScore("user1", "laptop1")
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		_, err = store.Rate("user1", "laptop1", 8)
+		require.NoError(t, err)
+		_, err = store.Rate("user2", "laptop1", 7)
+		require.NoError(t, err)
+		_, err = store.Rate("user3", "laptop1", 10)
+		require.NoError(t, err)
+
+		rating, err = store.Find("laptop1")
+		require.NoError(t, err)
+		require.Equal(t, uint32(3), rating.Count)
+		require.Equal(t, 25.0/3, rating.Average())
+		require.Equal(t, [maxScore]uint32{6: 1, 7: 1, 9: 1}, rating.Histogram)
+
+		_, ok, err = store.FindScore("user1", "laptop2")
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("find_weighted", func(t *testing.T) {
+		store := newStore(t)
+
+		rating, err := store.FindWeighted("laptop1", func(ratedAt time.Time) float64 { return 1 })
+		require.NoError(t, err)
+		require.Nil(t, rating)
+
+		before := time.Now()
+		_, err = store.Rate("user1", "laptop1", 8)
+		require.NoError(t, err)
+		_, err = store.Rate("user2", "laptop1", 4)
+		require.NoError(t, err)
+
+		rating, err = store.FindWeighted("laptop1", func(ratedAt time.Time) float64 {
+			require.False(t, ratedAt.Before(before))
+			return 0.5
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1.0, rating.Count)
+		require.Equal(t, 6.0, rating.Sum)
+	})
+
+	t.Run("returned_rating_is_a_copy", func(t *testing.T) {
+		store := newStore(t)
+
+		rating, err := store.Rate("user1", "laptop1", 8)
+		require.NoError(t, err)
+		rating.Count = 100
+		rating.Histogram[0] = 100
+
+		rating, err = store.Rate("user2", "laptop1", 8)
+		require.NoError(t, err)
+		require.Equal(t, uint32(2), rating.Count)
+		require.Zero(t, rating.Histogram[0])
+	})
+
+	t.Run("concurrent_rate", func(t *testing.T) {
+		store := newStore(t)
+
+		n := 50
+		wg := sync.WaitGroup{}
+		wg.Add(n)
+		for i := 0; i < n; i++ {
+			username := fmt.Sprintf("user%d", i)
+			go func() {
+				defer wg.Done()
+				_, err := store.Rate(username, "laptop1", 1)
+				require.NoError(t, err)
+				_, err = store.Rate(username, "laptop1", 2)
+				require.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		rating, err := store.Find("laptop1")
+		require.NoError(t, err)
+		require.Equal(t, uint32(n), rating.Count)
+		require.Equal(t, float64(2*n), rating.Sum)
+	})
+}
+
+func TestFileRatingStore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	store, err := NewFileRatingStore(dir)
+	require.NoError(t, err)
+
+	// an invalid score is rejected before it reaches the log
+	_, err = store.Rate("user1", "laptop1", 11)
+	require.Equal(t, ErrInvalidScore, err)
+	require.Zero(t, store.wal.count)
+
+	_, err = store.Rate("user1", "laptop1", 8)
+	require.NoError(t, err)
+	_, err = store.Rate("user2", "laptop1", 6)
+	require.NoError(t, err)
+	require.NoError(t, store.snapshot())
+	_, err = store.Rate("user1", "laptop1", 10)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	store, err = NewFileRatingStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	rating, err := store.Rate("user3", "laptop1", 4)
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), rating.Count)
+	require.Equal(t, 20.0, rating.Sum)
+
+	score, ok, err := store.FindScore("user1", "laptop1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 10.0, score)
+
+	// the times of the scores are replayed too
+	weighted, err := store.FindWeighted("laptop1", func(ratedAt time.Time) float64 {
+		require.False(t, ratedAt.IsZero())
+		return 1
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3.0, weighted.Count)
+}
+
+func TestFileRatingStoreReplayAnonymous(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	store, err := NewFileRatingStore(dir)
+	require.NoError(t, err)
+
+	// a rating written before the ratings were recorded per user
+	record := &pb.RatingLogRecord{LaptopId: "laptop1", Count: 2, Sum: 15}
+	require.NoError(t, store.commit(record, func() error { return nil }))
+	require.NoError(t, store.Close())
+
+	store, err = NewFileRatingStore(dir)
+	require.NoError(t, err)
+
+	rating, err := store.Rate("user1", "laptop1", 9)
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), rating.Count)
+	require.Equal(t, 24.0, rating.Sum)
+	require.Equal(t, [maxScore]uint32{8: 1}, rating.Histogram)
+
+	// the anonymous rating is kept by the snapshot
+	require.NoError(t, store.snapshot())
+	require.NoError(t, store.Close())
+
+	store, err = NewFileRatingStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	rating, err = store.Find("laptop1")
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), rating.Count)
+	require.Equal(t, 24.0, rating.Sum)
+}
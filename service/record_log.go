@@ -0,0 +1,355 @@
+package service
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// maximum 64 megabytes
+const maxRecordSize = 64 << 20
+
+// errCorruptRecord is returned when a record is truncated or its checksum doesn't match
+var errCorruptRecord = errors.New("corrupt record")
+
+// writeRecord writes a length-delimited protobuf record:
+// the payload length as uvarint, the CRC-32 checksum of the payload and the payload itself
+func writeRecord(w io.Writer, message proto.Message) error {
+	payload, err := proto.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("cannot marshal record: %w", err)
+	}
+
+	header := make([]byte, binary.MaxVarintLen64+4)
+	n := binary.PutUvarint(header, uint64(len(payload)))
+	binary.LittleEndian.PutUint32(header[n:], crc32.ChecksumIEEE(payload))
+
+	_, err = w.Write(append(header[:n+4], payload...))
+	if err != nil {
+		return fmt.Errorf("cannot write record: %w", err)
+	}
+
+	return nil
+}
+
+// readRecords reads records until the end of r and passes each payload to the replay function.
+// It returns the number of bytes taken by the valid records, together with errCorruptRecord
+// if the data after them is not a complete record.
+func readRecords(r io.Reader, replay func(payload []byte) error) (int64, error) {
+	reader := bufio.NewReader(r)
+	var offset int64
+
+	for {
+		length, err := binary.ReadUvarint(reader)
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err != nil || length > maxRecordSize {
+			return offset, errCorruptRecord
+		}
+
+		n := int64(binary.PutUvarint(make([]byte, binary.MaxVarintLen64), length))
+		data := make([]byte, 4+length)
+		_, err = io.ReadFull(reader, data)
+		if err != nil {
+			return offset, errCorruptRecord
+		}
+
+		payload := data[4:]
+		if binary.LittleEndian.Uint32(data) != crc32.ChecksumIEEE(payload) {
+			return offset, errCorruptRecord
+		}
+
+		err = replay(payload)
+		if err != nil {
+			return offset, err
+		}
+
+		offset += n + int64(len(data))
+	}
+}
+
+// logFile is the file of a recordLog
+type logFile interface {
+	io.ReadWriteSeeker
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+// recordLog is an append-only file of records
+type recordLog struct {
+	path  string
+	file  logFile
+	count int
+	// error that cutting off a failed record returned, the records appended after it could not be replayed
+	broken error
+}
+
+// openRecordLog opens the log at path, replays its records and cuts off an incomplete last record
+func openRecordLog(path string, replay func(payload []byte) error) (*recordLog, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open log file: %w", err)
+	}
+
+	wal := &recordLog{path: path, file: file}
+
+	offset, err := readRecords(file, func(payload []byte) error {
+		wal.count++
+		return replay(payload)
+	})
+	if errors.Is(err, errCorruptRecord) {
+		log.Printf("truncate corrupt log %s at offset %d", path, offset)
+		err = file.Truncate(offset)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("cannot replay log file: %w", err)
+	}
+
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("cannot seek log file: %w", err)
+	}
+
+	return wal, nil
+}
+
+// append writes a record to the end of the log and flushes it to disk.
+// A record that cannot be written or flushed is cut off, so that the records appended after it can be replayed.
+func (wal *recordLog) append(message proto.Message) error {
+	if wal.broken != nil {
+		return fmt.Errorf("log file is broken: %w", wal.broken)
+	}
+
+	offset, err := wal.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("cannot seek log file: %w", err)
+	}
+
+	err = writeRecord(wal.file, message)
+	if err == nil {
+		err = wal.file.Sync()
+		if err != nil {
+			err = fmt.Errorf("cannot sync log file: %w", err)
+		}
+	}
+	if err != nil {
+		if rollbackErr := wal.rollback(offset); rollbackErr != nil {
+			log.Printf("cannot cut off failed record of %s: %v", wal.path, rollbackErr)
+			wal.broken = rollbackErr
+		}
+		return err
+	}
+
+	wal.count++
+	return nil
+}
+
+// rollback cuts off the log at offset, where the next record is written
+func (wal *recordLog) rollback(offset int64) error {
+	err := wal.file.Truncate(offset)
+	if err != nil {
+		return fmt.Errorf("cannot truncate log file: %w", err)
+	}
+
+	_, err = wal.file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("cannot seek log file: %w", err)
+	}
+
+	return nil
+}
+
+// reset drops every record of the log
+func (wal *recordLog) reset() error {
+	err := wal.file.Truncate(0)
+	if err != nil {
+		return fmt.Errorf("cannot truncate log file: %w", err)
+	}
+
+	_, err = wal.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("cannot seek log file: %w", err)
+	}
+
+	wal.count = 0
+	wal.broken = nil
+	return wal.file.Sync()
+}
+
+// close closes the log file
+func (wal *recordLog) close() error {
+	return wal.file.Close()
+}
+
+// writeSnapshotFile atomically replaces the file at path with the records written by the write function
+func writeSnapshotFile(path string, write func(w io.Writer) error) error {
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("cannot create snapshot file: %w", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	err = write(writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("cannot write snapshot file: %w", err)
+	}
+
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		return fmt.Errorf("cannot rename snapshot file: %w", err)
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+// readSnapshotFile replays the records of the snapshot file at path, a missing file has no records
+func readSnapshotFile(path string, replay func(payload []byte) error) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot open snapshot file: %w", err)
+	}
+	defer file.Close()
+
+	_, err = readRecords(file, replay)
+	if err != nil {
+		return fmt.Errorf("cannot read snapshot file: %w", err)
+	}
+
+	return nil
+}
+
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("cannot open directory: %w", err)
+	}
+	defer file.Close()
+
+	// some file systems don't support syncing a directory
+	_ = file.Sync()
+	return nil
+}
+
+// number of log records after which a record store compacts its log into a snapshot
+const snapshotInterval = 1000
+
+// recordStore persists the records of a store to a write-ahead log, which is compacted
+// into a snapshot of the whole store from time to time. A crash during compaction replays the log
+// over the new snapshot, which already has its records, so each record must hold the state
+// it leaves, like the whole user or the score of a user, and replaying it twice is harmless.
+type recordStore struct {
+	mutex            sync.Mutex
+	snapshotPath     string
+	snapshotInterval int
+	wal              *recordLog
+	writeSnapshot    func(w io.Writer) error
+}
+
+// openRecordStore replays the snapshot and the log named after the store in dir
+func openRecordStore(
+	dir string,
+	name string,
+	replay func(payload []byte) error,
+	writeSnapshot func(w io.Writer) error,
+) (*recordStore, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create data directory: %w", err)
+	}
+
+	snapshotPath := filepath.Join(dir, name+".snapshot")
+	err = readSnapshotFile(snapshotPath, replay)
+	if err != nil {
+		return nil, err
+	}
+
+	wal, err := openRecordLog(filepath.Join(dir, name+".wal"), replay)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &recordStore{
+		snapshotPath:     snapshotPath,
+		snapshotInterval: snapshotInterval,
+		wal:              wal,
+		writeSnapshot:    writeSnapshot,
+	}
+	return store, nil
+}
+
+// commit appends the record to the log before applying it with the apply function
+func (store *recordStore) commit(record proto.Message, apply func() error) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	err := store.wal.append(record)
+	if err != nil {
+		return err
+	}
+
+	err = apply()
+	if err != nil {
+		return err
+	}
+
+	if store.wal.count >= store.snapshotInterval {
+		// the log still holds every record, so a failed snapshot loses nothing
+		if err := store.compact(); err != nil {
+			log.Printf("cannot snapshot %s: %v", store.snapshotPath, err)
+		}
+	}
+
+	return nil
+}
+
+// snapshot writes a new snapshot and clears the log
+func (store *recordStore) snapshot() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.compact()
+}
+
+// compact writes a new snapshot and clears the log, the caller must hold the lock
+func (store *recordStore) compact() error {
+	err := writeSnapshotFile(store.snapshotPath, store.writeSnapshot)
+	if err != nil {
+		return err
+	}
+
+	return store.wal.reset()
+}
+
+// Close closes the write-ahead log of the store
+func (store *recordStore) Close() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.wal.close()
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"errors"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/require"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+// faultyLogFile is a log file whose writes stop after a number of bytes and whose syncs can fail
+type faultyLogFile struct {
+	logFile
+	// number of bytes that can still be written, negative if there is no limit
+	writeLimit int
+	syncErr    error
+}
+
+var errFaultyLogFile = errors.New("faulty log file")
+
+func (file *faultyLogFile) Write(data []byte) (int, error) {
+	if file.writeLimit < 0 || len(data) <= file.writeLimit {
+		return file.logFile.Write(data)
+	}
+
+	n, err := file.logFile.Write(data[:file.writeLimit])
+	if err == nil {
+		err = errFaultyLogFile
+	}
+	return n, err
+}
+
+func (file *faultyLogFile) Sync() error {
+	if file.syncErr != nil {
+		return file.syncErr
+	}
+	return file.logFile.Sync()
+}
+
+func TestRecordLogFailedAppend(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "test.wal")
+	wal, err := openRecordLog(path, func(payload []byte) error { return nil })
+	require.NoError(t, err)
+
+	faulty := &faultyLogFile{logFile: wal.file, writeLimit: -1}
+	wal.file = faulty
+
+	require.NoError(t, wal.append(&pb.LaptopLogRecord{LaptopId: "1"}))
+
+	// a partial write
+	faulty.writeLimit = 5
+	require.Error(t, wal.append(&pb.LaptopLogRecord{LaptopId: "2"}))
+
+	// a failed sync
+	faulty.writeLimit = -1
+	faulty.syncErr = errFaultyLogFile
+	require.Error(t, wal.append(&pb.LaptopLogRecord{LaptopId: "3"}))
+
+	faulty.syncErr = nil
+	require.NoError(t, wal.append(&pb.LaptopLogRecord{LaptopId: "4"}))
+	require.Equal(t, 2, wal.count)
+	require.NoError(t, wal.close())
+
+	// the records appended after the failed ones are replayed
+	var ids []string
+	wal, err = openRecordLog(path, func(payload []byte) error {
+		record := &pb.LaptopLogRecord{}
+		require.NoError(t, proto.Unmarshal(payload, record))
+		ids = append(ids, record.GetLaptopId())
+		return nil
+	})
+	require.NoError(t, err)
+	defer wal.close()
+
+	require.Equal(t, []string{"1", "4"}, ids)
+}
+
+// openTestRecordStore opens a record store of user roles in dir, replaying its records into roles
+func openTestRecordStore(t *testing.T, dir string, roles map[string]string) *recordStore {
+	replay := func(payload []byte) error {
+		record := &pb.UserLogRecord{}
+		require.NoError(t, proto.Unmarshal(payload, record))
+		roles[record.GetUsername()] = record.GetRole()
+		return nil
+	}
+
+	writeSnapshot := func(w io.Writer) error {
+		for username, role := range roles {
+			err := writeRecord(w, &pb.UserLogRecord{Username: username, Role: role})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	store, err := openRecordStore(dir, "roles", replay, writeSnapshot)
+	require.NoError(t, err)
+	store.snapshotInterval = 3
+	return store
+}
+
+func TestRecordStore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	roles := make(map[string]string)
+	store := openTestRecordStore(t, dir, roles)
+
+	setRole := func(username string, role string) {
+		record := &pb.UserLogRecord{Username: username, Role: role}
+		require.NoError(t, store.commit(record, func() error {
+			roles[username] = role
+			return nil
+		}))
+	}
+
+	setRole("alice", userRole)
+	setRole("bob", userRole)
+	require.Equal(t, 2, store.wal.count)
+
+	// the third record compacts the log into a snapshot
+	setRole("alice", adminRole)
+	require.Zero(t, store.wal.count)
+	require.FileExists(t, filepath.Join(dir, "roles.snapshot"))
+
+	setRole("bob", sellerRole)
+	require.NoError(t, store.Close())
+
+	want := map[string]string{"alice": adminRole, "bob": sellerRole}
+	replayed := make(map[string]string)
+	store = openTestRecordStore(t, dir, replayed)
+	require.Equal(t, want, replayed)
+
+	// a crash after a new snapshot is written but before the log is cleared replays the log
+	// over the snapshot, which gives the same state since the records hold the roles they set
+	require.NoError(t, writeSnapshotFile(store.snapshotPath, store.writeSnapshot))
+	require.Equal(t, 1, store.wal.count)
+	require.NoError(t, store.Close())
+
+	replayed = make(map[string]string)
+	store = openTestRecordStore(t, dir, replayed)
+	defer store.Close()
+	require.Equal(t, want, replayed)
+}
@@ -0,0 +1,411 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/uuid"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maximum number of characters of a review
+	maxReviewLength = 5000
+	// page size of ListReviews when the request doesn't set one
+	defaultReviewPageSize = 20
+	maxReviewPageSize     = 100
+)
+
+// ReviewServer is the server that provide review service
+type ReviewServer struct {
+	pb.UnimplementedReviewServiceServer
+	reviewStore ReviewStore
+	laptopStore LaptopStore
+	ratingStore RatingStore
+}
+
+// NewReviewServer returns a new ReviewServer, the scores of the reviews are the ratings of the rating store
+func NewReviewServer(reviewStore ReviewStore, laptopStore LaptopStore, ratingStore RatingStore) *ReviewServer {
+	return &ReviewServer{
+		reviewStore: reviewStore,
+		laptopStore: laptopStore,
+		ratingStore: ratingStore,
+	}
+}
+
+// SubmitReview is a unary RPC to write a review of a laptop, which waits for moderation
+func (server *ReviewServer) SubmitReview(
+	ctx context.Context,
+	req *pb.SubmitReviewRequest,
+) (*pb.SubmitReviewResponse, error) {
+	username, err := authenticatedUsername(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	laptopID := req.GetLaptopId()
+	log.Printf("receive a submit-review request from %s for laptop: %s", username, laptopID)
+
+	err = validateReview(req.GetText(), req.GetScore())
+	if err != nil {
+		return nil, logError(err)
+	}
+
+	laptop, err := server.laptopStore.Find(laptopID)
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot find laptop: %v", err))
+	}
+	if laptop == nil {
+		return nil, logError(status.Errorf(codes.NotFound, "laptop %s doesn't exist", laptopID))
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot generate a new review ID: %v", err))
+	}
+
+	now := ptypes.TimestampNow()
+	review := &pb.Review{
+		Id:        id.String(),
+		LaptopId:  laptopID,
+		Username:  username,
+		Text:      req.GetText(),
+		Status:    pb.Review_PENDING,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err = server.reviewStore.Save(review)
+	if err != nil {
+		code := codes.Internal
+		if errors.Is(err, ErrAlreadyExists) {
+			code = codes.AlreadyExists
+		}
+		return nil, logError(status.Errorf(code, "cannot save review: %v", err))
+	}
+
+	err = server.rate(review, req.GetScore())
+	if err != nil {
+		return nil, logError(err)
+	}
+
+	log.Printf("saved review with id: %s", review.Id)
+
+	res := &pb.SubmitReviewResponse{Review: review}
+	return res, nil
+}
+
+// EditReview is a unary RPC to change the text of a review of the authenticated user,
+// which then waits for moderation again
+func (server *ReviewServer) EditReview(
+	ctx context.Context,
+	req *pb.EditReviewRequest,
+) (*pb.EditReviewResponse, error) {
+	username, err := authenticatedUsername(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("receive an edit-review request from %s with id: %s", username, req.GetId())
+
+	err = validateReview(req.GetText(), req.GetScore())
+	if err != nil {
+		return nil, logError(err)
+	}
+
+	review, err := server.findOwnReview(username, req.GetId())
+	if err != nil {
+		return nil, logError(err)
+	}
+
+	review.Text = req.GetText()
+	review.Status = pb.Review_PENDING
+	review.UpdatedAt = ptypes.TimestampNow()
+	review.ModeratedBy = ""
+	review.RejectionReason = ""
+
+	err = server.reviewStore.Update(review)
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot update review: %v", err))
+	}
+
+	err = server.rate(review, req.GetScore())
+	if err != nil {
+		return nil, logError(err)
+	}
+
+	res := &pb.EditReviewResponse{Review: review}
+	return res, nil
+}
+
+// DeleteReview is a unary RPC to delete a review of the authenticated user, the score is kept
+func (server *ReviewServer) DeleteReview(
+	ctx context.Context,
+	req *pb.DeleteReviewRequest,
+) (*pb.DeleteReviewResponse, error) {
+	username, err := authenticatedUsername(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("receive a delete-review request from %s with id: %s", username, req.GetId())
+
+	_, err = server.findOwnReview(username, req.GetId())
+	if err != nil {
+		return nil, logError(err)
+	}
+
+	err = server.reviewStore.Delete(req.GetId())
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot delete review: %v", err))
+	}
+
+	res := &pb.DeleteReviewResponse{Id: req.GetId()}
+	return res, nil
+}
+
+// ListReviews is a unary RPC to list a page of the reviews of a laptop from the newest to the oldest.
+// Users only see the approved reviews and their own ones, admins see every review.
+func (server *ReviewServer) ListReviews(
+	ctx context.Context,
+	req *pb.ListReviewsRequest,
+) (*pb.ListReviewsResponse, error) {
+	log.Printf("receive a list-reviews request for laptop: %s, status: %s", req.GetLaptopId(), req.GetStatus())
+
+	pageSize := int(req.GetPageSize())
+	if pageSize < 0 {
+		return nil, logError(status.Error(codes.InvalidArgument, "page size cannot be negative"))
+	}
+	if pageSize == 0 {
+		pageSize = defaultReviewPageSize
+	}
+	if pageSize > maxReviewPageSize {
+		pageSize = maxReviewPageSize
+	}
+
+	var after *pb.Review
+	if len(req.GetPageToken()) > 0 {
+		token, err := decodeReviewPageToken(req.GetPageToken())
+		if err != nil {
+			return nil, logError(status.Errorf(codes.InvalidArgument, "%v", err))
+		}
+		if token.LaptopID != req.GetLaptopId() || token.Status != int32(req.GetStatus()) {
+			return nil, logError(status.Error(codes.InvalidArgument, "page token doesn't match the request"))
+		}
+
+		after = &pb.Review{Id: token.ID, CreatedAt: timestampFromUnixNano(token.Time)}
+	}
+
+	claims, _ := UserClaimsFromContext(ctx)
+
+	var reviews []*pb.Review
+	more := false
+	err := server.reviewStore.List(req.GetLaptopId(), func(review *pb.Review) bool {
+		if after != nil && !isNewerReview(after, review) {
+			return true
+		}
+		if !canSeeReview(claims, review) {
+			return true
+		}
+		if req.GetStatus() != pb.Review_UNKNOWN && review.GetStatus() != req.GetStatus() {
+			return true
+		}
+
+		if len(reviews) == pageSize {
+			more = true
+			return false
+		}
+		reviews = append(reviews, review)
+		return true
+	})
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot list reviews: %v", err))
+	}
+
+	for _, review := range reviews {
+		err := server.fillScore(review)
+		if err != nil {
+			return nil, logError(err)
+		}
+	}
+
+	res := &pb.ListReviewsResponse{Reviews: reviews}
+	if more {
+		last := reviews[len(reviews)-1]
+		res.NextPageToken = encodeReviewPageToken(reviewPageToken{
+			LaptopID: req.GetLaptopId(),
+			Status:   int32(req.GetStatus()),
+			Time:     reviewTime(last),
+			ID:       last.GetId(),
+		})
+	}
+	return res, nil
+}
+
+// ApproveReview is a unary RPC for admins to publish a review
+func (server *ReviewServer) ApproveReview(
+	ctx context.Context,
+	req *pb.ApproveReviewRequest,
+) (*pb.ApproveReviewResponse, error) {
+	review, err := server.moderate(ctx, req.GetId(), pb.Review_APPROVED, "")
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.ApproveReviewResponse{Review: review}
+	return res, nil
+}
+
+// RejectReview is a unary RPC for admins to refuse to publish a review
+func (server *ReviewServer) RejectReview(
+	ctx context.Context,
+	req *pb.RejectReviewRequest,
+) (*pb.RejectReviewResponse, error) {
+	review, err := server.moderate(ctx, req.GetId(), pb.Review_REJECTED, req.GetReason())
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.RejectReviewResponse{Review: review}
+	return res, nil
+}
+
+// moderate sets the status of a review, the AuthInterceptor only lets admins moderate reviews
+func (server *ReviewServer) moderate(
+	ctx context.Context,
+	id string,
+	reviewStatus pb.Review_ModerationStatus,
+	reason string,
+) (*pb.Review, error) {
+	username, err := authenticatedUsername(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("receive a moderate-review request from %s with id: %s, status: %s", username, id, reviewStatus)
+
+	review, err := server.reviewStore.Find(id)
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot find review: %v", err))
+	}
+	if review == nil {
+		return nil, logError(status.Errorf(codes.NotFound, "review %s doesn't exist", id))
+	}
+
+	review.Status = reviewStatus
+	review.ModeratedBy = username
+	review.RejectionReason = reason
+
+	err = server.reviewStore.Update(review)
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot update review: %v", err))
+	}
+
+	err = server.fillScore(review)
+	if err != nil {
+		return nil, logError(err)
+	}
+
+	return review, nil
+}
+
+// findOwnReview finds a review that the user wrote. Errors are gRPC status errors.
+func (server *ReviewServer) findOwnReview(username string, id string) (*pb.Review, error) {
+	review, err := server.reviewStore.Find(id)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot find review: %v", err)
+	}
+	if review == nil {
+		return nil, status.Errorf(codes.NotFound, "review %s doesn't exist", id)
+	}
+	if review.GetUsername() != username {
+		return nil, status.Errorf(codes.PermissionDenied, "review %s is not written by %s", id, username)
+	}
+
+	return review, nil
+}
+
+// rate rates the laptop of the review with the score unless it is zero, and sets the score of the review.
+// Errors are gRPC status errors.
+func (server *ReviewServer) rate(review *pb.Review, score float64) error {
+	if score != 0 {
+		_, err := server.ratingStore.Rate(review.GetUsername(), review.GetLaptopId(), score)
+		if err != nil {
+			return status.Errorf(codes.Internal, "cannot rate laptop: %v", err)
+		}
+	}
+
+	return server.fillScore(review)
+}
+
+// fillScore sets the score that the author of the review gave to the laptop. Errors are gRPC status errors.
+func (server *ReviewServer) fillScore(review *pb.Review) error {
+	score, _, err := server.ratingStore.FindScore(review.GetUsername(), review.GetLaptopId())
+	if err != nil {
+		return status.Errorf(codes.Internal, "cannot find score: %v", err)
+	}
+
+	review.Score = score
+	return nil
+}
+
+// validateReview returns an InvalidArgument error if the text or the score of a review is invalid
+func validateReview(text string, score float64) error {
+	if len(strings.TrimSpace(text)) == 0 {
+		return status.Error(codes.InvalidArgument, "review text cannot be empty")
+	}
+	if utf8.RuneCountInString(text) > maxReviewLength {
+		return status.Errorf(codes.InvalidArgument, "review text cannot be longer than %d characters", maxReviewLength)
+	}
+	if score != 0 && !isValidScore(score) {
+		return status.Errorf(codes.InvalidArgument, "invalid score: %v", ErrInvalidScore)
+	}
+
+	return nil
+}
+
+// canSeeReview reports whether the user with the claims can see the review, claims is nil for anonymous users
+func canSeeReview(claims *UserClaims, review *pb.Review) bool {
+	if review.GetStatus() == pb.Review_APPROVED {
+		return true
+	}
+
+	return claims != nil && (claims.Role == adminRole || claims.Username == review.GetUsername())
+}
+
+// reviewPageToken is the position after which the next page of reviews starts
+type reviewPageToken struct {
+	LaptopID string `json:"l"`
+	Status   int32  `json:"s"`
+	Time     int64  `json:"t"`
+	ID       string `json:"i"`
+}
+
+func encodeReviewPageToken(token reviewPageToken) string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeReviewPageToken(s string) (reviewPageToken, error) {
+	token := reviewPageToken{}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return token, fmt.Errorf("invalid page token")
+	}
+
+	err = json.Unmarshal(data, &token)
+	if err != nil {
+		return token, fmt.Errorf("invalid page token")
+	}
+
+	return token, nil
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"github.com/treeforest/grpc-pcbook/sample"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReviewServer(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	ratingStore := NewInMemoryRatingStore()
+	jwtManager := NewJWTManager("secret", time.Minute)
+	serverAddress := startTestReviewServer(t, NewReviewServer(NewInMemoryReviewStore(), laptopStore, ratingStore), jwtManager)
+
+	conn, err := grpc.Dial(serverAddress, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	reviewClient := pb.NewReviewServiceClient(conn)
+
+	alice := newTestUserContext(t, jwtManager, "alice")
+	bob := newTestUserContext(t, jwtManager, "bob")
+	admin := newTestRoleContext(t, jwtManager, "carol", "admin")
+	anonymous := context.Background()
+
+	listReviews := func(ctx context.Context, reviewStatus pb.Review_ModerationStatus) []*pb.Review {
+		req := &pb.ListReviewsRequest{LaptopId: laptop.GetId(), Status: reviewStatus}
+		res, err := reviewClient.ListReviews(ctx, req)
+		require.NoError(t, err)
+		return res.GetReviews()
+	}
+
+	_, err = reviewClient.SubmitReview(anonymous, &pb.SubmitReviewRequest{LaptopId: laptop.GetId(), Text: "good"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	invalid := []*pb.SubmitReviewRequest{
+		{LaptopId: laptop.GetId(), Text: " "},
+		{LaptopId: laptop.GetId(), Text: strings.Repeat("a", maxReviewLength+1)},
+		{LaptopId: laptop.GetId(), Text: "good", Score: 11},
+	}
+	for _, req := range invalid {
+		_, err = reviewClient.SubmitReview(alice, req)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+
+	_, err = reviewClient.SubmitReview(alice, &pb.SubmitReviewRequest{LaptopId: "unknown", Text: "good"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	submitRes, err := reviewClient.SubmitReview(alice, &pb.SubmitReviewRequest{LaptopId: laptop.GetId(), Text: "good", Score: 8})
+	require.NoError(t, err)
+	aliceReview := submitRes.GetReview()
+	require.NotEmpty(t, aliceReview.GetId())
+	require.Equal(t, "alice", aliceReview.GetUsername())
+	require.Equal(t, pb.Review_PENDING, aliceReview.GetStatus())
+	require.Equal(t, 8.0, aliceReview.GetScore())
+
+	rating, err := ratingStore.Find(laptop.GetId())
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), rating.Count)
+
+	_, err = reviewClient.SubmitReview(alice, &pb.SubmitReviewRequest{LaptopId: laptop.GetId(), Text: "again"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	submitRes, err = reviewClient.SubmitReview(bob, &pb.SubmitReviewRequest{LaptopId: laptop.GetId(), Text: "bad"})
+	require.NoError(t, err)
+	bobReview := submitRes.GetReview()
+	require.Zero(t, bobReview.GetScore())
+
+	// pending reviews are only seen by their authors and the admins
+	require.Empty(t, listReviews(anonymous, pb.Review_UNKNOWN))
+	require.Len(t, listReviews(alice, pb.Review_UNKNOWN), 1)
+	require.Len(t, listReviews(admin, pb.Review_UNKNOWN), 2)
+	require.Len(t, listReviews(admin, pb.Review_PENDING), 2)
+
+	_, err = reviewClient.ApproveReview(alice, &pb.ApproveReviewRequest{Id: aliceReview.GetId()})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	approveRes, err := reviewClient.ApproveReview(admin, &pb.ApproveReviewRequest{Id: aliceReview.GetId()})
+	require.NoError(t, err)
+	require.Equal(t, pb.Review_APPROVED, approveRes.GetReview().GetStatus())
+	require.Equal(t, "carol", approveRes.GetReview().GetModeratedBy())
+	require.Equal(t, 8.0, approveRes.GetReview().GetScore())
+
+	rejectRes, err := reviewClient.RejectReview(admin, &pb.RejectReviewRequest{Id: bobReview.GetId(), Reason: "rude"})
+	require.NoError(t, err)
+	require.Equal(t, pb.Review_REJECTED, rejectRes.GetReview().GetStatus())
+	require.Equal(t, "rude", rejectRes.GetReview().GetRejectionReason())
+
+	_, err = reviewClient.ApproveReview(admin, &pb.ApproveReviewRequest{Id: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	reviews := listReviews(anonymous, pb.Review_UNKNOWN)
+	require.Len(t, reviews, 1)
+	require.Equal(t, aliceReview.GetId(), reviews[0].GetId())
+	require.Len(t, listReviews(bob, pb.Review_REJECTED), 1)
+	require.Empty(t, listReviews(alice, pb.Review_REJECTED))
+
+	// only the author edits or deletes a review, editing it waits for moderation again
+	_, err = reviewClient.EditReview(bob, &pb.EditReviewRequest{Id: aliceReview.GetId(), Text: "bad"})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = reviewClient.DeleteReview(admin, &pb.DeleteReviewRequest{Id: aliceReview.GetId()})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	editRes, err := reviewClient.EditReview(alice, &pb.EditReviewRequest{Id: aliceReview.GetId(), Text: "very good", Score: 9})
+	require.NoError(t, err)
+	require.Equal(t, "very good", editRes.GetReview().GetText())
+	require.Equal(t, pb.Review_PENDING, editRes.GetReview().GetStatus())
+	require.Empty(t, editRes.GetReview().GetModeratedBy())
+	require.Equal(t, 9.0, editRes.GetReview().GetScore())
+	require.Empty(t, listReviews(anonymous, pb.Review_UNKNOWN))
+
+	_, err = reviewClient.DeleteReview(alice, &pb.DeleteReviewRequest{Id: aliceReview.GetId()})
+	require.NoError(t, err)
+	_, err = reviewClient.DeleteReview(alice, &pb.DeleteReviewRequest{Id: aliceReview.GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Len(t, listReviews(admin, pb.Review_UNKNOWN), 1)
+
+	// the score stays when the review is deleted
+	rating, err = ratingStore.Find(laptop.GetId())
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), rating.Count)
+	require.Equal(t, 9.0, rating.Sum)
+}
+
+func TestReviewServerListReviewsPage(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	reviewStore := NewInMemoryReviewStore()
+	reviewServer := NewReviewServer(reviewStore, laptopStore, NewInMemoryRatingStore())
+
+	n := 7
+	now := time.Now()
+	for i := 0; i < n; i++ {
+		review := &pb.Review{
+			Id:        string(rune('a' + i)),
+			LaptopId:  laptop.GetId(),
+			Username:  string(rune('a' + i)),
+			Text:      "good",
+			Status:    pb.Review_APPROVED,
+			CreatedAt: timestampFromUnixNano(now.Add(time.Duration(i) * time.Second).UnixNano()),
+		}
+		require.NoError(t, reviewStore.Save(review))
+	}
+
+	var ids []string
+	pageToken := ""
+	for {
+		req := &pb.ListReviewsRequest{LaptopId: laptop.GetId(), PageSize: 3, PageToken: pageToken}
+		res, err := reviewServer.ListReviews(context.Background(), req)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(res.GetReviews()), 3)
+
+		for _, review := range res.GetReviews() {
+			ids = append(ids, review.GetId())
+		}
+
+		pageToken = res.GetNextPageToken()
+		if pageToken == "" {
+			break
+		}
+	}
+	require.Equal(t, []string{"g", "f", "e", "d", "c", "b", "a"}, ids)
+
+	_, err := reviewServer.ListReviews(context.Background(), &pb.ListReviewsRequest{LaptopId: laptop.GetId(), PageToken: "?"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	res, err := reviewServer.ListReviews(context.Background(), &pb.ListReviewsRequest{LaptopId: laptop.GetId(), PageSize: 3})
+	require.NoError(t, err)
+	req := &pb.ListReviewsRequest{LaptopId: "other", PageSize: 3, PageToken: res.GetNextPageToken()}
+	_, err = reviewServer.ListReviews(context.Background(), req)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func startTestReviewServer(t *testing.T, reviewServer pb.ReviewServiceServer, jwtManager *JWTManager) string {
+	const reviewServicePath = "/techschool.pcbook.ReviewService/"
+	interceptor := NewAuthInterceptor(jwtManager, nil, nil, nil, NewAuthPolicy(map[string][]string{
+		reviewServicePath + "SubmitReview":  {"admin", "user"},
+		reviewServicePath + "EditReview":    {"admin", "user"},
+		reviewServicePath + "DeleteReview":  {"admin", "user"},
+		reviewServicePath + "ApproveReview": {"admin"},
+		reviewServicePath + "RejectReview":  {"admin"},
+		reviewServicePath + "ListReviews":   nil,
+	}))
+
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(interceptor.Unary()),
+		grpc.StreamInterceptor(interceptor.Stream()),
+	)
+	pb.RegisterReviewServiceServer(grpcServer, reviewServer)
+
+	listener, err := net.Listen("tcp", ":0") // random available port
+	require.NoError(t, err)
+
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	return listener.Addr().String()
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReviewStore is an interface to store the reviews of laptops, a user writes at most one review per laptop
+type ReviewStore interface {
+	// Save saves a new review, returns ErrAlreadyExists if the user has already reviewed the laptop
+	Save(review *pb.Review) error
+	// Find finds a review by ID, returns nil if there is none
+	Find(id string) (*pb.Review, error)
+	// Update replaces an existing review
+	Update(review *pb.Review) error
+	// Delete deletes a review by ID
+	Delete(id string) error
+	// List calls found with the reviews of a laptop from the newest to the oldest, until found returns false
+	List(laptopID string, found func(review *pb.Review) bool) error
+}
+
+// InMemoryReviewStore stores reviews in memory
+type InMemoryReviewStore struct {
+	mutex   sync.RWMutex
+	reviews map[string]*pb.Review
+	// IDs of the reviews of each laptop by username
+	laptopReviews map[string]map[string]string
+}
+
+// NewInMemoryReviewStore returns a new InMemoryReviewStore
+func NewInMemoryReviewStore() *InMemoryReviewStore {
+	return &InMemoryReviewStore{
+		reviews:       make(map[string]*pb.Review),
+		laptopReviews: make(map[string]map[string]string),
+	}
+}
+
+// Save saves a new review, returns ErrAlreadyExists if the user has already reviewed the laptop
+func (store *InMemoryReviewStore) Save(review *pb.Review) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.isSaved(review) {
+		return ErrAlreadyExists
+	}
+
+	store.set(proto.Clone(review).(*pb.Review))
+	return nil
+}
+
+// Find finds a review by ID, returns nil if there is none
+func (store *InMemoryReviewStore) Find(id string) (*pb.Review, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	review := store.reviews[id]
+	if review == nil {
+		return nil, nil
+	}
+
+	return proto.Clone(review).(*pb.Review), nil
+}
+
+// Update replaces an existing review, its laptop and author cannot change
+func (store *InMemoryReviewStore) Update(review *pb.Review) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	stored := store.reviews[review.GetId()]
+	if stored == nil {
+		return ErrNotFound
+	}
+
+	other := proto.Clone(review).(*pb.Review)
+	other.LaptopId = stored.GetLaptopId()
+	other.Username = stored.GetUsername()
+	store.reviews[review.GetId()] = other
+	return nil
+}
+
+// Delete deletes a review by ID
+func (store *InMemoryReviewStore) Delete(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.reviews[id] == nil {
+		return ErrNotFound
+	}
+
+	store.unset(id)
+	return nil
+}
+
+// exists reports whether a review has the same ID, or the same laptop and author as the review
+func (store *InMemoryReviewStore) exists(review *pb.Review) bool {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return store.isSaved(review)
+}
+
+// isSaved reports whether a review has the same ID, or the same laptop and author as the review,
+// the caller must hold the lock
+func (store *InMemoryReviewStore) isSaved(review *pb.Review) bool {
+	if store.reviews[review.GetId()] != nil {
+		return true
+	}
+
+	_, ok := store.laptopReviews[review.GetLaptopId()][review.GetUsername()]
+	return ok
+}
+
+// put stores a copy of the review, replacing the stored review with the same ID
+func (store *InMemoryReviewStore) put(review *pb.Review) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.unset(review.GetId())
+	store.set(proto.Clone(review).(*pb.Review))
+}
+
+// remove deletes a review by ID if it exists
+func (store *InMemoryReviewStore) remove(id string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.unset(id)
+}
+
+// set stores the review and indexes it by laptop and author, the caller must hold the write lock
+func (store *InMemoryReviewStore) set(review *pb.Review) {
+	byUser := store.laptopReviews[review.GetLaptopId()]
+	if byUser == nil {
+		byUser = make(map[string]string)
+		store.laptopReviews[review.GetLaptopId()] = byUser
+	}
+
+	byUser[review.GetUsername()] = review.GetId()
+	store.reviews[review.GetId()] = review
+}
+
+// unset removes the review and its index entry, the caller must hold the write lock
+func (store *InMemoryReviewStore) unset(id string) {
+	review := store.reviews[id]
+	if review == nil {
+		return
+	}
+
+	delete(store.reviews, id)
+	delete(store.laptopReviews[review.GetLaptopId()], review.GetUsername())
+	if len(store.laptopReviews[review.GetLaptopId()]) == 0 {
+		delete(store.laptopReviews, review.GetLaptopId())
+	}
+}
+
+// List calls found with the reviews of a laptop from the newest to the oldest, until found returns false
+func (store *InMemoryReviewStore) List(laptopID string, found func(review *pb.Review) bool) error {
+	store.mutex.RLock()
+	reviews := make([]*pb.Review, 0, len(store.laptopReviews[laptopID]))
+	for _, id := range store.laptopReviews[laptopID] {
+		reviews = append(reviews, proto.Clone(store.reviews[id]).(*pb.Review))
+	}
+	store.mutex.RUnlock()
+
+	sort.Slice(reviews, func(i, j int) bool {
+		return isNewerReview(reviews[i], reviews[j])
+	})
+
+	for _, review := range reviews {
+		if !found(review) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// isNewerReview reports whether review a was created after review b, reviews created at the same time
+// are ordered by decreasing ID
+func isNewerReview(a, b *pb.Review) bool {
+	timeA, timeB := reviewTime(a), reviewTime(b)
+	if timeA != timeB {
+		return timeA > timeB
+	}
+	return a.GetId() > b.GetId()
+}
+
+// reviewTime returns the creation time of a review in nanoseconds since the Unix epoch
+func reviewTime(review *pb.Review) int64 {
+	createdAt, err := ptypes.Timestamp(review.GetCreatedAt())
+	if err != nil {
+		return 0
+	}
+	return createdAt.UnixNano()
+}
+
+// timestampFromUnixNano returns the timestamp of a time in nanoseconds since the Unix epoch
+func timestampFromUnixNano(nanos int64) *tspb.Timestamp {
+	timestamp, _ := ptypes.TimestampProto(time.Unix(0, nanos))
+	return timestamp
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"testing"
+	"time"
+)
+
+func TestInMemoryReviewStore(t *testing.T) {
+	t.Parallel()
+
+	newStore := func(t *testing.T) ReviewStore {
+		return NewInMemoryReviewStore()
+	}
+
+	t.Run("save", func(t *testing.T) {
+		store := newStore(t)
+
+		review := newTestReview(t, "laptop1", "alice", time.Unix(100, 0))
+		require.NoError(t, store.Save(review))
+
+		other, err := store.Find(review.GetId())
+		require.NoError(t, err)
+		require.Equal(t, review.GetText(), other.GetText())
+
+		require.Equal(t, ErrAlreadyExists, store.Save(review))
+		require.Equal(t, ErrAlreadyExists, store.Save(newTestReview(t, "laptop1", "alice", time.Unix(200, 0))))
+		require.NoError(t, store.Save(newTestReview(t, "laptop2", "alice", time.Unix(200, 0))))
+
+		other, err = store.Find("unknown")
+		require.NoError(t, err)
+		require.Nil(t, other)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		store := newStore(t)
+
+		review := newTestReview(t, "laptop1", "alice", time.Unix(100, 0))
+		require.NoError(t, store.Save(review))
+
+		review.Text = "changed"
+		review.Username = "bob"
+		require.NoError(t, store.Update(review))
+
+		other, err := store.Find(review.GetId())
+		require.NoError(t, err)
+		require.Equal(t, "changed", other.GetText())
+		require.Equal(t, "alice", other.GetUsername())
+
+		require.Equal(t, ErrNotFound, store.Update(newTestReview(t, "laptop1", "bob", time.Unix(100, 0))))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		store := newStore(t)
+
+		review := newTestReview(t, "laptop1", "alice", time.Unix(100, 0))
+		require.NoError(t, store.Save(review))
+		require.NoError(t, store.Delete(review.GetId()))
+		require.Equal(t, ErrNotFound, store.Delete(review.GetId()))
+
+		// the author can review the laptop again
+		require.NoError(t, store.Save(newTestReview(t, "laptop1", "alice", time.Unix(200, 0))))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		store := newStore(t)
+
+		old := newTestReview(t, "laptop1", "alice", time.Unix(100, 0))
+		recent := newTestReview(t, "laptop1", "bob", time.Unix(200, 0))
+		require.NoError(t, store.Save(old))
+		require.NoError(t, store.Save(recent))
+		require.NoError(t, store.Save(newTestReview(t, "laptop2", "carol", time.Unix(300, 0))))
+
+		require.Equal(t, []string{recent.GetId(), old.GetId()}, listTestReviewIDs(t, store, "laptop1", 10))
+		require.Equal(t, []string{recent.GetId()}, listTestReviewIDs(t, store, "laptop1", 1))
+	})
+}
+
+func TestFileReviewStore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	store, err := NewFileReviewStore(dir)
+	require.NoError(t, err)
+
+	review1 := newTestReview(t, "laptop1", "alice", time.Unix(100, 0))
+	review2 := newTestReview(t, "laptop1", "bob", time.Unix(200, 0))
+	review3 := newTestReview(t, "laptop1", "carol", time.Unix(300, 0))
+	require.NoError(t, store.Save(review1))
+	require.NoError(t, store.Save(review2))
+	require.NoError(t, store.snapshot())
+
+	// a second review of the author is rejected before it reaches the log
+	require.Equal(t, ErrAlreadyExists, store.Save(newTestReview(t, "laptop1", "alice", time.Unix(150, 0))))
+	require.Zero(t, store.wal.count)
+
+	// the logged review keeps its laptop and author
+	review1.Status = pb.Review_APPROVED
+	review1.Username = "mallory"
+	require.NoError(t, store.Update(review1))
+	require.NoError(t, store.Delete(review2.GetId()))
+	require.NoError(t, store.Save(review3))
+	require.NoError(t, store.Close())
+
+	store, err = NewFileReviewStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	other, err := store.Find(review1.GetId())
+	require.NoError(t, err)
+	require.Equal(t, pb.Review_APPROVED, other.GetStatus())
+	require.Equal(t, "alice", other.GetUsername())
+
+	other, err = store.Find(review2.GetId())
+	require.NoError(t, err)
+	require.Nil(t, other)
+
+	require.Equal(t, []string{review3.GetId(), review1.GetId()}, listTestReviewIDs(t, store, "laptop1", 10))
+	require.Equal(t, ErrAlreadyExists, store.Save(newTestReview(t, "laptop1", "carol", time.Unix(400, 0))))
+	// the deleted review doesn't keep its author from reviewing the laptop again
+	require.NoError(t, store.Save(newTestReview(t, "laptop1", "bob", time.Unix(500, 0))))
+}
+
+func newTestReview(t *testing.T, laptopID string, username string, createdAt time.Time) *pb.Review {
+	timestamp, err := ptypes.TimestampProto(createdAt)
+	require.NoError(t, err)
+
+	return &pb.Review{
+		Id:        uuid.New().String(),
+		LaptopId:  laptopID,
+		Username:  username,
+		Text:      "good",
+		Status:    pb.Review_PENDING,
+		CreatedAt: timestamp,
+		UpdatedAt: timestamp,
+	}
+}
+
+func listTestReviewIDs(t *testing.T, store ReviewStore, laptopID string, limit int) []string {
+	var ids []string
+	err := store.List(laptopID, func(review *pb.Review) bool {
+		ids = append(ids, review.GetId())
+		return len(ids) < limit
+	})
+	require.NoError(t, err)
+	return ids
+}
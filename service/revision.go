@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ETagHeader is the response metadata key that carries the revision of a laptop
+	ETagHeader = "etag"
+	// IfMatchHeader is the request metadata key that carries the revision a write expects
+	IfMatchHeader = "if-match"
+)
+
+// formatETag returns the strong entity tag of a revision
+func formatETag(revision uint64) string {
+	return strconv.Quote(strconv.FormatUint(revision, 10))
+}
+
+// parseETag returns the revision of an entity tag, "*" matches any revision and returns zero
+func parseETag(etag string) (uint64, error) {
+	etag = strings.TrimSpace(etag)
+	if etag == "*" {
+		return 0, nil
+	}
+
+	value, err := strconv.Unquote(etag)
+	if err != nil {
+		value = etag
+	}
+
+	revision, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid etag %s", etag)
+	}
+
+	return revision, nil
+}
+
+// expectedRevision returns the revision a write request expects. A revision in the request
+// message wins over the if-match header, zero means the write is unconditional.
+func expectedRevision(ctx context.Context, revision uint64) (uint64, error) {
+	if revision != 0 {
+		return revision, nil
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, nil
+	}
+
+	values := md[IfMatchHeader]
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	return parseETag(values[0])
+}
+
+// sendETag sends the revision of a laptop as the etag response header
+func sendETag(ctx context.Context, revision uint64) {
+	// an error only means the handler was not called through a gRPC transport
+	_ = grpc.SetHeader(ctx, metadata.Pairs(ETagHeader, formatETag(revision)))
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/uuid"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log"
+	"math"
+)
+
+// SavedSearchServer is the server that provide saved search service
+type SavedSearchServer struct {
+	pb.UnimplementedSavedSearchServiceServer
+	savedSearchStore SavedSearchStore
+	laptopStore      LaptopStore
+}
+
+// NewSavedSearchServer returns a new SavedSearchServer
+func NewSavedSearchServer(savedSearchStore SavedSearchStore, laptopStore LaptopStore) *SavedSearchServer {
+	return &SavedSearchServer{
+		savedSearchStore: savedSearchStore,
+		laptopStore:      laptopStore,
+	}
+}
+
+// CreateSavedSearch is a unary RPC to save a search of the authenticated user
+func (server *SavedSearchServer) CreateSavedSearch(
+	ctx context.Context,
+	req *pb.CreateSavedSearchRequest,
+) (*pb.CreateSavedSearchResponse, error) {
+	username, err := authenticatedUsername(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("receive a create-saved-search request from %s with filter: %v", username, req.GetFilter())
+
+	_, err = parseSavedSearchExpr(req.GetFilterExpr())
+	if err != nil {
+		return nil, logError(status.Errorf(codes.InvalidArgument, "invalid filter_expr: %v", err))
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot generate a new saved search ID: %v", err))
+	}
+
+	search := &pb.SavedSearch{
+		Id:         id.String(),
+		Name:       req.GetName(),
+		Filter:     savedSearchFilter(req.GetFilter()),
+		FilterExpr: req.GetFilterExpr(),
+		CreatedAt:  ptypes.TimestampNow(),
+	}
+
+	err = server.savedSearchStore.Save(username, search)
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot save saved search: %v", err))
+	}
+
+	log.Printf("saved search with id: %s", search.Id)
+
+	res := &pb.CreateSavedSearchResponse{SavedSearch: search}
+	return res, nil
+}
+
+// ListSavedSearches is a unary RPC to list the saved searches of the authenticated user
+func (server *SavedSearchServer) ListSavedSearches(
+	ctx context.Context,
+	req *pb.ListSavedSearchesRequest,
+) (*pb.ListSavedSearchesResponse, error) {
+	username, err := authenticatedUsername(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	searches, err := server.savedSearchStore.List(username)
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot list saved searches: %v", err))
+	}
+
+	res := &pb.ListSavedSearchesResponse{SavedSearches: searches}
+	return res, nil
+}
+
+// DeleteSavedSearch is a unary RPC to delete a saved search of the authenticated user
+func (server *SavedSearchServer) DeleteSavedSearch(
+	ctx context.Context,
+	req *pb.DeleteSavedSearchRequest,
+) (*pb.DeleteSavedSearchResponse, error) {
+	username, err := authenticatedUsername(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("receive a delete-saved-search request from %s with id: %s", username, req.GetId())
+
+	err = server.savedSearchStore.Delete(username, req.GetId())
+	if err == ErrNotFound {
+		return nil, logError(status.Errorf(codes.NotFound, "saved search %s doesn't exist", req.GetId()))
+	}
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot delete saved search: %v", err))
+	}
+
+	res := &pb.DeleteSavedSearchResponse{Id: req.GetId()}
+	return res, nil
+}
+
+// WatchSavedSearchMatches is a server-streaming RPC to watch the laptops that start matching
+// a saved search of the authenticated user. Every change of the catalog is matched against
+// the saved searches once, as it happens.
+func (server *SavedSearchServer) WatchSavedSearchMatches(
+	req *pb.WatchSavedSearchMatchesRequest,
+	stream pb.SavedSearchService_WatchSavedSearchMatchesServer,
+) error {
+	ctx := stream.Context()
+	username, err := authenticatedUsername(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("receive a watch-saved-search-matches request from %s after sequence: %d", username, req.GetAfterSequence())
+
+	// the expressions of the saved searches are only parsed once
+	exprs := make(map[string]*FilterExpr)
+
+	err = server.laptopStore.Watch(ctx, req.GetAfterSequence(), func(change *LaptopChange) error {
+		searches, err := server.savedSearchStore.List(username)
+		if err != nil {
+			return status.Errorf(codes.Internal, "cannot list saved searches: %v", err)
+		}
+
+		for _, search := range searches {
+			expr, ok := exprs[search.GetId()]
+			if !ok {
+				expr, err = parseSavedSearchExpr(search.GetFilterExpr())
+				if err != nil {
+					return status.Errorf(codes.Internal, "cannot parse saved search %s: %v", search.GetId(), err)
+				}
+				exprs[search.GetId()] = expr
+			}
+
+			reason := matchSavedSearch(search.GetFilter(), expr, change)
+			if reason == pb.SavedSearchMatch_UNKNOWN {
+				continue
+			}
+
+			res := &pb.WatchSavedSearchMatchesResponse{
+				Match: &pb.SavedSearchMatch{
+					SavedSearchId: search.GetId(),
+					Reason:        reason,
+					Laptop:        change.Event.GetLaptop(),
+					Sequence:      change.Event.GetSequence(),
+				},
+			}
+
+			err = stream.Send(res)
+			if err != nil {
+				return status.Errorf(codes.Unknown, "cannot send stream response: %v", err)
+			}
+
+			log.Printf("sent %s match of saved search %s with laptop id: %s", reason, search.GetId(), change.Event.GetLaptop().GetId())
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, ErrUnknownSequence) {
+		return logError(status.Errorf(codes.OutOfRange, "cannot resume after sequence %d: %v", req.GetAfterSequence(), err))
+	}
+	if err := contextError(ctx); err != nil {
+		return err
+	}
+	if _, ok := status.FromError(err); ok {
+		return logError(err)
+	}
+	return logError(status.Errorf(codes.Internal, "cannot watch saved search matches: %v", err))
+}
+
+// authenticatedUsername returns the username of the user authorized by the AuthInterceptor
+func authenticatedUsername(ctx context.Context) (string, error) {
+	claims, ok := UserClaimsFromContext(ctx)
+	if !ok || len(claims.Username) == 0 {
+		return "", logError(status.Error(codes.Unauthenticated, "user is not authenticated"))
+	}
+	return claims.Username, nil
+}
+
+// savedSearchFilter returns the filter to save, a filter without a max price has no price limit
+// as in a search, otherwise no laptop would ever match it
+func savedSearchFilter(filter *pb.Filter) *pb.Filter {
+	if filter == nil || filter.GetMaxPriceUsd() != 0 {
+		return filter
+	}
+
+	filter = proto.Clone(filter).(*pb.Filter)
+	filter.MaxPriceUsd = math.Inf(1)
+	return filter
+}
+
+// parseSavedSearchExpr parses the filter expression of a saved search, which may be empty
+func parseSavedSearchExpr(filterExpr string) (*FilterExpr, error) {
+	if len(filterExpr) == 0 {
+		return nil, nil
+	}
+	return ParseFilterExpr(filterExpr)
+}
+
+// matchSavedSearch returns why a change of a laptop is a new match of a saved search,
+// or UNKNOWN if it is not. A missing filter matches every laptop.
+func matchSavedSearch(filter *pb.Filter, expr *FilterExpr, change *LaptopChange) pb.SavedSearchMatch_Reason {
+	laptop := change.Event.GetLaptop()
+	switch change.Event.GetType() {
+	case pb.LaptopEvent_CREATED:
+		if isWatched(filter, expr, laptop) {
+			return pb.SavedSearchMatch_NEW_LAPTOP
+		}
+
+	case pb.LaptopEvent_UPDATED:
+		if !isWatched(filter, expr, laptop) || isWatched(filter, expr, change.Previous) {
+			break
+		}
+
+		limit := filter.GetMaxPriceUsd()
+		if limit > 0 && change.Previous.GetPriceUsd() > limit {
+			return pb.SavedSearchMatch_PRICE_DROP
+		}
+		return pb.SavedSearchMatch_NOW_MATCHING
+	}
+
+	return pb.SavedSearchMatch_UNKNOWN
+}
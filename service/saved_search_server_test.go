@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"github.com/treeforest/grpc-pcbook/sample"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMatchSavedSearch(t *testing.T) {
+	t.Parallel()
+
+	filter := &pb.Filter{MaxPriceUsd: 2000, MinCpuCores: 4}
+
+	newLaptop := func(price float64, cores uint32) *pb.Laptop {
+		laptop := sample.NewLaptop()
+		laptop.PriceUsd = price
+		laptop.Cpu.NumberCores = cores
+		return laptop
+	}
+
+	newChange := func(eventType pb.LaptopEvent_Type, laptop *pb.Laptop, previous *pb.Laptop) *LaptopChange {
+		return &LaptopChange{
+			Event:    &pb.LaptopEvent{Type: eventType, Laptop: laptop},
+			Previous: previous,
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		filter *pb.Filter
+		change *LaptopChange
+		reason pb.SavedSearchMatch_Reason
+	}{
+		{"new_match", filter, newChange(pb.LaptopEvent_CREATED, newLaptop(1500, 4), nil), pb.SavedSearchMatch_NEW_LAPTOP},
+		{"new_no_match", filter, newChange(pb.LaptopEvent_CREATED, newLaptop(2500, 4), nil), pb.SavedSearchMatch_UNKNOWN},
+		{"no_filter", nil, newChange(pb.LaptopEvent_CREATED, newLaptop(2500, 2), nil), pb.SavedSearchMatch_NEW_LAPTOP},
+		{"price_drop", filter, newChange(pb.LaptopEvent_UPDATED, newLaptop(1900, 4), newLaptop(2100, 4)), pb.SavedSearchMatch_PRICE_DROP},
+		{"price_drop_no_match", filter, newChange(pb.LaptopEvent_UPDATED, newLaptop(1900, 2), newLaptop(2100, 2)), pb.SavedSearchMatch_UNKNOWN},
+		{"cheaper_match", filter, newChange(pb.LaptopEvent_UPDATED, newLaptop(1500, 4), newLaptop(1900, 4)), pb.SavedSearchMatch_UNKNOWN},
+		{"now_matching", filter, newChange(pb.LaptopEvent_UPDATED, newLaptop(1500, 8), newLaptop(1500, 2)), pb.SavedSearchMatch_NOW_MATCHING},
+		{"deleted", filter, newChange(pb.LaptopEvent_DELETED, newLaptop(1500, 4), nil), pb.SavedSearchMatch_UNKNOWN},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.reason, matchSavedSearch(tc.filter, nil, tc.change))
+		})
+	}
+}
+
+func TestSavedSearchServer(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	jwtManager := NewJWTManager("secret", time.Minute)
+	serverAddress := startTestSavedSearchServer(t, laptopStore, jwtManager)
+
+	conn, err := grpc.Dial(serverAddress, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	savedSearchClient := pb.NewSavedSearchServiceClient(conn)
+
+	alice := newTestUserContext(t, jwtManager, "alice")
+	bob := newTestUserContext(t, jwtManager, "bob")
+
+	_, err = savedSearchClient.ListSavedSearches(context.Background(), &pb.ListSavedSearchesRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	req := &pb.CreateSavedSearchRequest{Name: "cheap", Filter: &pb.Filter{MaxPriceUsd: 1000}}
+	res, err := savedSearchClient.CreateSavedSearch(alice, req)
+	require.NoError(t, err)
+	cheap := res.GetSavedSearch()
+	require.NotEmpty(t, cheap.GetId())
+
+	req = &pb.CreateSavedSearchRequest{Name: "dell", FilterExpr: `brand == "Dell"`}
+	res, err = savedSearchClient.CreateSavedSearch(alice, req)
+	require.NoError(t, err)
+	dell := res.GetSavedSearch()
+
+	// a filter without a max price matches laptops at any price
+	req = &pb.CreateSavedSearchRequest{Name: "cores", Filter: &pb.Filter{MinCpuCores: 2}}
+	res, err = savedSearchClient.CreateSavedSearch(bob, req)
+	require.NoError(t, err)
+	cores := res.GetSavedSearch()
+
+	req = &pb.CreateSavedSearchRequest{Name: "bob", Filter: &pb.Filter{MaxPriceUsd: 5000}}
+	_, err = savedSearchClient.CreateSavedSearch(bob, req)
+	require.NoError(t, err)
+
+	req = &pb.CreateSavedSearchRequest{FilterExpr: `brand ==`}
+	_, err = savedSearchClient.CreateSavedSearch(alice, req)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	list, err := savedSearchClient.ListSavedSearches(alice, &pb.ListSavedSearchesRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.GetSavedSearches(), 2)
+	require.Equal(t, cheap.GetId(), list.GetSavedSearches()[0].GetId())
+
+	// a user cannot delete the saved searches of another user
+	_, err = savedSearchClient.DeleteSavedSearch(bob, &pb.DeleteSavedSearchRequest{Id: dell.GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	laptop := sample.NewLaptop()
+	laptop.Brand = "Lenovo"
+	laptop.PriceUsd = 1200
+	require.NoError(t, laptopStore.Save(laptop))
+
+	stream, err := savedSearchClient.WatchSavedSearchMatches(alice, &pb.WatchSavedSearchMatchesRequest{AfterSequence: 1})
+	require.NoError(t, err)
+
+	laptop.PriceUsd = 900
+	require.NoError(t, laptopStore.Update(laptop))
+
+	dellLaptop := sample.NewLaptop()
+	dellLaptop.Brand = "Dell"
+	dellLaptop.PriceUsd = 3000
+	require.NoError(t, laptopStore.Save(dellLaptop))
+
+	match, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, cheap.GetId(), match.GetMatch().GetSavedSearchId())
+	require.Equal(t, pb.SavedSearchMatch_PRICE_DROP, match.GetMatch().GetReason())
+	require.Equal(t, laptop.Id, match.GetMatch().GetLaptop().GetId())
+	require.Equal(t, uint64(2), match.GetMatch().GetSequence())
+
+	match, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, dell.GetId(), match.GetMatch().GetSavedSearchId())
+	require.Equal(t, pb.SavedSearchMatch_NEW_LAPTOP, match.GetMatch().GetReason())
+	require.Equal(t, dellLaptop.Id, match.GetMatch().GetLaptop().GetId())
+
+	_, err = savedSearchClient.DeleteSavedSearch(alice, &pb.DeleteSavedSearchRequest{Id: dell.GetId()})
+	require.NoError(t, err)
+
+	// the deleted saved search no longer matches
+	otherDell := sample.NewLaptop()
+	otherDell.Brand = "Dell"
+	otherDell.PriceUsd = 800
+	require.NoError(t, laptopStore.Save(otherDell))
+
+	match, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, cheap.GetId(), match.GetMatch().GetSavedSearchId())
+	require.Equal(t, otherDell.Id, match.GetMatch().GetLaptop().GetId())
+
+	expensive := sample.NewLaptop()
+	expensive.Cpu.NumberCores = 8
+	expensive.PriceUsd = 9000
+	require.NoError(t, laptopStore.Save(expensive))
+
+	bobStream, err := savedSearchClient.WatchSavedSearchMatches(bob, &pb.WatchSavedSearchMatchesRequest{AfterSequence: 4})
+	require.NoError(t, err)
+
+	match, err = bobStream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, cores.GetId(), match.GetMatch().GetSavedSearchId())
+	require.Equal(t, pb.SavedSearchMatch_NEW_LAPTOP, match.GetMatch().GetReason())
+	require.Equal(t, expensive.Id, match.GetMatch().GetLaptop().GetId())
+}
+
+func startTestSavedSearchServer(t *testing.T, laptopStore LaptopStore, jwtManager *JWTManager) string {
+	savedSearchServer := NewSavedSearchServer(NewInMemorySavedSearchStore(), laptopStore)
+
+	const savedSearchServicePath = "/techschool.pcbook.SavedSearchService/"
+	interceptor := NewAuthInterceptor(jwtManager, nil, nil, nil, NewAuthPolicy(map[string][]string{
+		savedSearchServicePath + "CreateSavedSearch":       {"user"},
+		savedSearchServicePath + "ListSavedSearches":       {"user"},
+		savedSearchServicePath + "DeleteSavedSearch":       {"user"},
+		savedSearchServicePath + "WatchSavedSearchMatches": {"user"},
+	}))
+
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(interceptor.Unary()),
+		grpc.StreamInterceptor(interceptor.Stream()),
+	)
+	pb.RegisterSavedSearchServiceServer(grpcServer, savedSearchServer)
+
+	listener, err := net.Listen("tcp", ":0") // random available port
+	require.NoError(t, err)
+
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	return listener.Addr().String()
+}
+
+// newTestUserContext returns a context with the access token of a user
+func newTestUserContext(t *testing.T, jwtManager *JWTManager, username string) context.Context {
+	return newTestRoleContext(t, jwtManager, username, "user")
+}
+
+// newTestRoleContext returns a context with the access token of a user with the role
+func newTestRoleContext(t *testing.T, jwtManager *JWTManager, username string, role string) context.Context {
+	token, err := jwtManager.Generate(&User{Username: username, Role: role})
+	require.NoError(t, err)
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", token)
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"sync"
+)
+
+// SavedSearchStore is an interface to store the saved searches of users
+type SavedSearchStore interface {
+	// Save saves a saved search of the user
+	Save(username string, search *pb.SavedSearch) error
+	// List returns the saved searches of the user in the order they were saved
+	List(username string) ([]*pb.SavedSearch, error)
+	// Delete deletes a saved search of the user by ID
+	Delete(username string, id string) error
+}
+
+// InMemorySavedSearchStore stores saved searches in memory
+type InMemorySavedSearchStore struct {
+	mutex    sync.RWMutex
+	searches map[string][]*pb.SavedSearch
+}
+
+// NewInMemorySavedSearchStore returns a new in-memory saved search store
+func NewInMemorySavedSearchStore() *InMemorySavedSearchStore {
+	return &InMemorySavedSearchStore{
+		searches: make(map[string][]*pb.SavedSearch),
+	}
+}
+
+// Save saves a saved search of the user
+func (store *InMemorySavedSearchStore) Save(username string, search *pb.SavedSearch) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.find(username, search.GetId()) >= 0 {
+		return ErrAlreadyExists
+	}
+
+	store.searches[username] = append(store.searches[username], proto.Clone(search).(*pb.SavedSearch))
+	return nil
+}
+
+// List returns the saved searches of the user in the order they were saved
+func (store *InMemorySavedSearchStore) List(username string) ([]*pb.SavedSearch, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	searches := make([]*pb.SavedSearch, len(store.searches[username]))
+	for i, search := range store.searches[username] {
+		searches[i] = proto.Clone(search).(*pb.SavedSearch)
+	}
+
+	return searches, nil
+}
+
+// Delete deletes a saved search of the user by ID
+func (store *InMemorySavedSearchStore) Delete(username string, id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.find(username, id) < 0 {
+		return ErrNotFound
+	}
+
+	store.unset(username, id)
+	return nil
+}
+
+// exists reports whether the user has a saved search with the ID
+func (store *InMemorySavedSearchStore) exists(username string, id string) bool {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return store.find(username, id) >= 0
+}
+
+// put stores a copy of the saved search of the user unless the user already has one with the same ID
+func (store *InMemorySavedSearchStore) put(username string, search *pb.SavedSearch) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.find(username, search.GetId()) < 0 {
+		store.searches[username] = append(store.searches[username], proto.Clone(search).(*pb.SavedSearch))
+	}
+}
+
+// remove deletes a saved search of the user by ID if it exists
+func (store *InMemorySavedSearchStore) remove(username string, id string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.unset(username, id)
+}
+
+// find returns the index of the saved search of the user with the ID, or -1 if there is none.
+// The caller must hold the lock.
+func (store *InMemorySavedSearchStore) find(username string, id string) int {
+	for i, search := range store.searches[username] {
+		if search.GetId() == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// unset removes a saved search of the user by ID, the caller must hold the write lock
+func (store *InMemorySavedSearchStore) unset(username string, id string) {
+	i := store.find(username, id)
+	if i < 0 {
+		return
+	}
+
+	searches := store.searches[username]
+	store.searches[username] = append(searches[:i:i], searches[i+1:]...)
+	if len(store.searches[username]) == 0 {
+		delete(store.searches, username)
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"testing"
+)
+
+func TestInMemorySavedSearchStore(t *testing.T) {
+	t.Parallel()
+
+	store := NewInMemorySavedSearchStore()
+
+	require.NoError(t, store.Save("alice", &pb.SavedSearch{Id: "1", Name: "cheap"}))
+	require.NoError(t, store.Save("alice", &pb.SavedSearch{Id: "2", Name: "light"}))
+	require.NoError(t, store.Save("bob", &pb.SavedSearch{Id: "1", Name: "fast"}))
+	require.Equal(t, ErrAlreadyExists, store.Save("alice", &pb.SavedSearch{Id: "1"}))
+	require.Equal(t, []string{"cheap", "light"}, listTestSavedSearchNames(t, store, "alice"))
+
+	require.NoError(t, store.Delete("alice", "1"))
+	require.Equal(t, ErrNotFound, store.Delete("alice", "1"))
+	require.Equal(t, []string{"light"}, listTestSavedSearchNames(t, store, "alice"))
+	require.Equal(t, []string{"fast"}, listTestSavedSearchNames(t, store, "bob"))
+	require.Empty(t, listTestSavedSearchNames(t, store, "carol"))
+}
+
+func TestFileSavedSearchStore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	store, err := NewFileSavedSearchStore(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Save("alice", &pb.SavedSearch{Id: "1", Name: "cheap"}))
+	require.NoError(t, store.Save("alice", &pb.SavedSearch{Id: "2", Name: "light"}))
+	require.NoError(t, store.snapshot())
+
+	// the IDs are per user, a taken one and an unknown one are rejected before they reach the log
+	require.Equal(t, ErrAlreadyExists, store.Save("alice", &pb.SavedSearch{Id: "2", Name: "other"}))
+	require.Equal(t, ErrNotFound, store.Delete("bob", "1"))
+	require.Zero(t, store.wal.count)
+
+	require.NoError(t, store.Save("bob", &pb.SavedSearch{Id: "1", Name: "gaming"}))
+	require.NoError(t, store.Delete("alice", "1"))
+	require.NoError(t, store.Save("alice", &pb.SavedSearch{Id: "3", Name: "fast"}))
+	require.NoError(t, store.Close())
+
+	store, err = NewFileSavedSearchStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.Equal(t, []string{"light", "fast"}, listTestSavedSearchNames(t, store, "alice"))
+	require.Equal(t, []string{"gaming"}, listTestSavedSearchNames(t, store, "bob"))
+	require.Equal(t, ErrAlreadyExists, store.Save("alice", &pb.SavedSearch{Id: "2"}))
+}
+
+func listTestSavedSearchNames(t *testing.T, store SavedSearchStore, username string) []string {
+	searches, err := store.List(username)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(searches))
+	for _, search := range searches {
+		names = append(names, search.GetName())
+	}
+	return names
+}
@@ -0,0 +1,646 @@
+// Package storetest provides a behavioural test suite for implementations of service.LaptopStore
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"github.com/treeforest/grpc-pcbook/pb"
+	"github.com/treeforest/grpc-pcbook/sample"
+	"github.com/treeforest/grpc-pcbook/service"
+	"google.golang.org/protobuf/proto"
+	"sync"
+	"testing"
+)
+
+// TestLaptopStore runs the behaviour every LaptopStore must have against the stores built by newStore.
+// newStore must return a new empty store each time it is called.
+func TestLaptopStore(t *testing.T, newStore func(t *testing.T) service.LaptopStore) {
+	t.Run("save_and_find", func(t *testing.T) {
+		testSaveAndFind(t, newStore(t))
+	})
+	t.Run("duplicate_id", func(t *testing.T) {
+		testDuplicateID(t, newStore(t))
+	})
+	t.Run("update", func(t *testing.T) {
+		testUpdate(t, newStore(t))
+	})
+	t.Run("delete", func(t *testing.T) {
+		testDelete(t, newStore(t))
+	})
+	t.Run("copy_isolation", func(t *testing.T) {
+		testCopyIsolation(t, newStore(t))
+	})
+	t.Run("search_filter", func(t *testing.T) {
+		testSearchFilter(t, newStore(t))
+	})
+	t.Run("search_rich_filter", func(t *testing.T) {
+		testSearchRichFilter(t, newStore(t))
+	})
+	t.Run("search_filter_expr", func(t *testing.T) {
+		testSearchFilterExpr(t, newStore(t))
+	})
+	t.Run("search_text", func(t *testing.T) {
+		testSearchText(t, newStore(t))
+	})
+	t.Run("facets", func(t *testing.T) {
+		testFacets(t, newStore(t))
+	})
+	t.Run("find_similar", func(t *testing.T) {
+		testFindSimilar(t, newStore(t))
+	})
+	t.Run("watch", func(t *testing.T) {
+		testWatch(t, newStore(t))
+	})
+	t.Run("search_context_canceled", func(t *testing.T) {
+		testSearchContextCanceled(t, newStore(t))
+	})
+	t.Run("concurrent_save_and_search", func(t *testing.T) {
+		testConcurrentSaveAndSearch(t, newStore(t))
+	})
+}
+
+func testSaveAndFind(t *testing.T, store service.LaptopStore) {
+	laptop := sample.NewLaptop()
+	require.NoError(t, store.Save(laptop))
+	require.Equal(t, uint64(1), laptop.Revision)
+
+	other, err := store.Find(laptop.Id)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(laptop, other))
+
+	other, err = store.Find(sample.NewLaptop().Id)
+	require.NoError(t, err)
+	require.Nil(t, other)
+}
+
+func testDuplicateID(t *testing.T, store service.LaptopStore) {
+	laptop := sample.NewLaptop()
+	require.NoError(t, store.Save(laptop))
+
+	other := sample.NewLaptop()
+	other.Id = laptop.Id
+	require.Equal(t, service.ErrAlreadyExists, store.Save(other))
+
+	found, err := store.Find(laptop.Id)
+	require.NoError(t, err)
+	require.Equal(t, laptop.GetName(), found.GetName())
+	require.Equal(t, laptop.GetPriceUsd(), found.GetPriceUsd())
+}
+
+func testUpdate(t *testing.T, store service.LaptopStore) {
+	laptop := sample.NewLaptop()
+	require.Equal(t, service.ErrNotFound, store.Update(laptop))
+	require.NoError(t, store.Save(laptop))
+
+	laptop.PriceUsd = 999
+	require.NoError(t, store.Update(laptop))
+	require.Equal(t, uint64(2), laptop.Revision)
+
+	stale := proto.Clone(laptop).(*pb.Laptop)
+	stale.Revision = 1
+	require.Equal(t, service.ErrRevisionMismatch, store.Update(stale))
+
+	laptop.Revision = 0
+	laptop.PriceUsd = 1000
+	require.NoError(t, store.Update(laptop))
+	require.Equal(t, uint64(3), laptop.Revision)
+
+	other, err := store.Find(laptop.Id)
+	require.NoError(t, err)
+	require.Equal(t, 1000.0, other.GetPriceUsd())
+	require.Equal(t, uint64(3), other.GetRevision())
+}
+
+func testDelete(t *testing.T, store service.LaptopStore) {
+	laptop := sample.NewLaptop()
+	require.Equal(t, service.ErrNotFound, store.Delete(laptop.Id, 0))
+	require.NoError(t, store.Save(laptop))
+
+	require.Equal(t, service.ErrRevisionMismatch, store.Delete(laptop.Id, 2))
+	require.NoError(t, store.Delete(laptop.Id, 1))
+
+	other, err := store.Find(laptop.Id)
+	require.NoError(t, err)
+	require.Nil(t, other)
+
+	// the ID can be used again
+	require.NoError(t, store.Save(laptop))
+}
+
+func testCopyIsolation(t *testing.T, store service.LaptopStore) {
+	laptop := sample.NewLaptop()
+	laptop.PriceUsd = 1000
+	laptop.Cpu.NumberCores = 4
+	require.NoError(t, store.Save(laptop))
+
+	// changing the saved laptop doesn't change the stored one
+	laptop.PriceUsd = 1
+	laptop.Cpu.NumberCores = 1
+
+	found, err := store.Find(laptop.Id)
+	require.NoError(t, err)
+	require.Equal(t, 1000.0, found.GetPriceUsd())
+	require.Equal(t, uint32(4), found.GetCpu().GetNumberCores())
+
+	// changing a found laptop doesn't change the stored one
+	found.PriceUsd = 2
+	found.Cpu.NumberCores = 2
+	found.Gpus[0].Name = "changed"
+
+	err = store.Search(context.Background(), openFilter(), nil, func(other *pb.Laptop) error {
+		require.Equal(t, 1000.0, other.GetPriceUsd())
+		require.Equal(t, uint32(4), other.GetCpu().GetNumberCores())
+		require.NotEqual(t, "changed", other.GetGpus()[0].GetName())
+
+		// changing a searched laptop doesn't change the stored one
+		other.PriceUsd = 3
+		other.Cpu.NumberCores = 3
+		return nil
+	})
+	require.NoError(t, err)
+
+	found, err = store.Find(laptop.Id)
+	require.NoError(t, err)
+	require.Equal(t, 1000.0, found.GetPriceUsd())
+	require.Equal(t, uint32(4), found.GetCpu().GetNumberCores())
+}
+
+func testSearchFilter(t *testing.T, store service.LaptopStore) {
+	filter := &pb.Filter{
+		MaxPriceUsd: 2000,
+		MinCpuCores: 4,
+		MinCpuGhz:   2.2,
+		MinRam:      &pb.Memory{Value: 8, Unit: pb.Memory_GIGABYTE},
+	}
+
+	newLaptop := func(price float64, cores uint32, ghz float64, ram *pb.Memory) *pb.Laptop {
+		laptop := sample.NewLaptop()
+		laptop.PriceUsd = price
+		laptop.Cpu.NumberCores = cores
+		laptop.Cpu.MinGhz = ghz
+		laptop.Ram = ram
+		return laptop
+	}
+
+	gigabytes := func(value uint64) *pb.Memory {
+		return &pb.Memory{Value: value, Unit: pb.Memory_GIGABYTE}
+	}
+
+	testCases := []struct {
+		name     string
+		laptop   *pb.Laptop
+		expected bool
+	}{
+		{"too_expensive", newLaptop(2000.01, 4, 2.2, gigabytes(8)), false},
+		{"too_few_cores", newLaptop(2000, 3, 2.2, gigabytes(8)), false},
+		{"too_slow", newLaptop(2000, 4, 2.19, gigabytes(8)), false},
+		{"too_little_ram", newLaptop(2000, 4, 2.2, &pb.Memory{Value: 8191, Unit: pb.Memory_MEGABYTE}), false},
+		{"no_ram", newLaptop(2000, 4, 2.2, nil), false},
+		{"exact_bounds", newLaptop(2000, 4, 2.2, gigabytes(8)), true},
+		{"ram_in_megabytes", newLaptop(1500, 6, 3.0, &pb.Memory{Value: 8192, Unit: pb.Memory_MEGABYTE}), true},
+		{"ram_in_terabytes", newLaptop(1999, 8, 2.5, &pb.Memory{Value: 1, Unit: pb.Memory_TERABYTE}), true},
+	}
+
+	expected := make(map[string]string)
+	for _, tc := range testCases {
+		require.NoError(t, store.Save(tc.laptop))
+		if tc.expected {
+			expected[tc.laptop.Id] = tc.name
+		}
+	}
+
+	found := make(map[string]string)
+	err := store.Search(context.Background(), filter, nil, func(laptop *pb.Laptop) error {
+		for _, tc := range testCases {
+			if tc.laptop.Id == laptop.Id {
+				found[laptop.Id] = tc.name
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, expected, found)
+}
+
+func testSearchRichFilter(t *testing.T, store service.LaptopStore) {
+	filter := &pb.Filter{
+		MaxPriceUsd:         3000,
+		MinPriceUsd:         1000,
+		Brand:               "lenovo",
+		Name:                "THINKPAD",
+		GpuBrand:            "nvidia",
+		MinGpuMemory:        &pb.Memory{Value: 4, Unit: pb.Memory_GIGABYTE},
+		StorageDriver:       pb.Storage_SSD,
+		MinStorage:          &pb.Memory{Value: 512, Unit: pb.Memory_GIGABYTE},
+		MinScreenSizeInch:   13,
+		MaxScreenSizeInch:   15,
+		MinScreenResolution: &pb.Screen_Resolution{Width: 1920, Height: 1080},
+		ScreenPanel:         pb.Screen_OLED,
+		KeyboardLayout:      pb.Keyboard_QWERTY,
+		KeyboardBacklit:     true,
+		MaxWeightKg:         2,
+		MinReleaseYear:      2019,
+		MaxReleaseYear:      2020,
+	}
+
+	newLaptop := func(change func(laptop *pb.Laptop)) *pb.Laptop {
+		laptop := sample.NewLaptop()
+		laptop.Brand = "Lenovo"
+		laptop.Name = "Thinkpad X1"
+		laptop.PriceUsd = 2000
+		laptop.Gpus = []*pb.GPU{
+			{Brand: "AMD", Memory: &pb.Memory{Value: 8, Unit: pb.Memory_GIGABYTE}},
+			{Brand: "NVIDIA", Memory: &pb.Memory{Value: 4096, Unit: pb.Memory_MEGABYTE}},
+		}
+		laptop.Storages = []*pb.Storage{
+			{Driver: pb.Storage_HDD, Memory: &pb.Memory{Value: 2, Unit: pb.Memory_TERABYTE}},
+			{Driver: pb.Storage_SSD, Memory: &pb.Memory{Value: 512, Unit: pb.Memory_GIGABYTE}},
+		}
+		laptop.Screen = &pb.Screen{
+			SizeInch:   14,
+			Resolution: &pb.Screen_Resolution{Width: 2560, Height: 1440},
+			Panel:      pb.Screen_OLED,
+		}
+		laptop.Keyboard = &pb.Keyboard{Layout: pb.Keyboard_QWERTY, Backlit: true}
+		laptop.Weight = &pb.Laptop_WeightLb{WeightLb: 4.4}
+		laptop.ReleaseYear = 2019
+		change(laptop)
+		return laptop
+	}
+
+	testCases := []struct {
+		name     string
+		laptop   *pb.Laptop
+		expected bool
+	}{
+		{"match", newLaptop(func(laptop *pb.Laptop) {}), true},
+		{"weight_in_kg", newLaptop(func(laptop *pb.Laptop) { laptop.Weight = &pb.Laptop_WeightKg{WeightKg: 2} }), true},
+		{"too_cheap", newLaptop(func(laptop *pb.Laptop) { laptop.PriceUsd = 999 }), false},
+		{"other_brand", newLaptop(func(laptop *pb.Laptop) { laptop.Brand = "Dell" }), false},
+		{"other_name", newLaptop(func(laptop *pb.Laptop) { laptop.Name = "Yoga" }), false},
+		{"small_gpu_of_brand", newLaptop(func(laptop *pb.Laptop) { laptop.Gpus[1].Memory.Value = 2048 }), false},
+		{"no_gpu", newLaptop(func(laptop *pb.Laptop) { laptop.Gpus = nil }), false},
+		{"small_ssd", newLaptop(func(laptop *pb.Laptop) { laptop.Storages[1].Memory.Value = 256 }), false},
+		{"screen_too_small", newLaptop(func(laptop *pb.Laptop) { laptop.Screen.SizeInch = 12.5 }), false},
+		{"screen_too_large", newLaptop(func(laptop *pb.Laptop) { laptop.Screen.SizeInch = 17 }), false},
+		{"low_resolution", newLaptop(func(laptop *pb.Laptop) { laptop.Screen.Resolution.Height = 768 }), false},
+		{"ips_panel", newLaptop(func(laptop *pb.Laptop) { laptop.Screen.Panel = pb.Screen_IPS }), false},
+		{"azerty_keyboard", newLaptop(func(laptop *pb.Laptop) { laptop.Keyboard.Layout = pb.Keyboard_AZERTY }), false},
+		{"not_backlit", newLaptop(func(laptop *pb.Laptop) { laptop.Keyboard.Backlit = false }), false},
+		{"too_heavy", newLaptop(func(laptop *pb.Laptop) { laptop.Weight = &pb.Laptop_WeightLb{WeightLb: 4.5} }), false},
+		{"unknown_weight", newLaptop(func(laptop *pb.Laptop) { laptop.Weight = nil }), false},
+		{"too_old", newLaptop(func(laptop *pb.Laptop) { laptop.ReleaseYear = 2018 }), false},
+		{"too_new", newLaptop(func(laptop *pb.Laptop) { laptop.ReleaseYear = 2021 }), false},
+	}
+
+	expected := make(map[string]string)
+	for _, tc := range testCases {
+		require.NoError(t, store.Save(tc.laptop))
+		if tc.expected {
+			expected[tc.laptop.Id] = tc.name
+		}
+	}
+
+	found := make(map[string]string)
+	err := store.Search(context.Background(), filter, nil, func(laptop *pb.Laptop) error {
+		for _, tc := range testCases {
+			if tc.laptop.Id == laptop.Id {
+				found[laptop.Id] = tc.name
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, expected, found)
+}
+
+func testSearchFilterExpr(t *testing.T, store service.LaptopStore) {
+	expr, err := service.ParseFilterExpr(`ram >= 16GB && (brand == "Dell" || brand == "Apple")`)
+	require.NoError(t, err)
+
+	newLaptop := func(brand string, ram uint64, price float64) *pb.Laptop {
+		laptop := sample.NewLaptop()
+		laptop.Brand = brand
+		laptop.Ram = &pb.Memory{Value: ram, Unit: pb.Memory_GIGABYTE}
+		laptop.PriceUsd = price
+		require.NoError(t, store.Save(laptop))
+		return laptop
+	}
+
+	dell := newLaptop("Dell", 16, 2000)
+	newLaptop("Lenovo", 32, 2000)
+	newLaptop("Apple", 8, 2000)
+	newLaptop("Apple", 32, 4000)
+
+	var ids []string
+	filter := &pb.Filter{MaxPriceUsd: 3000}
+	err = store.Search(context.Background(), filter, expr, func(laptop *pb.Laptop) error {
+		ids = append(ids, laptop.Id)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{dell.Id}, ids)
+}
+
+func testSearchText(t *testing.T, store service.LaptopStore) {
+	newLaptop := func(name string, cpu string, gpu string) *pb.Laptop {
+		laptop := sample.NewLaptop()
+		laptop.Brand = "Lenovo"
+		laptop.Name = name
+		laptop.Cpu.Name = cpu
+		laptop.Gpus = []*pb.GPU{{Brand: "NVIDIA", Name: gpu}}
+		return laptop
+	}
+
+	ryzenRTX := newLaptop("Legion 5", "Ryzen 7 PRO 2700U", "RTX 2070")
+	ryzenGTX := newLaptop("Legion 7", "Ryzen 7 PRO 2700U", "GTX 1660-TI")
+	intelRTX := newLaptop("Thinkpad X1", "Core i7-1020H", "RTX 2070")
+	ryzenName := newLaptop("Ryzen Edition", "Ryzen 7 PRO 2700U", "RTX 2060")
+	for _, laptop := range []*pb.Laptop{ryzenRTX, ryzenGTX, intelRTX, ryzenName} {
+		require.NoError(t, store.Save(laptop))
+	}
+
+	search := func(query string, filter *pb.Filter) []string {
+		var ids []string
+		lastScore := 0.0
+		err := store.SearchText(context.Background(), query, filter, nil, func(laptop *pb.Laptop, score float64) error {
+			require.Greater(t, score, 0.0)
+			if len(ids) > 0 {
+				require.LessOrEqual(t, score, lastScore)
+			}
+			lastScore = score
+			ids = append(ids, laptop.Id)
+			return nil
+		})
+		require.NoError(t, err)
+		return ids
+	}
+
+	require.Equal(t, []string{ryzenRTX.Id}, search("ryzen 7 rtx 2070", openFilter()))
+	require.ElementsMatch(t, []string{ryzenRTX.Id, intelRTX.Id, ryzenName.Id}, search("RTX 20", openFilter()))
+	require.Empty(t, search("ryzen 9", openFilter()))
+	require.Empty(t, search("  ", openFilter()))
+
+	// a word of the name scores higher than the same word of the CPU name
+	ids := search("ryzen", openFilter())
+	require.Len(t, ids, 3)
+	require.Equal(t, ryzenName.Id, ids[0])
+
+	ryzenGTX.PriceUsd = 1e10
+	require.NoError(t, store.Update(ryzenGTX))
+	require.Len(t, search("ryzen", openFilter()), 2)
+
+	ryzenRTX.Gpus[0].Name = "RX 580"
+	require.NoError(t, store.Update(ryzenRTX))
+	require.Empty(t, search("ryzen rtx 2070", openFilter()))
+	require.Equal(t, []string{ryzenRTX.Id}, search("rx 5", openFilter()))
+
+	require.NoError(t, store.Delete(intelRTX.Id, 0))
+	require.Empty(t, search("i7", openFilter()))
+}
+
+func testFacets(t *testing.T, store service.LaptopStore) {
+	newLaptop := func(brand string, ram uint64, year uint32, price float64, gpuBrands ...string) {
+		laptop := sample.NewLaptop()
+		laptop.Brand = brand
+		laptop.Cpu.Brand = "Intel"
+		laptop.Ram = &pb.Memory{Value: ram, Unit: pb.Memory_GIGABYTE}
+		laptop.ReleaseYear = year
+		laptop.PriceUsd = price
+		laptop.Gpus = nil
+		for _, gpuBrand := range gpuBrands {
+			laptop.Gpus = append(laptop.Gpus, &pb.GPU{Brand: gpuBrand, Name: "RTX 2070"})
+		}
+		laptop.Storages = []*pb.Storage{{Driver: pb.Storage_SSD}, {Driver: pb.Storage_SSD}}
+		laptop.Screen.Panel = pb.Screen_IPS
+		require.NoError(t, store.Save(laptop))
+	}
+
+	newLaptop("Dell", 16, 2020, 1000, "NVIDIA", "NVIDIA")
+	newLaptop("Dell", 24, 2019, 2000, "NVIDIA", "AMD")
+	newLaptop("Apple", 8, 2020, 3000)
+	newLaptop("Lenovo", 64, 2018, 9000, "AMD")
+
+	facets, err := store.Facets(context.Background(), "", &pb.Filter{MaxPriceUsd: 5000}, nil)
+	require.NoError(t, err)
+
+	count := func(value string, count uint32) *pb.FacetCount {
+		return &pb.FacetCount{Value: value, Count: count}
+	}
+	expected := &pb.SearchFacets{
+		Total:          3,
+		Brands:         []*pb.FacetCount{count("Dell", 2), count("Apple", 1)},
+		CpuBrands:      []*pb.FacetCount{count("Intel", 3)},
+		GpuBrands:      []*pb.FacetCount{count("NVIDIA", 2), count("AMD", 1)},
+		RamBuckets:     []*pb.FacetCount{count("8GB", 1), count("16GB", 2)},
+		StorageDrivers: []*pb.FacetCount{count("SSD", 3)},
+		ScreenPanels:   []*pb.FacetCount{count("IPS", 3)},
+		ReleaseYears:   []*pb.FacetCount{count("2019", 1), count("2020", 2)},
+		Price:          &pb.PriceStats{MinUsd: 1000, MaxUsd: 3000, AvgUsd: 2000},
+	}
+	require.True(t, proto.Equal(expected, facets), "facets: %v", facets)
+
+	expr, err := service.ParseFilterExpr(`brand == "Dell"`)
+	require.NoError(t, err)
+	facets, err = store.Facets(context.Background(), "rtx", openFilter(), expr)
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), facets.GetTotal())
+
+	facets, err = store.Facets(context.Background(), "", &pb.Filter{MaxPriceUsd: 1}, nil)
+	require.NoError(t, err)
+	require.Zero(t, facets.GetTotal())
+	require.Nil(t, facets.GetPrice())
+}
+
+func testFindSimilar(t *testing.T, store service.LaptopStore) {
+	newLaptop := func(cores uint32, ramGB uint64, price float64) *pb.Laptop {
+		laptop := sample.NewLaptop()
+		laptop.Cpu.NumberCores = cores
+		laptop.Cpu.MinGhz = 2.5
+		laptop.Ram = &pb.Memory{Value: ramGB, Unit: pb.Memory_GIGABYTE}
+		laptop.Gpus = nil
+		laptop.Storages = []*pb.Storage{{Driver: pb.Storage_SSD, Memory: &pb.Memory{Value: 512, Unit: pb.Memory_GIGABYTE}}}
+		laptop.Screen.SizeInch = 15
+		laptop.Weight = &pb.Laptop_WeightKg{WeightKg: 2}
+		laptop.PriceUsd = price
+		require.NoError(t, store.Save(laptop))
+		return laptop
+	}
+
+	target := newLaptop(8, 16, 2000)
+	sameSpecs := newLaptop(8, 16, 2000)
+	cheaper := newLaptop(8, 16, 1500)
+	lessRAM := newLaptop(8, 8, 2000)
+	different := newLaptop(2, 4, 500)
+
+	findSimilar := func(weights *pb.SimilarityWeights, k int) []string {
+		var ids []string
+		lastDistance := 0.0
+		err := store.FindSimilar(context.Background(), target.Id, weights, k, func(laptop *pb.Laptop, distance float64) error {
+			require.GreaterOrEqual(t, distance, lastDistance)
+			lastDistance = distance
+			ids = append(ids, laptop.Id)
+			return nil
+		})
+		require.NoError(t, err)
+		return ids
+	}
+
+	ids := findSimilar(nil, 10)
+	require.Len(t, ids, 4)
+	require.NotContains(t, ids, target.Id)
+	require.Equal(t, sameSpecs.Id, ids[0])
+	require.Equal(t, different.Id, ids[3])
+
+	require.Equal(t, []string{sameSpecs.Id}, findSimilar(nil, 1))
+
+	// only the price counts
+	ids = findSimilar(&pb.SimilarityWeights{Price: 1}, 3)
+	require.ElementsMatch(t, []string{sameSpecs.Id, lessRAM.Id}, ids[:2])
+	require.Equal(t, cheaper.Id, ids[2])
+
+	// only the RAM counts
+	ids = findSimilar(&pb.SimilarityWeights{Ram: 1}, 3)
+	require.ElementsMatch(t, []string{sameSpecs.Id, cheaper.Id}, ids[:2])
+	require.Equal(t, lessRAM.Id, ids[2])
+
+	// the index follows updates and deletes
+	different.Cpu.NumberCores = 8
+	different.Ram = &pb.Memory{Value: 16, Unit: pb.Memory_GIGABYTE}
+	different.PriceUsd = 2000
+	require.NoError(t, store.Update(different))
+	require.NoError(t, store.Delete(sameSpecs.Id, 0))
+	require.Equal(t, []string{different.Id}, findSimilar(nil, 1))
+
+	err := store.FindSimilar(context.Background(), sameSpecs.Id, nil, 10, func(laptop *pb.Laptop, distance float64) error {
+		return nil
+	})
+	require.Equal(t, service.ErrNotFound, err)
+}
+
+func testWatch(t *testing.T, store service.LaptopStore) {
+	laptop := sample.NewLaptop()
+	require.NoError(t, store.Save(laptop))
+
+	laptop.PriceUsd = 1234
+	require.NoError(t, store.Update(laptop))
+	require.NoError(t, store.Delete(laptop.Id, 0))
+	require.Error(t, store.Delete(laptop.Id, 0))
+
+	other := sample.NewLaptop()
+	require.NoError(t, store.Save(other))
+
+	errStop := fmt.Errorf("stop watching")
+	var changes []*service.LaptopChange
+	err := store.Watch(context.Background(), 1, func(change *service.LaptopChange) error {
+		changes = append(changes, change)
+		if len(changes) == 3 {
+			return errStop
+		}
+		return nil
+	})
+	require.Equal(t, errStop, err)
+
+	require.Equal(t, uint64(2), changes[0].Event.GetSequence())
+	require.Equal(t, pb.LaptopEvent_UPDATED, changes[0].Event.GetType())
+	require.Equal(t, 1234.0, changes[0].Event.GetLaptop().GetPriceUsd())
+	require.Equal(t, laptop.Id, changes[0].Previous.GetId())
+	require.NotEqual(t, 1234.0, changes[0].Previous.GetPriceUsd())
+
+	require.Equal(t, uint64(3), changes[1].Event.GetSequence())
+	require.Equal(t, pb.LaptopEvent_DELETED, changes[1].Event.GetType())
+	require.Equal(t, laptop.Id, changes[1].Event.GetLaptop().GetId())
+
+	require.Equal(t, uint64(4), changes[2].Event.GetSequence())
+	require.Equal(t, pb.LaptopEvent_CREATED, changes[2].Event.GetType())
+	require.Equal(t, other.Id, changes[2].Event.GetLaptop().GetId())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = store.Watch(ctx, 4, func(change *service.LaptopChange) error {
+		return nil
+	})
+	require.Equal(t, context.Canceled, err)
+
+	err = store.Watch(context.Background(), 5, func(change *service.LaptopChange) error {
+		return nil
+	})
+	require.Equal(t, service.ErrUnknownSequence, err)
+}
+
+func testSearchContextCanceled(t *testing.T, store service.LaptopStore) {
+	for i := 0; i < 10; i++ {
+		require.NoError(t, store.Save(sample.NewLaptop()))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	found := 0
+	err := store.Search(ctx, openFilter(), nil, func(laptop *pb.Laptop) error {
+		found++
+		cancel()
+		return nil
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, found)
+}
+
+func testConcurrentSaveAndSearch(t *testing.T, store service.LaptopStore) {
+	const writers, searchers, laptops = 4, 4, 25
+
+	wg := sync.WaitGroup{}
+	errs := make(chan error, writers+searchers)
+
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < laptops; j++ {
+				if err := store.Save(sample.NewLaptop()); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < searchers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < laptops; j++ {
+				err := store.Search(context.Background(), openFilter(), nil, func(laptop *pb.Laptop) error {
+					if laptop.GetId() == "" {
+						return fmt.Errorf("found a laptop without ID")
+					}
+					return nil
+				})
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	count := 0
+	err := store.Search(context.Background(), openFilter(), nil, func(laptop *pb.Laptop) error {
+		count++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, writers*laptops, count)
+}
+
+// openFilter returns a filter that every sample laptop passes
+func openFilter() *pb.Filter {
+	return &pb.Filter{MaxPriceUsd: 1e9}
+}
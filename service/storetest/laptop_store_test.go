@@ -0,0 +1,26 @@
+package storetest
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/treeforest/grpc-pcbook/service"
+	"testing"
+)
+
+func TestInMemoryLaptopStore(t *testing.T) {
+	t.Parallel()
+
+	TestLaptopStore(t, func(t *testing.T) service.LaptopStore {
+		return service.NewInMemoryLaptopStore()
+	})
+}
+
+func TestFileLaptopStore(t *testing.T) {
+	t.Parallel()
+
+	TestLaptopStore(t, func(t *testing.T) service.LaptopStore {
+		store, err := service.NewFileLaptopStore(t.TempDir())
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Close() })
+		return store
+	})
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// number of writes after which the expired tokens are removed from the InMemoryTokenStore
+const tokenCleanupInterval = 100
+
+// RefreshToken is a refresh token, which can be used once to get new tokens
+type RefreshToken struct {
+	// HashedToken is the SHA-256 hash of the token, the token itself is never stored
+	HashedToken string
+	// Family identifies the login that issued the token, the tokens it is rotated into have the same family
+	Family    string
+	Username  string
+	ExpiresAt time.Time
+	Used      bool
+}
+
+// TokenStore is an interface to store refresh tokens and revoked access tokens
+type TokenStore interface {
+	// SaveRefreshToken saves a new refresh token
+	SaveRefreshToken(token *RefreshToken) error
+	// UseRefreshToken marks the refresh token with the hash as used and returns it as it was before,
+	// returns nil if there is none
+	UseRefreshToken(hashedToken string) (*RefreshToken, error)
+	// RevokeFamily revokes the refresh tokens of a family and the access tokens issued with them
+	RevokeFamily(family string) error
+	// RevokeUser revokes every refresh token family of the user and the access tokens issued with them
+	RevokeUser(username string) error
+	// RevokeAccessToken revokes an access token by ID until it expires
+	RevokeAccessToken(id string, expiresAt time.Time) error
+	// IsRevoked reports whether an access token with the ID and the family, which may be empty, is revoked
+	IsRevoked(id string, family string) (bool, error)
+}
+
+// InMemoryTokenStore stores tokens in memory, every token is lost when the server restarts
+type InMemoryTokenStore struct {
+	mutex         sync.RWMutex
+	refreshTokens map[string]*RefreshToken
+	// expiry of the last refresh token of each revoked family
+	revokedFamilies map[string]time.Time
+	// expiry of each revoked access token
+	revokedTokens map[string]time.Time
+	writes        int
+}
+
+// NewInMemoryTokenStore returns a new InMemoryTokenStore
+func NewInMemoryTokenStore() *InMemoryTokenStore {
+	return &InMemoryTokenStore{
+		refreshTokens:   make(map[string]*RefreshToken),
+		revokedFamilies: make(map[string]time.Time),
+		revokedTokens:   make(map[string]time.Time),
+	}
+}
+
+// SaveRefreshToken saves a new refresh token
+func (store *InMemoryTokenStore) SaveRefreshToken(token *RefreshToken) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.refreshTokens[token.HashedToken] != nil {
+		return ErrAlreadyExists
+	}
+
+	store.putRefreshToken(token)
+	store.written()
+	return nil
+}
+
+// putRefreshToken saves a copy of the refresh token, the caller must hold the write lock
+func (store *InMemoryTokenStore) putRefreshToken(token *RefreshToken) {
+	other := *token
+	store.refreshTokens[token.HashedToken] = &other
+}
+
+// findRefreshToken returns a copy of the refresh token with the hash, or nil if there is none
+func (store *InMemoryTokenStore) findRefreshToken(hashedToken string) *RefreshToken {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	token := store.refreshTokens[hashedToken]
+	if token == nil {
+		return nil
+	}
+
+	other := *token
+	return &other
+}
+
+// UseRefreshToken marks the refresh token with the hash as used and returns it as it was before,
+// returns nil if there is none
+func (store *InMemoryTokenStore) UseRefreshToken(hashedToken string) (*RefreshToken, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	token := store.refreshTokens[hashedToken]
+	if token == nil {
+		return nil, nil
+	}
+
+	other := *token
+	token.Used = true
+	return &other, nil
+}
+
+// RevokeFamily revokes the refresh tokens of a family and the access tokens issued with them
+func (store *InMemoryTokenStore) RevokeFamily(family string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.revokeFamily(family, time.Time{})
+	store.written()
+	return nil
+}
+
+// revokeFamily revokes a family until its last refresh token expires, or until expiresAt if it is later,
+// and returns the time until which it is revoked. The caller must hold the write lock.
+func (store *InMemoryTokenStore) revokeFamily(family string, expiresAt time.Time) time.Time {
+	// the access tokens of the family expire before its last refresh token
+	if revokedUntil := store.revokedFamilies[family]; revokedUntil.After(expiresAt) {
+		expiresAt = revokedUntil
+	}
+	for hashedToken, token := range store.refreshTokens {
+		if token.Family == family {
+			if token.ExpiresAt.After(expiresAt) {
+				expiresAt = token.ExpiresAt
+			}
+			delete(store.refreshTokens, hashedToken)
+		}
+	}
+
+	store.revokedFamilies[family] = expiresAt
+	return expiresAt
+}
+
+// RevokeUser revokes every refresh token family of the user and the access tokens issued with them
+func (store *InMemoryTokenStore) RevokeUser(username string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.revokeUser(username)
+	store.written()
+	return nil
+}
+
+// revokeUser revokes the families of the refresh tokens of the user, the caller must hold the write lock
+func (store *InMemoryTokenStore) revokeUser(username string) {
+	families := make(map[string]bool)
+	for _, token := range store.refreshTokens {
+		if token.Username == username {
+			families[token.Family] = true
+		}
+	}
+
+	for family := range families {
+		store.revokeFamily(family, time.Time{})
+	}
+}
+
+// RevokeAccessToken revokes an access token by ID until it expires
+func (store *InMemoryTokenStore) RevokeAccessToken(id string, expiresAt time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.revokedTokens[id] = expiresAt
+	store.written()
+	return nil
+}
+
+// IsRevoked reports whether an access token with the ID and the family, which may be empty, is revoked
+func (store *InMemoryTokenStore) IsRevoked(id string, family string) (bool, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	if _, ok := store.revokedTokens[id]; ok {
+		return true, nil
+	}
+
+	if len(family) > 0 {
+		if _, ok := store.revokedFamilies[family]; ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// written removes the expired tokens every tokenCleanupInterval writes, the caller must hold the write lock
+func (store *InMemoryTokenStore) written() {
+	store.writes++
+	if store.writes%tokenCleanupInterval != 0 {
+		return
+	}
+
+	now := time.Now()
+	for hashedToken, token := range store.refreshTokens {
+		if now.After(token.ExpiresAt) {
+			delete(store.refreshTokens, hashedToken)
+		}
+	}
+	for family, expiresAt := range store.revokedFamilies {
+		if now.After(expiresAt) {
+			delete(store.revokedFamilies, family)
+		}
+	}
+	for id, expiresAt := range store.revokedTokens {
+		if now.After(expiresAt) {
+			delete(store.revokedTokens, id)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestInMemoryTokenStore(t *testing.T) {
+	t.Parallel()
+
+	store := NewInMemoryTokenStore()
+	expiresAt := time.Now().Add(time.Hour)
+
+	token := &RefreshToken{HashedToken: "hash1", Family: "family1", Username: "alice", ExpiresAt: expiresAt}
+	require.NoError(t, store.SaveRefreshToken(token))
+	require.Equal(t, ErrAlreadyExists, store.SaveRefreshToken(token))
+	require.NoError(t, store.SaveRefreshToken(&RefreshToken{HashedToken: "hash2", Family: "family1", ExpiresAt: expiresAt}))
+	require.NoError(t, store.SaveRefreshToken(&RefreshToken{HashedToken: "hash3", Family: "family2", ExpiresAt: expiresAt}))
+
+	other, err := store.UseRefreshToken("unknown")
+	require.NoError(t, err)
+	require.Nil(t, other)
+
+	// the token is returned as it was before it was used
+	other, err = store.UseRefreshToken("hash1")
+	require.NoError(t, err)
+	require.Equal(t, token, other)
+	other, err = store.UseRefreshToken("hash1")
+	require.NoError(t, err)
+	require.True(t, other.Used)
+
+	revoked, err := store.IsRevoked("token1", "family1")
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	require.NoError(t, store.RevokeFamily("family1"))
+
+	for _, hashedToken := range []string{"hash1", "hash2"} {
+		other, err = store.UseRefreshToken(hashedToken)
+		require.NoError(t, err)
+		require.Nil(t, other)
+	}
+	other, err = store.UseRefreshToken("hash3")
+	require.NoError(t, err)
+	require.NotNil(t, other)
+
+	revoked, err = store.IsRevoked("token1", "family1")
+	require.NoError(t, err)
+	require.True(t, revoked)
+	revoked, err = store.IsRevoked("token1", "")
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	require.NoError(t, store.RevokeAccessToken("token1", expiresAt))
+	revoked, err = store.IsRevoked("token1", "")
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// revoking a user revokes every family of the user
+	require.NoError(t, store.SaveRefreshToken(&RefreshToken{HashedToken: "hash4", Family: "family3", Username: "bob", ExpiresAt: expiresAt}))
+	require.NoError(t, store.SaveRefreshToken(&RefreshToken{HashedToken: "hash5", Family: "family4", Username: "bob", ExpiresAt: expiresAt}))
+	require.NoError(t, store.RevokeUser("bob"))
+
+	for _, family := range []string{"family3", "family4"} {
+		revoked, err = store.IsRevoked("token2", family)
+		require.NoError(t, err)
+		require.True(t, revoked, family)
+	}
+	revoked, err = store.IsRevoked("token2", "family2")
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	other, err = store.UseRefreshToken("hash4")
+	require.NoError(t, err)
+	require.Nil(t, other)
+}
+
+func TestFileTokenStore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	expiresAt := time.Unix(time.Now().Add(time.Hour).Unix(), 0)
+
+	store, err := NewFileTokenStore(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, store.SaveRefreshToken(&RefreshToken{HashedToken: "hash1", Family: "family1", Username: "alice", ExpiresAt: expiresAt}))
+	require.NoError(t, store.SaveRefreshToken(&RefreshToken{HashedToken: "hash2", Family: "family2", Username: "alice", ExpiresAt: expiresAt}))
+	require.NoError(t, store.SaveRefreshToken(&RefreshToken{HashedToken: "hash3", Family: "family3", Username: "bob", ExpiresAt: expiresAt}))
+	_, err = store.UseRefreshToken("hash3")
+	require.NoError(t, err)
+
+	// a taken hash and a token used again are not logged
+	count := store.wal.count
+	require.Equal(t, ErrAlreadyExists, store.SaveRefreshToken(&RefreshToken{HashedToken: "hash1", Family: "family5", ExpiresAt: expiresAt}))
+	_, err = store.UseRefreshToken("hash3")
+	require.NoError(t, err)
+	require.Equal(t, count, store.wal.count)
+
+	require.NoError(t, store.RevokeUser("alice"))
+	require.NoError(t, store.RevokeAccessToken("token1", expiresAt))
+
+	// the state survives both a snapshot and the log written after it
+	require.NoError(t, store.snapshot())
+	require.NoError(t, store.SaveRefreshToken(&RefreshToken{HashedToken: "hash4", Family: "family4", Username: "carol", ExpiresAt: expiresAt}))
+	require.NoError(t, store.RevokeFamily("family4"))
+	require.NoError(t, store.Close())
+
+	store, err = NewFileTokenStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	for _, family := range []string{"family1", "family2", "family4"} {
+		revoked, err := store.IsRevoked("", family)
+		require.NoError(t, err)
+		require.True(t, revoked, family)
+	}
+
+	revoked, err := store.IsRevoked("token1", "family3")
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = store.IsRevoked("token2", "family3")
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	token, err := store.UseRefreshToken("hash3")
+	require.NoError(t, err)
+	require.True(t, token.Used)
+	require.Equal(t, "bob", token.Username)
+	require.True(t, expiresAt.Equal(token.ExpiresAt))
+
+	token, err = store.UseRefreshToken("hash1")
+	require.NoError(t, err)
+	require.Nil(t, token)
+}
+
+func TestInMemoryTokenStoreCleanup(t *testing.T) {
+	t.Parallel()
+
+	store := NewInMemoryTokenStore()
+	expired := time.Now().Add(-time.Minute)
+
+	require.NoError(t, store.RevokeAccessToken("expired", expired))
+	require.NoError(t, store.SaveRefreshToken(&RefreshToken{HashedToken: "expired", Family: "expired", ExpiresAt: expired}))
+	require.NoError(t, store.RevokeFamily("expired"))
+
+	for i := 0; i < tokenCleanupInterval; i++ {
+		require.NoError(t, store.RevokeAccessToken(fmt.Sprintf("token%d", i), time.Now().Add(time.Hour)))
+	}
+
+	revoked, err := store.IsRevoked("expired", "expired")
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	revoked, err = store.IsRevoked("token0", "")
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
@@ -3,6 +3,25 @@ package service
 import (
 	"fmt"
 	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"unicode"
+)
+
+const (
+	// role of the users who administrate the service
+	adminRole = "admin"
+	// role of the users who sell laptops, they own the laptops they create
+	sellerRole = "seller"
+	// role of the registered users
+	userRole = "user"
+)
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 32
+	minPasswordLength = 8
+	// bcrypt ignores the bytes after the 72th one
+	maxPasswordLength = 72
 )
 
 // User contains user's information
@@ -10,24 +29,51 @@ type User struct {
 	Username       string
 	HashedPassword string
 	Role           string
+	// a disabled user can neither login nor use an access token
+	Disabled bool
 }
 
 // NewUser returns a new user
 func NewUser(username string, password string, role string) (*User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return nil, fmt.Errorf("cannot hash password: %w", err)
+	user := &User{
+		Username: username,
+		Role:     role,
 	}
 
-	user := &User{
-		Username:       username,
-		HashedPassword: string(hashedPassword),
-		Role:           role,
+	err := user.SetPassword(password)
+	if err != nil {
+		return nil, err
 	}
 
 	return user, nil
 }
 
+// NewAdmin returns a new admin after checking the username and the password as Register does
+func NewAdmin(username string, password string) (*User, error) {
+	err := validateUsername(username)
+	if err != nil {
+		return nil, fmt.Errorf("invalid username: %w", err)
+	}
+
+	err = validatePassword(username, password)
+	if err != nil {
+		return nil, fmt.Errorf("weak password: %w", err)
+	}
+
+	return NewUser(username, password, adminRole)
+}
+
+// SetPassword replaces the password of the user
+func (user *User) SetPassword(password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("cannot hash password: %w", err)
+	}
+
+	user.HashedPassword = string(hashedPassword)
+	return nil
+}
+
 // IsCorrectPassword checks if the provided password is correct or not
 func (user *User) IsCorrectPassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(user.HashedPassword), []byte(password))
@@ -40,5 +86,53 @@ func (user *User) Clone() *User {
 		Username:       user.Username,
 		HashedPassword: user.HashedPassword,
 		Role:           user.Role,
+		Disabled:       user.Disabled,
+	}
+}
+
+// validateUsername returns an error if a username cannot be registered
+func validateUsername(username string) error {
+	if len(username) < minUsernameLength || len(username) > maxUsernameLength {
+		return fmt.Errorf("username must have from %d to %d characters", minUsernameLength, maxUsernameLength)
+	}
+
+	for _, c := range username {
+		if c > unicode.MaxASCII || !(unicode.IsLetter(c) || unicode.IsDigit(c) || strings.ContainsRune("._-", c)) {
+			return fmt.Errorf("username can only have letters, digits, '.', '_' and '-'")
+		}
 	}
+
+	return nil
+}
+
+// validatePassword returns an error if a password is too weak: it must be long enough,
+// have lowercase and uppercase letters and digits, and not contain the username
+func validatePassword(username string, password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password must have at least %d characters", minPasswordLength)
+	}
+	if len(password) > maxPasswordLength {
+		return fmt.Errorf("password cannot be longer than %d bytes", maxPasswordLength)
+	}
+
+	var lower, upper, digit bool
+	for _, c := range password {
+		switch {
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsDigit(c):
+			digit = true
+		}
+	}
+	if !lower || !upper || !digit {
+		return fmt.Errorf("password must have lowercase and uppercase letters and digits")
+	}
+
+	if len(username) > 0 && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return fmt.Errorf("password cannot contain the username")
+	}
+
+	return nil
 }
\ No newline at end of file
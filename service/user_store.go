@@ -1,6 +1,9 @@
 package service
 
-import "sync"
+import (
+	"sort"
+	"sync"
+)
 
 // UserStore is an interface to store users
 type UserStore interface {
@@ -8,6 +11,11 @@ type UserStore interface {
 	Save(user *User) error
 	// Find finds a user by username
 	Find(username string) (*User, error)
+	// Update replaces the user with the same username, returns ErrNotFound if there is none
+	Update(user *User) error
+	// List calls found with the users whose username comes after the given one, ordered by username,
+	// until found returns false
+	List(after string, found func(user *User) bool) error
 }
 
 // InMemoryUserStore stores users in memory
@@ -32,7 +40,7 @@ func (store *InMemoryUserStore) Save(user *User) error {
 		return ErrAlreadyExists
 	}
 
-	store.users[user.Username] = user
+	store.users[user.Username] = user.Clone()
 	return nil
 }
 
@@ -47,4 +55,50 @@ func (store *InMemoryUserStore) Find(username string) (*User, error) {
 	}
 
 	return user.Clone(), nil
-}
\ No newline at end of file
+}
+
+// Update replaces the user with the same username, returns ErrNotFound if there is none
+func (store *InMemoryUserStore) Update(user *User) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.users[user.Username] == nil {
+		return ErrNotFound
+	}
+
+	store.users[user.Username] = user.Clone()
+	return nil
+}
+
+// List calls found with the users whose username comes after the given one, ordered by username,
+// until found returns false
+func (store *InMemoryUserStore) List(after string, found func(user *User) bool) error {
+	store.mutex.RLock()
+	users := make([]*User, 0, len(store.users))
+	for username, user := range store.users {
+		if username > after {
+			users = append(users, user.Clone())
+		}
+	}
+	store.mutex.RUnlock()
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+
+	for _, user := range users {
+		if !found(user) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// put stores a clone of the user, replacing the user with the same username
+func (store *InMemoryUserStore) put(user *User) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.users[user.Username] = user.Clone()
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInMemoryUserStore(t *testing.T) {
+	t.Parallel()
+
+	newStore := func(t *testing.T) UserStore {
+		return NewInMemoryUserStore()
+	}
+
+	t.Run("save_and_find", func(t *testing.T) {
+		store := newStore(t)
+
+		user, err := NewUser("user1", "secret", "user")
+		require.NoError(t, err)
+		require.NoError(t, store.Save(user))
+
+		other, err := store.Find("user1")
+		require.NoError(t, err)
+		require.Equal(t, user, other)
+		require.True(t, other.IsCorrectPassword("secret"))
+
+		other, err = store.Find("user2")
+		require.NoError(t, err)
+		require.Nil(t, other)
+	})
+
+	t.Run("duplicate_username", func(t *testing.T) {
+		store := newStore(t)
+
+		user, err := NewUser("user1", "secret", "user")
+		require.NoError(t, err)
+		require.NoError(t, store.Save(user))
+
+		other, err := NewUser("user1", "other", "admin")
+		require.NoError(t, err)
+		require.Equal(t, ErrAlreadyExists, store.Save(other))
+	})
+
+	t.Run("update", func(t *testing.T) {
+		store := newStore(t)
+
+		user, err := NewUser("user1", "secret", "user")
+		require.NoError(t, err)
+		require.Equal(t, ErrNotFound, store.Update(user))
+		require.NoError(t, store.Save(user))
+
+		user.Role = "admin"
+		user.Disabled = true
+		require.NoError(t, user.SetPassword("other"))
+		require.NoError(t, store.Update(user))
+
+		other, err := store.Find("user1")
+		require.NoError(t, err)
+		require.Equal(t, user, other)
+		require.True(t, other.IsCorrectPassword("other"))
+		require.False(t, other.IsCorrectPassword("secret"))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		store := newStore(t)
+
+		for _, username := range []string{"carol", "alice", "dave", "bob"} {
+			user, err := NewUser(username, "secret", "user")
+			require.NoError(t, err)
+			require.NoError(t, store.Save(user))
+		}
+
+		list := func(after string, limit int) []string {
+			var usernames []string
+			err := store.List(after, func(user *User) bool {
+				usernames = append(usernames, user.Username)
+				return len(usernames) < limit
+			})
+			require.NoError(t, err)
+			return usernames
+		}
+
+		require.Equal(t, []string{"alice", "bob", "carol", "dave"}, list("", 10))
+		require.Equal(t, []string{"alice", "bob"}, list("", 2))
+		require.Equal(t, []string{"carol", "dave"}, list("bob", 10))
+		require.Empty(t, list("dave", 10))
+	})
+
+	t.Run("found_user_is_a_clone", func(t *testing.T) {
+		store := newStore(t)
+
+		user, err := NewUser("user1", "secret", "user")
+		require.NoError(t, err)
+		require.NoError(t, store.Save(user))
+
+		other, err := store.Find("user1")
+		require.NoError(t, err)
+		other.Role = "admin"
+
+		other, err = store.Find("user1")
+		require.NoError(t, err)
+		require.Equal(t, "user", other.Role)
+	})
+}
+
+func TestFileUserStore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	store, err := NewFileUserStore(dir)
+	require.NoError(t, err)
+
+	user, err := NewUser("admin1", "secret", "admin")
+	require.NoError(t, err)
+	require.NoError(t, store.Save(user))
+
+	// a taken username is rejected before it reaches the log
+	other, err := NewUser("admin1", "other", "user")
+	require.NoError(t, err)
+	require.Equal(t, ErrAlreadyExists, store.Save(other))
+	other, err = NewUser("user1", "secret", "user")
+	require.NoError(t, err)
+	require.Equal(t, ErrNotFound, store.Update(other))
+
+	require.NoError(t, store.Save(other))
+	other.Disabled = true
+	require.NoError(t, store.Update(other))
+	require.NoError(t, store.Close())
+
+	// only the bcrypt hash reaches the disk
+	data, err := ioutil.ReadFile(filepath.Join(dir, "users.wal"))
+	require.NoError(t, err)
+	require.False(t, strings.Contains(string(data), "secret"))
+
+	store, err = NewFileUserStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	found, err := store.Find("admin1")
+	require.NoError(t, err)
+	require.Equal(t, user, found)
+	require.True(t, found.IsCorrectPassword("secret"))
+
+	found, err = store.Find("user1")
+	require.NoError(t, err)
+	require.True(t, found.Disabled)
+}